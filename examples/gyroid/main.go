@@ -84,7 +84,7 @@ func gyroidTeapot(cyclesPerSide int) (sdf.SDF3, error) {
 	}
 
 	// create the SDF from the STL mesh
-	teapot, err := obj.ImportSTL("../../files/teapot.stl", 20, 3, 5)
+	teapot, err := obj.ImportSTLSign("../../files/teapot.stl", sdf.MeshSignNormal)
 	if err != nil {
 		return nil, err
 	}
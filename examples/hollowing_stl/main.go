@@ -26,7 +26,8 @@ func carveinside(path string) (sdf.SDF3, error) {
 
 	// create the SDF from the mesh
 	// WARNING: It will only work on non-intersecting closed-surface(s) meshes.
-	imported, err := obj.ImportSTL(path, 20, 3, 5)
+	// Use sdf.MeshSignWinding for meshes with holes or self-intersections.
+	imported, err := obj.ImportSTLSign(path, sdf.MeshSignNormal)
 	if err != nil {
 		return nil, err
 	}
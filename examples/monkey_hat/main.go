@@ -22,7 +22,7 @@ import (
 func monkeyWithHat() (sdf.SDF3, error) {
 
 	// create the SDF from the mesh (a modified Suzanne from Blender with 366 faces)
-	monkeyImported, err := obj.ImportSTL("../../files/monkey.stl", 20, 3, 5)
+	monkeyImported, err := obj.ImportSTLSign("../../files/monkey.stl", sdf.MeshSignNormal)
 	if err != nil {
 		return nil, err
 	}
@@ -48,9 +48,7 @@ func monkeyWithHat() (sdf.SDF3, error) {
 	// It also smooths the mesh a little using trilinear interpolation.
 	// It is actually slower for this mesh (unless meshCells <<< renderer's meshCells), but should be faster for
 	// more complex meshes (with more triangles) or SDF3 hierarchies that take longer to evaluate.
-	monkeyHat = sdf.NewVoxelSDF3(monkeyHat, 64, nil)
-
-	return monkeyHat, nil
+	return sdf.NewVoxelSDF3Interp(monkeyHat, 64, sdf.VoxelLinear, nil)
 }
 
 //-----------------------------------------------------------------------------
@@ -62,7 +60,7 @@ func main() {
 	}
 	render.ToSTL(monkeyHat, "monkey-out.stl", render.NewMarchingCubesUniform(128))
 	//render.ToSTL(monkeyHat, "monkey-out.stl", render.NewMarchingCubesOctree(128))
-	//render.ToSTL(monkeyHat, 64, "monkey-out.stl", dc.NewDualContouringDefault())
+	//render.ToSTL(monkeyHat, "monkey-out.stl", dc.NewDualContouringDefault(64))
 }
 
 //-----------------------------------------------------------------------------
@@ -58,7 +58,7 @@ func main() {
 		log.Fatalf("error: %s", err)
 	}
 	render.ToSTL(pool, "pool1.stl", render.NewMarchingCubesOctree(300))
-	//render.ToSTL(pool, 15, "pool2.stl", dc.NewDualContouringDefault())
+	//render.ToSTL(pool, "pool2.stl", dc.NewDualContouringV1(300, 1e-3, 0, false))
 }
 
 //-----------------------------------------------------------------------------
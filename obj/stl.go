@@ -1,7 +1,7 @@
 //-----------------------------------------------------------------------------
 /*
 
-Closed-surface triangle meshes (and STL files)
+Closed-surface triangle meshes (and STL, 3MF and OBJ files)
 
 */
 //-----------------------------------------------------------------------------
@@ -9,169 +9,75 @@ Closed-surface triangle meshes (and STL files)
 package obj
 
 import (
-	"math"
-
 	"github.com/deadsy/sdfx/render"
 	"github.com/deadsy/sdfx/sdf"
-	v3 "github.com/deadsy/sdfx/vec/v3"
-	"github.com/dhconnelly/rtreego"
 )
 
 //-----------------------------------------------------------------------------
 
-func v3ToPoint(v v3.Vec) rtreego.Point {
-	return rtreego.Point{v.X, v.Y, v.Z}
-}
-
-//-----------------------------------------------------------------------------
-
-type triMeshSdf struct {
-	rtree        *rtreego.Rtree
-	numNeighbors int
-	bb           sdf.Box3
-}
-
-const stlEpsilon = 1e-1
-
-func (t *triMeshSdf) Evaluate(p v3.Vec) float64 {
-	// Check all triangle distances
-	signedDistanceResult := 1.
-	closestTriangle := math.MaxFloat64
-	// Quickly skip checking most triangles by only checking the N closest neighbours (AABB based)
-	neighbors := t.rtree.NearestNeighbors(t.numNeighbors, v3ToPoint(p))
-	for _, neighbor := range neighbors {
-		triangle := neighbor.(*sdf.Triangle3)
-		testPointToTriangle := p.Sub(triangle[0])
-		triNormal := triangle.Normal()
-		signedDistanceToTriPlane := triNormal.Dot(testPointToTriangle)
-		// Take this triangle as the source of truth if the projection of the point on the triangle is the closest
-		distToTri, _ := stlPointToTriangleDistSq(p, triangle)
-		if distToTri < closestTriangle {
-			closestTriangle = distToTri
-			signedDistanceResult = signedDistanceToTriPlane
-		}
-	}
-	return signedDistanceResult
-}
-
-func (t *triMeshSdf) BoundingBox() sdf.Box3 {
-	return t.bb
-}
-
-// ImportTriMesh converts a triangle-based mesh into a SDF3 surface. minChildren and maxChildren are parameters that can
-// affect the performance of the internal data structure (3 and 5 are a good default; maxChildren >= minChildren > 0).
+// ImportTriMesh converts a triangle-based mesh into a SDF3 surface.
+// The distance is exact (see sdf.Mesh3D). It returns nil if the mesh has no (non-degenerate)
+// triangles, use ImportTriMeshSign for the error.
 //
-// WARNING: Setting a low numNeighbors will consider many fewer triangles for each evaluated point, greatly speeding up
-// the algorithm. However, if the count of triangles is too low artifacts will appear on the surface (triangle
-// continuations). Setting this value to MaxInt is extremely slow but will provide correct results, so choose a value
-// that works for your model.
+// Deprecated: numNeighbors, minChildren and maxChildren tuned the r-tree of an earlier
+// implementation and are ignored. Use ImportTriMeshSign.
 //
-// It is recommended to cache (and/or smooth) its values by using sdf.VoxelSdf3.
+// It is recommended to cache (and/or smooth) its values by using sdf.VoxelSdf3,
+// or sdf.SparseVoxelSDF3 which can also be saved to a file between runs.
 //
 // WARNING: It will only work on non-intersecting closed-surface(s) meshes.
 // NOTE: Fix using blender for intersecting surfaces: Edit mode > P > By loose parts > Add boolean modifier to join them
+// or use ImportTriMeshSign with sdf.MeshSignWinding.
 func ImportTriMesh(mesh []*sdf.Triangle3, numNeighbors, minChildren, maxChildren int) sdf.SDF3 {
-	if len(mesh) == 0 {
+	s, err := ImportTriMeshSign(mesh, sdf.MeshSignNormal)
+	if err != nil {
 		return nil
 	}
-	// Compute the bounding box
-	bulkLoad := make([]rtreego.Spatial, len(mesh))
-	bb := mesh[0].BoundingBox()
-	for i, triangle := range mesh {
-		bulkLoad[i] = triangle
-		bb = bb.Extend(triangle.BoundingBox())
-	}
-	return &triMeshSdf{
-		rtree:        rtreego.NewTree(3, minChildren, maxChildren, bulkLoad...),
-		numNeighbors: numNeighbors,
-		bb:           bb,
-	}
+	return s
 }
 
-//-----------------------------------------------------------------------------
-
-func stlPointToTriangleDistSq(p v3.Vec, triangle *sdf.Triangle3) (float64, bool /* falls outside? */) {
-	// Compute the closest point
-	closest, fallsOutside := stlClosestTrianglePointTo(p, triangle)
-	// Compute distance to the closest point
-	closestToP := p.Sub(closest)
-	distance := closestToP.Length2()
-	// Solve influence (distance) ties, by prioritizing triangles with normals more aligned to `closestToP`.
-	// This should fix ghost triangle extensions and smooth the field over sharp angles.
-	if fallsOutside { // <-- This is an optimization, as others have 0 extra influence in this step
-		distance *= 1 + (1-math.Abs(closestToP.Normalize().Dot(triangle.Normal())))*stlEpsilon
-	}
-	//log.Println(distance, closestToP.Normalize().Dot(triangle.Normal()))
-	return distance, fallsOutside
+// ImportTriMeshSign converts a triangle-based mesh into a SDF3 surface. See ImportTriMesh.
+// With sdf.MeshSignWinding the inside/outside of the surface is decided by the generalized winding number,
+// so meshes with holes, self-intersections or flipped triangles (e.g. scans) also work.
+func ImportTriMeshSign(mesh []*sdf.Triangle3, sign sdf.MeshSign) (sdf.SDF3, error) {
+	return sdf.Mesh3DSign(mesh, sign)
 }
 
-// https://stackoverflow.com/a/47505833
-func stlClosestTrianglePointTo(p v3.Vec, triangle *sdf.Triangle3) (v3.Vec, bool /* falls outside? */) {
-	edgeAbDelta := triangle[1].Sub(triangle[0])
-	edgeCaDelta := triangle[0].Sub(triangle[2])
-	edgeBcDelta := triangle[2].Sub(triangle[1])
-
-	// The closest point may be a vertex
-	uab := stlEdgeProject(triangle[0], edgeAbDelta, p)
-	uca := stlEdgeProject(triangle[2], edgeCaDelta, p)
-	if uca > 1 && uab < 0 {
-		return triangle[0], true
-	}
-	ubc := stlEdgeProject(triangle[1], edgeBcDelta, p)
-	if uab > 1 && ubc < 0 {
-		return triangle[1], true
-	}
-	if ubc > 1 && uca < 0 {
-		return triangle[2], true
-	}
-
-	// The closest point may be on an edge
-	triNormal := triangle.Normal()
-	planeAbNormal := triNormal.Cross(edgeAbDelta)
-	planeBcNormal := triNormal.Cross(edgeBcDelta)
-	planeCaNormal := triNormal.Cross(edgeCaDelta)
-	if uab >= 0 && uab <= 1 && !stlPlaneIsAbove(triangle[0], planeAbNormal, p) {
-		return stlEdgePointAt(triangle[0], edgeAbDelta, uab), true
-	}
-	if ubc >= 0 && ubc <= 1 && !stlPlaneIsAbove(triangle[1], planeBcNormal, p) {
-		return stlEdgePointAt(triangle[1], edgeBcDelta, ubc), true
-	}
-	if uca >= 0 && uca <= 1 && !stlPlaneIsAbove(triangle[2], planeCaNormal, p) {
-		return stlEdgePointAt(triangle[2], edgeCaDelta, uca), true
-	}
-
-	// The closest point is in the triangle so project to the plane to find it
-	return stlPlaneProject(triangle[0], triNormal, p), false
-}
-
-func stlEdgeProject(edge1, edgeDelta, p v3.Vec) float64 {
-	return p.Sub(edge1).Dot(edgeDelta) / edgeDelta.Length2()
-}
+//-----------------------------------------------------------------------------
 
-func stlEdgePointAt(edge1, edgeDelta v3.Vec, t float64) v3.Vec {
-	return edge1.Add(edgeDelta.MulScalar(t))
+// ImportSTL converts an STL model into a SDF3 surface. See ImportTriMesh.
+//
+// Deprecated: numNeighbors, minChildren and maxChildren are ignored. Use ImportSTLSign.
+func ImportSTL(path string, numNeighbors, minChildren, maxChildren int) (sdf.SDF3, error) {
+	return ImportSTLSign(path, sdf.MeshSignNormal)
 }
 
-func stlPlaneIsAbove(anyPoint, normal, testPoint v3.Vec) bool {
-	return normal.Dot(testPoint.Sub(anyPoint)) > 0
+// ImportSTLSign converts an STL model into a SDF3 surface. See ImportTriMeshSign.
+func ImportSTLSign(path string, sign sdf.MeshSign) (sdf.SDF3, error) {
+	mesh, err := render.LoadSTL(path)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Mesh3DSign(mesh, sign)
 }
 
-func stlPlaneProject(anyPoint, normal, testPoint v3.Vec) v3.Vec {
-	v := testPoint.Sub(anyPoint)
-	d := normal.Dot(v)
-	p := testPoint.Sub(normal.MulScalar(d))
-	return p
+// Import3MF converts a 3MF model into a SDF3 surface. See ImportTriMeshSign.
+// All the build items of the model are combined into a single surface.
+func Import3MF(path string, sign sdf.MeshSign) (sdf.SDF3, error) {
+	mesh, err := render.Load3MF(path)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Mesh3DSign(mesh, sign)
 }
 
-//-----------------------------------------------------------------------------
-
-// ImportSTL converts an STL model into a SDF3 surface. See ImportTriMesh.
-func ImportSTL(path string, numNeighbors, minChildren, maxChildren int) (sdf.SDF3, error) {
-	mesh, err := render.LoadSTL(path)
+// ImportOBJ converts an OBJ model into a SDF3 surface. See ImportTriMeshSign.
+func ImportOBJ(path string, sign sdf.MeshSign) (sdf.SDF3, error) {
+	mesh, err := render.LoadOBJ(path)
 	if err != nil {
 		return nil, err
 	}
-	return ImportTriMesh(mesh, numNeighbors, minChildren, maxChildren), nil
+	return sdf.Mesh3DSign(mesh, sign)
 }
 
 //-----------------------------------------------------------------------------
@@ -16,13 +16,24 @@ File sizes for 3MF are around 7x smaller than an STL with the same mesh.
 3MF files are not identical from run to run. 3MF files are a zipped archive.
 The contents of the archive *are* the same but the containing zip file differs.
 
+Loading a 3MF file combines the meshes of all the build items. Build item and
+component transforms are applied, and the vertices are scaled to millimeters.
+
+Multi-part files have one object (and one build item) per part. Parts with
+a colour or material name reference a base material, so slicers can show
+the parts as separate bodies and assign them to different extruders.
+
 */
 //-----------------------------------------------------------------------------
 
 package render
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"image/color"
+	"io"
 	"sync"
 
 	"github.com/deadsy/sdfx/sdf"
@@ -37,20 +48,67 @@ func toPoint3D(a v3.Vec) go3mf.Point3D {
 	return go3mf.Point3D{float32(a.X), float32(a.Y), float32(a.Z)}
 }
 
-//-----------------------------------------------------------------------------
+// add3MFTriangles adds triangles to a 3MF mesh.
+func add3MFTriangles(mb *go3mf.MeshBuilder, ts []*sdf.Triangle3) {
+	for _, t := range ts {
+		v1 := mb.AddVertex(toPoint3D(t[0]))
+		v2 := mb.AddVertex(toPoint3D(t[1]))
+		v3 := mb.AddVertex(toPoint3D(t[2]))
+		mb.Mesh.Triangles.Triangle = append(mb.Mesh.Triangles.Triangle, go3mf.Triangle{V1: v1, V2: v2, V3: v3})
+	}
+}
 
-// write3MF writes a stream of triangles to a 3MF file.
-func write3MF(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error) {
+// fromPoint3D converts a go3mf 3D vector to a 3D float vector.
+func fromPoint3D(a go3mf.Point3D) v3.Vec {
+	return v3.Vec{float64(a[0]), float64(a[1]), float64(a[2])}
+}
 
-	f, err := go3mf.CreateWriter(path)
-	if err != nil {
-		return nil, err
+// toMatrix converts a transform matrix to a go3mf matrix.
+// The zero matrix is treated as the identity.
+func toMatrix(m sdf.M44) go3mf.Matrix {
+	if m == (sdf.M44{}) {
+		return go3mf.Identity()
+	}
+	// 3MF uses row vectors, so the matrix is transposed
+	var x go3mf.Matrix
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			x[4*j+i] = float32(m[4*i+j])
+		}
 	}
+	return x
+}
 
+// toRGBA converts a color to the non-premultiplied RGBA used by 3MF.
+func toRGBA(c color.Color) color.RGBA {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return color.RGBA{n.R, n.G, n.B, n.A}
+}
+
+//-----------------------------------------------------------------------------
+
+// write3MF writes a stream of triangles to a 3MF file.
+// Any error (including cancellation of the context) is stored in *err by the time wg.Wait() returns.
+func write3MF(ctx context.Context, wg *sync.WaitGroup, w io.Writer, err *error) chan<- []*sdf.Triangle3 {
 	// External code writes triangles to this channel.
 	// This goroutine reads the channel and writes triangles to the file.
 	c := make(chan []*sdf.Triangle3)
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		*err = stream3MF(ctx, w, c)
+		// keep reading the channel so the renderer doesn't block
+		for range c {
+		}
+	}()
+
+	return c
+}
+
+// stream3MF reads triangles from a channel and writes them to a 3MF file.
+func stream3MF(ctx context.Context, w io.Writer, c <-chan []*sdf.Triangle3) error {
+
 	var model go3mf.Model
 	var mesh go3mf.Mesh
 
@@ -63,27 +121,248 @@ func write3MF(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error)
 	// use the mesh builder to de-dup the vertices
 	mb := go3mf.NewMeshBuilder(&mesh)
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		defer f.Close()
-		// read triangles from the channel and add them to the model
-		for ts := range c {
-			for _, t := range ts {
-				v1 := mb.AddVertex(toPoint3D(t[0]))
-				v2 := mb.AddVertex(toPoint3D(t[1]))
-				v3 := mb.AddVertex(toPoint3D(t[2]))
-				mesh.Triangles.Triangle = append(mesh.Triangles.Triangle, go3mf.Triangle{V1: v1, V2: v2, V3: v3})
+	// read triangles from the channel and add them to the model
+	for ts := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		add3MFTriangles(mb, ts)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// encode and write out the file
+	return go3mf.NewEncoder(w).Encode(&model)
+}
+
+//-----------------------------------------------------------------------------
+// Multi-part 3MF files.
+
+// Part3 is a named part of a multi-part 3MF file.
+type Part3 struct {
+	Name      string      // object name (defaults to "part<n>")
+	SDF       sdf.SDF3    // sdf3 to render
+	Color     color.Color // display color of the part material (nil for none)
+	Material  string      // base material name (defaults to the part name if a color is given)
+	Transform sdf.M44     // build item transform (the zero matrix is the identity)
+}
+
+// material returns the base material for a part, or false if it has none.
+func (p *Part3) material(name string) (go3mf.Base, bool) {
+	if p.Color == nil && p.Material == "" {
+		return go3mf.Base{}, false
+	}
+	m := go3mf.Base{Name: p.Material, Color: color.RGBA{0x80, 0x80, 0x80, 0xff}}
+	if m.Name == "" {
+		m.Name = name
+	}
+	if p.Color != nil {
+		m.Color = toRGBA(p.Color)
+	}
+	return m, true
+}
+
+// checkParts checks the parts of a multi-part file.
+func checkParts(parts []Part3) error {
+	if len(parts) == 0 {
+		return errors.New("no parts")
+	}
+	for i := range parts {
+		if parts[i].SDF == nil {
+			return fmt.Errorf("part %d has no sdf3", i)
+		}
+	}
+	return nil
+}
+
+// To3MFMulti renders a set of parts and writes them to a single 3MF file.
+func To3MFMulti(
+	parts []Part3, // parts to render
+	path string, // path to filename
+	r Render3, // rendering method
+) error {
+	if err := checkParts(parts); err != nil {
+		return err
+	}
+	for i := range parts {
+		fmt.Printf("rendering %s part %d (%s)\n", path, i, r.Info(parts[i].SDF))
+	}
+	return toFile(path, func(w io.Writer) error {
+		return Write3MFMulti(context.Background(), w, parts, r, nil)
+	})
+}
+
+// Write3MFMulti renders a set of parts and writes them to w as a 3MF file.
+// Each part is a separate object with its own build item.
+// Rendering stops with the context error if the context is cancelled.
+func Write3MFMulti(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	parts []Part3, // parts to render
+	r Render3, // rendering method
+	progress Progress, // progress function (may be nil)
+) error {
+	if err := checkParts(parts); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var model go3mf.Model
+
+	// base materials shared by the parts
+	materials := &go3mf.BaseMaterials{}
+	type materialKey struct {
+		name  string
+		color color.RGBA
+	}
+	materialIndex := make(map[materialKey]uint32)
+	var materialObjects []*go3mf.Object
+
+	for i := range parts {
+		p := &parts[i]
+		name := p.Name
+		if name == "" {
+			name = fmt.Sprintf("part%d", i)
+		}
+		// render the part
+		var partProgress Progress
+		if progress != nil {
+			partProgress = func(fraction float64) {
+				progress((float64(i) + fraction) / float64(len(parts)))
 			}
 		}
-		// encode and write out the file
-		if err := f.Encode(&model); err != nil {
-			fmt.Printf("%s\n", err)
-			return
+		triangles, err := ToTrianglesContext(ctx, p.SDF, r, partProgress)
+		if err != nil {
+			return err
 		}
-	}()
+		// add the part to the model
+		var mesh go3mf.Mesh
+		add3MFTriangles(go3mf.NewMeshBuilder(&mesh), triangles)
+		obj := &go3mf.Object{ID: model.Resources.UnusedID(), Name: name, Mesh: &mesh}
+		if m, ok := p.material(name); ok {
+			key := materialKey{m.Name, m.Color}
+			k, found := materialIndex[key]
+			if !found {
+				k = uint32(len(materials.Materials))
+				materialIndex[key] = k
+				materials.Materials = append(materials.Materials, m)
+			}
+			obj.PIndex = k
+			materialObjects = append(materialObjects, obj)
+		}
+		model.Resources.Objects = append(model.Resources.Objects, obj)
+		model.Build.Items = append(model.Build.Items, &go3mf.Item{ObjectID: obj.ID, Transform: toMatrix(p.Transform)})
+	}
+
+	if len(materials.Materials) != 0 {
+		// assets are written before objects, so the materials are defined before use
+		materials.ID = model.Resources.UnusedID()
+		model.Resources.Assets = append(model.Resources.Assets, materials)
+		for _, obj := range materialObjects {
+			obj.PID = materials.ID
+		}
+	}
+
+	// encode and write out the file
+	return go3mf.NewEncoder(w).Encode(&model)
+}
+
+//-----------------------------------------------------------------------------
+// Load a 3MF file.
+
+// load3MFMaxDepth is the maximum nesting of 3MF components (this also catches cycles).
+const load3MFMaxDepth = 32
+
+// unitScale3MF is the scale from 3MF model units to millimeters.
+var unitScale3MF = map[go3mf.Units]float64{
+	go3mf.UnitMillimeter: 1,
+	go3mf.UnitMicrometer: 1e-3,
+	go3mf.UnitCentimeter: 10,
+	go3mf.UnitInch:       25.4,
+	go3mf.UnitFoot:       304.8,
+	go3mf.UnitMeter:      1000,
+}
+
+// transform3MF returns the transform for a build item or component.
+// The zero matrix (no transform) is the identity.
+func transform3MF(m go3mf.Matrix) go3mf.Matrix {
+	if m == (go3mf.Matrix{}) {
+		return go3mf.Identity()
+	}
+	return m
+}
+
+// load3MFObject adds the transformed triangles of an object (and its components) to a mesh.
+func load3MFObject(model *go3mf.Model, path string, id uint32, m go3mf.Matrix, scale float64, depth int, mesh *[]*sdf.Triangle3) error {
+	if depth > load3MFMaxDepth {
+		return errors.New("3mf components are nested too deeply")
+	}
+	obj, ok := model.FindObject(path, id)
+	if !ok {
+		return fmt.Errorf("3mf object %d not found", id)
+	}
+	if obj.Type == go3mf.ObjectTypeSupport || obj.Type == go3mf.ObjectTypeSolidSupport {
+		// support structures aren't part of the model
+		return nil
+	}
+	if obj.Mesh != nil {
+		v := obj.Mesh.Vertices.Vertex
+		for _, t := range obj.Mesh.Triangles.Triangle {
+			if int(t.V1) >= len(v) || int(t.V2) >= len(v) || int(t.V3) >= len(v) {
+				return fmt.Errorf("3mf object %d has an invalid vertex index", id)
+			}
+			*mesh = append(*mesh, &sdf.Triangle3{
+				fromPoint3D(m.Mul3D(v[t.V1])).MulScalar(scale),
+				fromPoint3D(m.Mul3D(v[t.V2])).MulScalar(scale),
+				fromPoint3D(m.Mul3D(v[t.V3])).MulScalar(scale),
+			})
+		}
+	}
+	if obj.Components != nil {
+		for _, c := range obj.Components.Component {
+			err := load3MFObject(model, c.ObjectPath(path), c.ObjectID, m.Mul(transform3MF(c.Transform)), scale, depth+1, mesh)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	return c, nil
+// decode3MF returns the triangles of all the build items in a 3MF model.
+func decode3MF(model *go3mf.Model) ([]*sdf.Triangle3, error) {
+	scale, ok := unitScale3MF[model.Units]
+	if !ok {
+		return nil, fmt.Errorf("unknown 3mf units %s", model.Units)
+	}
+	var mesh []*sdf.Triangle3
+	for _, item := range model.Build.Items {
+		err := load3MFObject(model, item.ObjectPath(), item.ObjectID, transform3MF(item.Transform), scale, 0, &mesh)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(mesh) == 0 {
+		return nil, errors.New("3mf file has no triangles")
+	}
+	return mesh, nil
+}
+
+// Load3MF loads a 3MF file and returns the triangle mesh for all the build items.
+// The mesh vertices are in millimeters.
+func Load3MF(path string) ([]*sdf.Triangle3, error) {
+	r, err := go3mf.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var model go3mf.Model
+	if err := r.Decode(&model); err != nil {
+		return nil, err
+	}
+	return decode3MF(&model)
 }
 
 //-----------------------------------------------------------------------------
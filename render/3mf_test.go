@@ -0,0 +1,195 @@
+//-----------------------------------------------------------------------------
+/*
+
+3MF Output Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/hpinc/go3mf"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Write3MFMulti(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 1)
+	sphere, _ := sdf.Sphere3D(5)
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	parts := []Part3{
+		{Name: "box", SDF: box, Color: red, Material: "red"},
+		{Name: "lid", SDF: box, Color: red, Material: "red", Transform: sdf.Translate3d(v3.Vec{0, 0, 10})},
+		{SDF: sphere, Material: "PLA", Transform: sdf.Translate3d(v3.Vec{20, 0, 0})},
+		{Name: "plain", SDF: sphere},
+	}
+	r := NewMarchingCubesOctree(30)
+	var buf bytes.Buffer
+	last := 0.0
+	err := Write3MFMulti(context.Background(), &buf, parts, r, func(fraction float64) {
+		if fraction < last {
+			t.Errorf("progress %f after %f", fraction, last)
+		}
+		last = fraction
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 1 {
+		t.Errorf("progress %f at the end of the render", last)
+	}
+
+	// read the file back
+	var model go3mf.Model
+	if err := go3mf.NewDecoder(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Decode(&model); err != nil {
+		t.Fatal(err)
+	}
+	objects := model.Resources.Objects
+	if len(objects) != len(parts) || len(model.Build.Items) != len(parts) {
+		t.Fatalf("%d objects, %d build items", len(objects), len(model.Build.Items))
+	}
+	if len(model.Resources.Assets) != 1 {
+		t.Fatalf("%d assets", len(model.Resources.Assets))
+	}
+	materials, ok := model.Resources.Assets[0].(*go3mf.BaseMaterials)
+	if !ok || len(materials.Materials) != 2 {
+		t.Fatalf("expected 2 base materials, got %v", model.Resources.Assets[0])
+	}
+	names := []string{"box", "lid", "part2", "plain"}
+	for i, obj := range objects {
+		if obj.Name != names[i] {
+			t.Errorf("object %d: name %q, expected %q", i, obj.Name, names[i])
+		}
+		if obj.Mesh == nil || len(obj.Mesh.Triangles.Triangle) == 0 {
+			t.Errorf("%s: no triangles", obj.Name)
+		}
+		if model.Build.Items[i].ObjectID != obj.ID {
+			t.Errorf("%s: build item object id %d, expected %d", obj.Name, model.Build.Items[i].ObjectID, obj.ID)
+		}
+	}
+	// the box and lid share a material, the plain part has none
+	for i, x := range []struct {
+		pid    uint32
+		pindex uint32
+	}{{materials.ID, 0}, {materials.ID, 0}, {materials.ID, 1}, {0, 0}} {
+		if objects[i].PID != x.pid || objects[i].PIndex != x.pindex {
+			t.Errorf("%s: material %d/%d, expected %d/%d", objects[i].Name, objects[i].PID, objects[i].PIndex, x.pid, x.pindex)
+		}
+	}
+	if m := materials.Materials[0]; m.Name != "red" || m.Color != red {
+		t.Errorf("material 0: %v", m)
+	}
+	if m := materials.Materials[1]; m.Name != "PLA" || m.Color.A != 0xff {
+		t.Errorf("material 1: %v", m)
+	}
+	// build item transforms
+	p := model.Build.Items[2].Transform.Mul3D(go3mf.Point3D{1, 2, 3})
+	if p != (go3mf.Point3D{21, 2, 3}) {
+		t.Errorf("transformed point %v", p)
+	}
+	if model.Build.Items[0].HasTransform() {
+		t.Errorf("unexpected transform %v", model.Build.Items[0].Transform)
+	}
+
+	// errors
+	if err := Write3MFMulti(context.Background(), &buf, nil, r, nil); err == nil {
+		t.Errorf("expected an error for no parts")
+	}
+	if err := Write3MFMulti(context.Background(), &buf, []Part3{{Name: "x"}}, r, nil); err == nil {
+		t.Errorf("expected an error for a part with no sdf3")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Write3MFMulti(ctx, &buf, parts, r, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+}
+
+func Test_Load3MF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sdfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	box, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 0)
+	r := NewMarchingCubesOctree(20)
+	mesh := ToTriangles(box, r)
+	bb := meshBoundingBox(mesh)
+
+	// two build items with transforms
+	m := sdf.Translate3d(v3.Vec{20, 0, 0}).Mul(sdf.RotateZ(sdf.DtoR(90)))
+	path := filepath.Join(dir, "multi.3mf")
+	parts := []Part3{{SDF: box}, {SDF: box, Transform: m}}
+	if err := To3MFMulti(parts, path, r); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load3MF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2*len(mesh) {
+		t.Fatalf("%d triangles, expected %d", len(loaded), 2*len(mesh))
+	}
+	if got := meshBoundingBox(loaded[:len(mesh)]); !got.Equals(bb, 1e-3) {
+		t.Errorf("bounding box %v, expected %v", got, bb)
+	}
+	if got, want := meshBoundingBox(loaded[len(mesh):]), m.MulBox(bb); !got.Equals(want, 1e-3) {
+		t.Errorf("bounding box %v, expected %v", got, want)
+	}
+
+	// nested components in centimeters
+	var model go3mf.Model
+	model.Units = go3mf.UnitCentimeter
+	var m3mf go3mf.Mesh
+	add3MFTriangles(go3mf.NewMeshBuilder(&m3mf), mesh)
+	model.Resources.Objects = []*go3mf.Object{
+		{ID: 1, Mesh: &m3mf},
+		{ID: 2, Components: &go3mf.Components{Component: []*go3mf.Component{
+			{ObjectID: 1},
+			{ObjectID: 1, Transform: go3mf.Identity().Translate(0, 0, 10)},
+		}}},
+	}
+	model.Build.Items = []*go3mf.Item{{ObjectID: 2, Transform: go3mf.Identity().Translate(1, 0, 0)}}
+	loaded, err = decode3MF(&model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sdf.Box3{Min: bb.Min.Add(v3.Vec{1, 0, 0}).MulScalar(10), Max: bb.Max.Add(v3.Vec{1, 0, 10}).MulScalar(10)}
+	if got := meshBoundingBox(loaded); len(loaded) != 2*len(mesh) || !got.Equals(want, 1e-3) {
+		t.Errorf("%d triangles, bounding box %v, expected %v", len(loaded), got, want)
+	}
+
+	// component cycles are errors
+	model.Resources.Objects[0].Components = &go3mf.Components{Component: []*go3mf.Component{{ObjectID: 2}}}
+	if _, err := decode3MF(&model); err == nil {
+		t.Errorf("expected an error for a component cycle")
+	}
+	model.Build.Items[0].ObjectID = 3
+	if _, err := decode3MF(&model); err == nil {
+		t.Errorf("expected an error for a missing object")
+	}
+}
+
+// meshBoundingBox returns the bounding box of a triangle mesh.
+func meshBoundingBox(mesh []*sdf.Triangle3) sdf.Box3 {
+	bb := mesh[0].BoundingBox()
+	for _, t := range mesh {
+		bb = bb.Extend(t.BoundingBox())
+	}
+	return bb
+}
+
+//-----------------------------------------------------------------------------
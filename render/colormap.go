@@ -0,0 +1,184 @@
+//-----------------------------------------------------------------------------
+/*
+
+Distance Field Images
+
+Render the distance field of an SDF2 (or a planar section of an SDF3) to a
+png image for debugging. Distances are colored with a colormap, and there are
+options for iso-distance bands, highlighting the zero crossing (the surface)
+and showing the gradient magnitude.
+
+For an exact distance field the gradient magnitude is 1 everywhere. Values
+greater than 1 are Lipschitz violations, where the field overestimates the
+distance and ray marching or octree rendering may miss the surface.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/vec/conv"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Colormap returns the color for a normalized value in the range [-1, 1].
+// Negative values are inside the field, positive values are outside.
+type Colormap func(x float64) color.RGBA
+
+// lerpRGBA linearly interpolates between two colors.
+func lerpRGBA(c0, c1 color.RGBA, t float64) color.RGBA {
+	f := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+	}
+	return color.RGBA{f(c0.R, c1.R), f(c0.G, c1.G), f(c0.B, c1.B), 0xff}
+}
+
+// ColormapGray maps the inside to black and the outside to white.
+func ColormapGray(x float64) color.RGBA {
+	y := uint8(math.Round(255 * 0.5 * (sdf.Clamp(x, -1, 1) + 1)))
+	return color.RGBA{y, y, y, 0xff}
+}
+
+// diverging colormap colors
+var (
+	divergingInside  = color.RGBA{0x3b, 0x4c, 0xc0, 0xff} // blue
+	divergingZero    = color.RGBA{0xf7, 0xf7, 0xf7, 0xff} // white
+	divergingOutside = color.RGBA{0xb4, 0x04, 0x26, 0xff} // red
+)
+
+// ColormapDiverging maps the inside to blue and the outside to red (white at zero).
+func ColormapDiverging(x float64) color.RGBA {
+	x = sdf.Clamp(x, -1, 1)
+	if x < 0 {
+		return lerpRGBA(divergingZero, divergingInside, -x)
+	}
+	return lerpRGBA(divergingZero, divergingOutside, x)
+}
+
+//-----------------------------------------------------------------------------
+
+// FieldStyle sets how a distance field is rendered to an image.
+type FieldStyle struct {
+	Colormap Colormap    // colormap for the field (nil for gray scale)
+	Min, Max float64     // distance range of the colormap (0, 0 for the sampled range)
+	Spacing  float64     // iso-distance band spacing (0 for no bands)
+	Zero     color.Color // color of the zero crossing (nil for no highlight)
+	Gradient bool        // show the gradient magnitude (0 to 2) instead of the distance
+}
+
+// fieldBand is the brightness of alternate iso-distance bands.
+const fieldBand = 0.85
+
+// fieldColor returns the colormap value for a distance.
+// The inside and outside are scaled separately so zero is at the center of the colormap.
+func fieldColor(dist, dmin, dmax float64) float64 {
+	if dist >= 0 {
+		if dmax <= 0 {
+			return 0
+		}
+		return dist / dmax
+	}
+	if dmin >= 0 {
+		return 0
+	}
+	return dist / -dmin
+}
+
+// RenderField renders a 2d signed distance field with a colormap and overlays.
+// The style may be nil for the defaults (gray scale distance).
+func (d *PNG) RenderField(s sdf.SDF2, style *FieldStyle) {
+	if style == nil {
+		style = &FieldStyle{}
+	}
+	cmap := style.Colormap
+	if cmap == nil {
+		cmap = ColormapGray
+	}
+
+	// sample the distance field
+	nx, ny := d.pixels.X, d.pixels.Y
+	dist := make([]float64, nx*ny)
+	for x := 0; x < nx; x++ {
+		for y := 0; y < ny; y++ {
+			dist[x*ny+y] = s.Evaluate(d.m.ToV2(v2i.Vec{x, y}))
+		}
+	}
+
+	// the gradient magnitude (central differences at half a pixel)
+	var grad []float64
+	if style.Gradient {
+		h := d.bb.Size().Div(conv.V2iToV2(d.pixels)).MulScalar(0.5)
+		grad = make([]float64, nx*ny)
+		for x := 0; x < nx; x++ {
+			for y := 0; y < ny; y++ {
+				p := d.m.ToV2(v2i.Vec{x, y})
+				dx := s.Evaluate(p.Add(v2.Vec{h.X, 0})) - s.Evaluate(p.Sub(v2.Vec{h.X, 0}))
+				dy := s.Evaluate(p.Add(v2.Vec{0, h.Y})) - s.Evaluate(p.Sub(v2.Vec{0, h.Y}))
+				grad[x*ny+y] = v2.Vec{dx / (2 * h.X), dy / (2 * h.Y)}.Length()
+			}
+		}
+	}
+
+	dmin, dmax := style.Min, style.Max
+	if dmin == 0 && dmax == 0 {
+		for _, v := range dist {
+			dmin = math.Min(dmin, v)
+			dmax = math.Max(dmax, v)
+		}
+	}
+
+	for x := 0; x < nx; x++ {
+		for y := 0; y < ny; y++ {
+			i := x*ny + y
+			var c color.RGBA
+			if grad != nil {
+				// 1 (exact) is at the center of the colormap
+				c = cmap(grad[i] - 1)
+			} else {
+				c = cmap(fieldColor(dist[i], dmin, dmax))
+			}
+			if style.Spacing > 0 && int(math.Floor(dist[i]/style.Spacing))&1 == 1 {
+				c = lerpRGBA(color.RGBA{0, 0, 0, 0xff}, c, fieldBand)
+			}
+			if style.Zero != nil && d.zeroCrossing(dist, x, y) {
+				d.img.Set(x, y, style.Zero)
+				continue
+			}
+			d.img.Set(x, y, c)
+		}
+	}
+}
+
+// zeroCrossing returns true if the distance changes sign between a pixel and its next neighbours.
+func (d *PNG) zeroCrossing(dist []float64, x, y int) bool {
+	ny := d.pixels.Y
+	v := dist[x*ny+y]
+	if v == 0 {
+		return true
+	}
+	if x+1 < d.pixels.X && (v < 0) != (dist[(x+1)*ny+y] < 0) {
+		return true
+	}
+	if y+1 < ny && (v < 0) != (dist[x*ny+y+1] < 0) {
+		return true
+	}
+	return false
+}
+
+// RenderSlice renders a planar section of a 3d signed distance field.
+// The plane passes through a with normal n, and has the 2d coordinates of sdf.Slice2D
+// (E.g. use the bounding box of sdf.Slice2D(s, a, n) for the png).
+func (d *PNG) RenderSlice(s sdf.SDF3, a, n v3.Vec, style *FieldStyle) {
+	d.RenderField(sdf.Slice2D(s, a, n), style)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,130 @@
+//-----------------------------------------------------------------------------
+/*
+
+Distance Field Image Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// scaleSDF2 scales the distance of an SDF2 (making it a non-exact field).
+type scaleSDF2 struct {
+	sdf.SDF2
+	k float64
+}
+
+func (s *scaleSDF2) Evaluate(p v2.Vec) float64 {
+	return s.k * s.SDF2.Evaluate(p)
+}
+
+func Test_Colormap(t *testing.T) {
+	if c := ColormapDiverging(0); c != divergingZero {
+		t.Errorf("expected white at zero, got %v", c)
+	}
+	if c := ColormapDiverging(-2); c != divergingInside {
+		t.Errorf("expected blue inside, got %v", c)
+	}
+	if c := ColormapDiverging(1); c != divergingOutside {
+		t.Errorf("expected red outside, got %v", c)
+	}
+	if c := ColormapGray(-1); c.R != 0 {
+		t.Errorf("expected black inside, got %v", c)
+	}
+	if c := ColormapGray(1); c.R != 0xff {
+		t.Errorf("expected white outside, got %v", c)
+	}
+}
+
+func Test_RenderField(t *testing.T) {
+	s, _ := sdf.Circle2D(10)
+	bb := sdf.Box2{Min: v2.Vec{-16, -16}, Max: v2.Vec{16, 16}}
+	pixels := v2i.Vec{64, 64}
+	newPNG := func() *PNG {
+		d, err := NewPNG("", bb, pixels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	rgba := func(d *PNG, p v2.Vec) color.RGBA {
+		q := d.m.ToV2i(p)
+		return d.img.RGBAAt(q.X, q.Y)
+	}
+
+	// diverging colormap
+	d := newPNG()
+	d.RenderField(s, &FieldStyle{Colormap: ColormapDiverging})
+	if c := rgba(d, v2.Vec{0.1, 0.1}); c.B <= c.R {
+		t.Errorf("expected blue inside, got %v", c)
+	}
+	if c := rgba(d, v2.Vec{-15, 15}); c.R <= c.B {
+		t.Errorf("expected red outside, got %v", c)
+	}
+
+	// zero crossing
+	green := color.RGBA{0, 0xff, 0, 0xff}
+	d = newPNG()
+	d.RenderField(s, &FieldStyle{Zero: green})
+	n := 0
+	for x := 0; x < pixels.X-1; x++ {
+		for y := 0; y < pixels.Y-1; y++ {
+			if d.img.RGBAAt(x, y) == green {
+				n++
+				if r := d.m.ToV2(v2i.Vec{x, y}).Length(); r < 9 || r > 11 {
+					t.Fatalf("zero crossing at radius %f", r)
+				}
+			}
+		}
+	}
+	if n < 100 {
+		t.Errorf("expected a zero crossing, got %d pixels", n)
+	}
+
+	// iso-distance bands
+	d0 := newPNG()
+	d0.RenderField(s, &FieldStyle{Min: -10, Max: 10})
+	d = newPNG()
+	d.RenderField(s, &FieldStyle{Min: -10, Max: 10, Spacing: 2})
+	if rgba(d, v2.Vec{11, 0}) != rgba(d0, v2.Vec{11, 0}) {
+		t.Error("expected no change in an even band")
+	}
+	if rgba(d, v2.Vec{13, 0}).R >= rgba(d0, v2.Vec{13, 0}).R {
+		t.Error("expected an odd band to be darker")
+	}
+
+	// gradient magnitude
+	d = newPNG()
+	d.RenderField(s, &FieldStyle{Colormap: ColormapDiverging, Gradient: true})
+	if c := rgba(d, v2.Vec{-15, 15}); c != divergingZero {
+		t.Errorf("expected white for an exact field, got %v", c)
+	}
+	d.RenderField(&scaleSDF2{s, 2}, &FieldStyle{Colormap: ColormapDiverging, Gradient: true})
+	if c := rgba(d, v2.Vec{-15, 15}); c != divergingOutside {
+		t.Errorf("expected red for a lipschitz violation, got %v", c)
+	}
+
+	// sphere section
+	s3, _ := sdf.Sphere3D(10)
+	d = newPNG()
+	d.RenderSlice(s3, v3.Vec{0, 0, 0}, v3.Vec{0, 0, 1}, &FieldStyle{Min: -10, Max: 10})
+	for _, p := range []v2.Vec{{0.1, 0.1}, {5, -3}, {-15, 15}} {
+		if rgba(d, p) != rgba(d0, p) {
+			t.Errorf("expected the sphere section to be the circle at %v", p)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
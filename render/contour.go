@@ -0,0 +1,359 @@
+//-----------------------------------------------------------------------------
+/*
+
+Contours
+
+The 2D renderers output unordered line segments. Chain them into polygons
+(closed where possible) and work out which closed contours are holes.
+Outer contours are counter-clockwise and holes are clockwise.
+
+Contour vertices can be simplified with the Douglas-Peucker algorithm.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// contourSnap is the distance at which segment end points are joined (relative to the drawing size).
+const contourSnap = 1e-9
+
+// Contour is a polygon chained from the line segments of a 2d render.
+type Contour struct {
+	Points v2.VecSet // vertices (the first point is not repeated for a closed contour)
+	Closed bool      // the contour is a closed loop
+	Parent int       // index of the enclosing closed contour (-1 for none)
+	Depth  int       // number of enclosing closed contours (odd depths are holes)
+}
+
+// Hole returns true if the contour is a hole within its parent.
+func (c *Contour) Hole() bool {
+	return c.Depth%2 != 0
+}
+
+// Area returns the signed area of a closed contour (positive for counter-clockwise).
+func (c *Contour) Area() float64 {
+	if !c.Closed {
+		return 0
+	}
+	a := 0.0
+	n := len(c.Points)
+	for i, p := range c.Points {
+		a += p.Cross(c.Points[(i+1)%n])
+	}
+	return 0.5 * a
+}
+
+// boundingBox returns the bounding box of the contour vertices.
+func (c *Contour) boundingBox() sdf.Box2 {
+	return sdf.Box2{Min: c.Points.Min(), Max: c.Points.Max()}
+}
+
+// inside returns true if a point is inside a closed contour (crossing test).
+func (c *Contour) inside(p v2.Vec) bool {
+	in := false
+	n := len(c.Points)
+	for i, a := range c.Points {
+		b := c.Points[(i+1)%n]
+		if (a.Y > p.Y) != (b.Y > p.Y) {
+			x := a.X + (p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			if p.X < x {
+				in = !in
+			}
+		}
+	}
+	return in
+}
+
+// reverse reverses the direction of the contour.
+func (c *Contour) reverse() {
+	for i, j := 0, len(c.Points)-1; i < j; i, j = i+1, j-1 {
+		c.Points[i], c.Points[j] = c.Points[j], c.Points[i]
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Douglas-Peucker Simplification
+
+// segmentDistance returns the distance from a point to a line segment.
+func segmentDistance(p, a, b v2.Vec) float64 {
+	ab := b.Sub(a)
+	l2 := ab.Length2()
+	if l2 == 0 {
+		return p.Sub(a).Length()
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l2))
+	return p.Sub(a.Add(ab.MulScalar(t))).Length()
+}
+
+// douglasPeucker marks the vertices to keep between the end points of a polyline.
+func douglasPeucker(pts []v2.Vec, tol float64, keep []bool) {
+	n := len(pts)
+	if n < 3 {
+		return
+	}
+	k := 0
+	dmax := 0.0
+	for i := 1; i < n-1; i++ {
+		if d := segmentDistance(pts[i], pts[0], pts[n-1]); d > dmax {
+			k, dmax = i, d
+		}
+	}
+	if dmax <= tol {
+		return
+	}
+	keep[k] = true
+	douglasPeucker(pts[:k+1], tol, keep[:k+1])
+	douglasPeucker(pts[k:], tol, keep[k:])
+}
+
+// simplify removes vertices that are within tol of the simplified contour.
+func (c *Contour) simplify(tol float64) {
+	pts := c.Points
+	n := len(pts)
+	if n < 3 {
+		return
+	}
+	keep := make([]bool, n+1)
+	if c.Closed {
+		// split the loop at the vertex furthest from the first vertex
+		k := 0
+		dmax := 0.0
+		for i, p := range pts {
+			if d := p.Sub(pts[0]).Length2(); d > dmax {
+				k, dmax = i, d
+			}
+		}
+		keep[0], keep[k], keep[n] = true, true, true
+		loop := append(append(v2.VecSet{}, pts...), pts[0])
+		douglasPeucker(loop[:k+1], tol, keep[:k+1])
+		douglasPeucker(loop[k:], tol, keep[k:])
+	} else {
+		keep[0], keep[n-1] = true, true
+		douglasPeucker(pts, tol, keep[:n])
+	}
+	var out v2.VecSet
+	for i, p := range pts {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	if c.Closed && len(out) < 3 {
+		// too small to simplify
+		return
+	}
+	c.Points = out
+}
+
+//-----------------------------------------------------------------------------
+// Chaining
+
+// vertexKey is the grid cell of a welded vertex.
+type vertexKey [2]int64
+
+// vertexWelder gives end points within a snap distance the same vertex index.
+type vertexWelder struct {
+	snap   float64
+	cell   map[vertexKey][]int
+	vertex []v2.Vec
+}
+
+func (w *vertexWelder) key(p v2.Vec) vertexKey {
+	return vertexKey{int64(math.Floor(p.X / w.snap)), int64(math.Floor(p.Y / w.snap))}
+}
+
+// index returns the index of the vertex for a point.
+func (w *vertexWelder) index(p v2.Vec) int {
+	k := w.key(p)
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			for _, i := range w.cell[vertexKey{k[0] + dx, k[1] + dy}] {
+				if w.vertex[i].Equals(p, w.snap) {
+					return i
+				}
+			}
+		}
+	}
+	i := len(w.vertex)
+	w.vertex = append(w.vertex, p)
+	w.cell[k] = append(w.cell[k], i)
+	return i
+}
+
+// Contours chains line segments into contours with their hole hierarchy.
+// Closed outer contours are counter-clockwise and holes are clockwise.
+// The vertices are simplified if the simplify tolerance is > 0.
+func Contours(lines []*sdf.Line2, simplify float64) []*Contour {
+	if len(lines) == 0 {
+		return nil
+	}
+	// weld the end points
+	bb := lines[0].BoundingBox()
+	for _, l := range lines {
+		bb = bb.Include(l[0]).Include(l[1])
+	}
+	snap := math.Max(contourSnap*bb.Size().MaxComponent(), epsilon)
+	w := &vertexWelder{snap: snap, cell: make(map[vertexKey][]int)}
+	type segment [2]int
+	var seg []segment
+	for _, l := range lines {
+		s := segment{w.index(l[0]), w.index(l[1])}
+		if s[0] != s[1] {
+			seg = append(seg, s)
+		}
+	}
+	// segments at each vertex
+	adj := make([][]int, len(w.vertex))
+	for i, s := range seg {
+		adj[s[0]] = append(adj[s[0]], i)
+		adj[s[1]] = append(adj[s[1]], i)
+	}
+	used := make([]bool, len(seg))
+	// next returns the vertex at the other end of an unused segment from v (or -1).
+	next := func(v int) int {
+		for _, i := range adj[v] {
+			if !used[i] {
+				used[i] = true
+				if seg[i][0] == v {
+					return seg[i][1]
+				}
+				return seg[i][0]
+			}
+		}
+		return -1
+	}
+
+	var contours []*Contour
+	for i, s := range seg {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		chain := []int{s[0], s[1]}
+		for chain[len(chain)-1] != chain[0] {
+			v := next(chain[len(chain)-1])
+			if v < 0 {
+				break
+			}
+			chain = append(chain, v)
+		}
+		c := &Contour{Parent: -1}
+		if chain[len(chain)-1] == chain[0] {
+			c.Closed = true
+			chain = chain[:len(chain)-1]
+		} else {
+			// open: extend the chain backwards from its start
+			var head []int
+			for v := next(chain[0]); v >= 0; v = next(v) {
+				head = append(head, v)
+			}
+			for j, k := 0, len(head)-1; j < k; j, k = j+1, k-1 {
+				head[j], head[k] = head[k], head[j]
+			}
+			chain = append(head, chain...)
+		}
+		c.Points = make(v2.VecSet, len(chain))
+		for j, v := range chain {
+			c.Points[j] = w.vertex[v]
+		}
+		if simplify > 0 {
+			c.simplify(simplify)
+		}
+		contours = append(contours, c)
+	}
+	contourHierarchy(contours)
+	return contours
+}
+
+// contourHierarchy sets the nesting and orientation of the contours.
+func contourHierarchy(contours []*Contour) {
+	bb := make([]sdf.Box2, len(contours))
+	for i, c := range contours {
+		bb[i] = c.boundingBox()
+	}
+	// inside returns true if contour i is inside closed contour j
+	inside := func(i, j int) bool {
+		if i == j || !contours[j].Closed {
+			return false
+		}
+		if !bb[j].Contains(bb[i].Min) || !bb[j].Contains(bb[i].Max) {
+			return false
+		}
+		return contours[j].inside(contours[i].Points[0])
+	}
+	for i, c := range contours {
+		c.Depth = 0
+		for j := range contours {
+			if inside(i, j) {
+				c.Depth++
+			}
+		}
+	}
+	for i, c := range contours {
+		c.Parent = -1
+		for j := range contours {
+			if contours[j].Depth == c.Depth-1 && inside(i, j) {
+				c.Parent = j
+				break
+			}
+		}
+		if c.Closed && (c.Area() < 0) != c.Hole() {
+			c.reverse()
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// ToContours renders an SDF2 to contours. See Contours.
+func ToContours(
+	s sdf.SDF2, // sdf2 to render
+	r Render2, // rendering method
+	simplify float64, // simplification tolerance (0 for none)
+) []*Contour {
+	contours, _ := ToContoursContext(context.Background(), s, r, simplify, nil)
+	return contours
+}
+
+// ToContoursContext renders an SDF2 to contours with cancellation and progress reporting.
+func ToContoursContext(
+	ctx context.Context, // context for cancellation
+	s sdf.SDF2, // sdf2 to render
+	r Render2, // rendering method
+	simplify float64, // simplification tolerance (0 for none)
+	progress Progress, // progress function (may be nil)
+) ([]*Contour, error) {
+	var lines []*sdf.Line2
+	var wg sync.WaitGroup
+	// collect the line segments
+	output := make(chan []*sdf.Line2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ls := range output {
+			lines = append(lines, ls...)
+		}
+	}()
+	// run the renderer
+	err := render2(ctx, r, s, sdf.NewLine2Buffer(output), progress)
+	// stop the collector reading on the channel
+	close(output)
+	// wait for the collection to complete
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return Contours(lines, simplify), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,143 @@
+//-----------------------------------------------------------------------------
+/*
+
+Contour Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Contours(t *testing.T) {
+	// a washer with an island in the hole, and a separate square
+	outer, _ := sdf.Circle2D(10)
+	inner, _ := sdf.Circle2D(6)
+	island, _ := sdf.Circle2D(2)
+	square := sdf.Transform2D(sdf.Box2D(v2.Vec{6, 6}, 0.5), sdf.Translate2d(v2.Vec{20.13, 0.17}))
+	s := sdf.Union2D(sdf.Difference2D(outer, inner), island, square)
+
+	for _, r := range []Render2{NewMarchingSquaresUniform(200), NewMarchingSquaresQuadtree(200)} {
+		contours := ToContours(s, r, 0)
+		if len(contours) != 4 {
+			t.Fatalf("expected 4 contours, got %d", len(contours))
+		}
+		depth := make(map[int]int)
+		for _, c := range contours {
+			if !c.Closed {
+				t.Fatalf("expected a closed contour")
+			}
+			depth[c.Depth]++
+			// outer contours are counter-clockwise, holes are clockwise
+			if (c.Area() < 0) != c.Hole() {
+				t.Errorf("bad orientation for depth %d, area %f", c.Depth, c.Area())
+			}
+			if c.Depth > 0 && contours[c.Parent].Depth != c.Depth-1 {
+				t.Errorf("bad parent for depth %d", c.Depth)
+			}
+			if c.Depth == 0 && c.Parent != -1 {
+				t.Errorf("expected no parent for depth 0")
+			}
+			// the vertices are on the surface
+			for _, p := range c.Points {
+				if d := s.Evaluate(p); math.Abs(d) > 0.05 {
+					t.Fatalf("vertex %v is %f from the surface", p, d)
+				}
+			}
+		}
+		if depth[0] != 2 || depth[1] != 1 || depth[2] != 1 {
+			t.Errorf("bad depths %v", depth)
+		}
+	}
+}
+
+func Test_Contours_Simplify(t *testing.T) {
+	// a square with extra collinear vertices and segments in any order and direction
+	lines := []*sdf.Line2{
+		{{0, 0}, {1, 0}},
+		{{2, 0}, {1, 0}},
+		{{2, 0}, {2, 2}},
+		{{0, 2}, {2, 2}},
+		{{0, 2}, {0, 1}},
+		{{0, 1}, {0, 0}},
+		// an open polyline
+		{{5, 0}, {6, 0.001}},
+		{{6, 0.001}, {7, 0}},
+	}
+	contours := Contours(lines, 0.01)
+	if len(contours) != 2 {
+		t.Fatalf("expected 2 contours, got %d", len(contours))
+	}
+	c := contours[0]
+	if !c.Closed || len(c.Points) != 4 {
+		t.Errorf("expected a closed square, got %v", c.Points)
+	}
+	if !sdf.EqualFloat64(c.Area(), 4, 1e-9) {
+		t.Errorf("expected area 4, got %f", c.Area())
+	}
+	c = contours[1]
+	if c.Closed || len(c.Points) != 2 {
+		t.Errorf("expected an open line, got %v", c.Points)
+	}
+	// no simplification
+	contours = Contours(lines, 0)
+	if n := len(contours[0].Points); n != 6 {
+		t.Errorf("expected 6 vertices, got %d", n)
+	}
+}
+
+func Test_WriteContours(t *testing.T) {
+	s, _ := sdf.Circle2D(5)
+	r := NewMarchingSquaresQuadtree(50)
+	var buf bytes.Buffer
+	if err := WriteDXF(context.Background(), &buf, s, r, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "LWPOLYLINE"); n != 1 {
+		t.Errorf("expected 1 polyline, got %d", n)
+	}
+	if strings.Contains(buf.String(), "\nLINE\n") {
+		t.Error("unexpected line entity")
+	}
+	buf.Reset()
+	if err := WriteSVG(context.Background(), &buf, s, r, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "<path"); n != 1 {
+		t.Errorf("expected 1 path, got %d", n)
+	}
+	if strings.Contains(buf.String(), "<line") {
+		t.Error("unexpected line element")
+	}
+	// the drawing area is the circle
+	var w, h float64
+	out := buf.String()
+	if _, err := fmt.Sscanf(out[strings.Index(out, "<svg"):], `<svg width="%f" height="%f"`, &w, &h); err != nil {
+		t.Fatal(err)
+	}
+	if !sdf.EqualFloat64(w, 10, 0.05) || !sdf.EqualFloat64(h, 10, 0.05) {
+		t.Errorf("expected a 10x10 drawing, got %fx%f", w, h)
+	}
+
+	// lines
+	svg := NewSVG("", "")
+	svg.Line(v2.Vec{0, 0}, v2.Vec{10, 5})
+	if svg.min != (v2.Vec{0, 0}) || svg.max != (v2.Vec{10, 5}) {
+		t.Errorf("bad drawing area %v %v", svg.min, svg.max)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -8,16 +8,23 @@ Uses octree space subdivision.
 Supports sharp edges and octree-based mesh simplification.
 Based on: https://github.com/nickgildea/DualContouringSample
 
+The octree is adaptive. Only the nodes that contain the surface are refined
+down to the leaf level. Leaf nodes are then merged back up the tree while the
+QEF error of the merged vertex stays within the simplification threshold,
+so flat regions become large triangles and sharp edges are kept.
+
 */
 //-----------------------------------------------------------------------------
 
 package dc
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
 
+	"github.com/deadsy/sdfx/render"
 	"github.com/deadsy/sdfx/sdf"
 	"github.com/deadsy/sdfx/vec/conv"
 	v3 "github.com/deadsy/sdfx/vec/v3"
@@ -29,9 +36,10 @@ import (
 
 // DualContouringV1 renders using dual contouring (octree sampling, sharp edges!, automatic simplification)
 type DualContouringV1 struct {
+	meshCells int // number of cells on the longest axis of bounding box. e.g 200
 	// Simplify: how much to simplify (if >=0).
-	// NOTE: Meshing might fail with simplification enabled (FIXME),
-	// but the mesh might can still simplified later using external tools (the main benefit of dual contouring is sharp edges).
+	// Octree nodes are merged while the QEF error of the merged vertex is at most Simplify.
+	// The QEF error is a sum of squared distances, so it scales with the size of the object squared.
 	Simplify float64
 	// RCond [0, 1) is the parameter that controls the accuracy of sharp edges, with lower being more accurate
 	// but it can cause instability leading to large wrong triangles. Leave the default if unsure.
@@ -42,36 +50,49 @@ type DualContouringV1 struct {
 }
 
 // NewDualContouringV1 see DualContouringV1
-func NewDualContouringV1(simplify float64, RCond float64, lockVertices bool) *DualContouringV1 {
-	return &DualContouringV1{Simplify: simplify, RCond: RCond, LockVertices: lockVertices}
+func NewDualContouringV1(meshCells int, simplify float64, RCond float64, lockVertices bool) *DualContouringV1 {
+	return &DualContouringV1{meshCells: meshCells, Simplify: simplify, RCond: RCond, LockVertices: lockVertices}
 }
 
 // Info returns a string describing the rendered volume.
-func (m *DualContouringV1) Info(s sdf.SDF3, meshCells int) string {
+func (m *DualContouringV1) Info(s sdf.SDF3) string {
 	bbSize := s.BoundingBox().Size()
-	resolution := bbSize.MaxComponent() / float64(meshCells)
+	resolution := bbSize.MaxComponent() / float64(m.meshCells)
 	cells := conv.V3ToV3i(bbSize.DivScalar(resolution))
 	return fmt.Sprintf("%dx%dx%d, resolution %.2f", cells.X, cells.Y, cells.Z, resolution)
 }
 
 // Render produces a 3d triangle mesh over the bounding volume of an sdf3.
-func (m *DualContouringV1) Render(s sdf.SDF3, meshCells int, output chan<- *sdf.Triangle3) {
+func (m *DualContouringV1) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
+	m.RenderContext(context.Background(), s, output, nil)
+}
+
+// RenderContext produces a 3d triangle mesh over the bounding volume of an sdf3.
+// It reports progress and stops early if the context is cancelled.
+func (m *DualContouringV1) RenderContext(ctx context.Context, s sdf.SDF3, output sdf.Triangle3Writer, progress render.Progress) error {
+	monitor := render.NewProgressMonitor(ctx, progress)
 	if m.RCond == 0 {
 		m.RCond = 1e-3
 	}
 	// work out the sampling resolution to use
 	bbSize := s.BoundingBox().Size()
-	resolution := bbSize.MaxComponent() / float64(meshCells)
+	resolution := bbSize.MaxComponent() / float64(m.meshCells)
 	cells := conv.V3ToV3i(bbSize.DivScalar(resolution))
 	// Build the octree
 	dcOctreeRootNode := dcNewOctree(cells, m.RCond, m.LockVertices)
-	dcOctreeRootNode.Populate(s)
-	// Simplify it
+	dcOctreeRootNode.populate(s, monitor)
+	if monitor.Stopped() {
+		return monitor.Finish(output)
+	}
+	// Simplify it (the root node is kept, contouring needs an internal node)
 	if m.Simplify >= 0 {
-		dcOctreeRootNode.Simplify(s, m.Simplify)
+		for _, child := range dcOctreeRootNode.children {
+			child.Simplify(s, m.Simplify)
+		}
 	}
 	// Generate the final mesh
 	dcOctreeRootNode.GenerateMesh(output)
+	return monitor.Finish(output)
 }
 
 //-----------------------------------------------------------------------------
@@ -192,21 +213,41 @@ func dcNewOctree(cellCounts v3i.Vec, rCond float64, lockVertices bool) *dcOctree
 	return rootNode
 }
 
-func (node *dcOctree) Populate(d sdf.SDF3) {
+// dcPopulateProgress is the fraction of the render progress used to build the octree.
+const dcPopulateProgress = 0.9
+
+// Populate builds the octree down to the leaf nodes that contain the surface.
+// It returns false if the node does not contain the surface.
+func (node *dcOctree) Populate(d sdf.SDF3) bool {
+	return node.populate(d, nil)
+}
+
+// populate builds the octree, reporting the fraction of the octree volume processed.
+// It stops early (leaving the octree incomplete) if the monitor has stopped.
+func (node *dcOctree) populate(d sdf.SDF3, m *render.ProgressMonitor) bool {
+	if m.Stopped() {
+		return false
+	}
 	minOffset := node.minOffset
 	meshSize := node.meshSize
 	cellCounts := node.cellCounts
-	maxOffset := minOffset.AddScalar(meshSize)
-	// Avoid generating any octree node outside the bounding volume (may filter before reaching leaves)
-	if minOffset.X > (meshSize+cellCounts.X)/2 || maxOffset.X < (meshSize-cellCounts.X)/2 ||
-		minOffset.Y > (meshSize+cellCounts.Y)/2 || maxOffset.Y < (meshSize-cellCounts.Y)/2 ||
-		minOffset.Z > (meshSize+cellCounts.Z)/2 || maxOffset.Z < (meshSize-cellCounts.Z)/2 {
-		return
+	maxOffset := minOffset.AddScalar(node.size)
+	// Avoid generating any octree node outside the bounding volume
+	if minOffset.X >= cellCounts.X || minOffset.Y >= cellCounts.Y || minOffset.Z >= cellCounts.Z {
+		node.progress(m)
+		return false
+	}
+	// Avoid generating any octree node that is entirely inside or outside the surface
+	interval := sdf.EvaluateInterval3(d, sdf.Box3{Min: node.relToSDF(d, minOffset), Max: node.relToSDF(d, maxOffset)})
+	if interval[0] >= 0 || interval[1] < 0 {
+		node.progress(m)
+		return false
 	}
 	childSize := node.size / 2
+	found := false
 	for i := 0; i < 8; i++ {
 		childMinOffset := minOffset.Add(conv.V3ToV3i(conv.V3iToV3(dcChildMinOffsets[i]).MulScalar(float64(childSize))))
-		node.children[i] = &dcOctree{
+		child := &dcOctree{
 			kind:         dcOctreeNodeTypeInternal,
 			minOffset:    childMinOffset,
 			size:         childSize,
@@ -218,12 +259,25 @@ func (node *dcOctree) Populate(d sdf.SDF3) {
 			lockVertices: node.lockVertices,
 		}
 		// Recursive children or a leaf node
+		var ok bool
 		if childSize > 1 {
-			node.children[i].Populate(d)
+			ok = child.populate(d, m)
 		} else {
-			node.children[i].computeOctreeLeaf(d)
+			ok = child.computeOctreeLeaf(d)
+			child.progress(m)
+		}
+		if ok {
+			node.children[i] = child
+			found = true
 		}
 	}
+	return found
+}
+
+// progress reports the volume of a processed node as a fraction of the octree volume.
+func (node *dcOctree) progress(m *render.ProgressMonitor) {
+	k := float64(node.size) / float64(node.meshSize)
+	m.Add(dcPopulateProgress * k * k * k)
 }
 
 func (node *dcOctree) relToSDF(d sdf.SDF3, i v3i.Vec) v3.Vec {
@@ -232,8 +286,9 @@ func (node *dcOctree) relToSDF(d sdf.SDF3, i v3i.Vec) v3.Vec {
 		Div(conv.V3iToV3(node.cellCounts).DivScalar(float64(node.meshSize)))))
 }
 
-// computeOctreeLeaf computes the required leaf information that later will be used for meshing
-func (node *dcOctree) computeOctreeLeaf(d sdf.SDF3) {
+// computeOctreeLeaf computes the required leaf information that later will be used for meshing.
+// It returns false if the leaf does not contain the surface.
+func (node *dcOctree) computeOctreeLeaf(d sdf.SDF3) bool {
 	corners := 0
 	for i := 0; i < 8; i++ {
 		cornerPos := node.relToSDF(d, node.minOffset.Add(dcChildMinOffsets[i]))
@@ -244,7 +299,7 @@ func (node *dcOctree) computeOctreeLeaf(d sdf.SDF3) {
 	}
 	if corners == 0 || corners == 255 {
 		// voxel is fully inside or outside the volume: store nil for this child
-		return
+		return false
 	}
 	// otherwise, the voxel contains the surface, so find the edge intersections
 	const maxCrossings = 6
@@ -281,6 +336,7 @@ func (node *dcOctree) computeOctreeLeaf(d sdf.SDF3) {
 		qef:           qefSolver,
 	}
 	node.kind = dcOctreeNodeTypeLeaf
+	return true
 }
 
 // dcBoundVertexPosition binds the given vertex to their right voxel by using the mass point if out of bounds.
@@ -509,22 +565,26 @@ func dcContourProcessEdge(node [4]*dcOctree, dir int, indexBuffer *[]int) {
 	}
 }
 
-func (node *dcOctree) GenerateMesh(output chan<- *sdf.Triangle3) {
+func (node *dcOctree) GenerateMesh(output sdf.Triangle3Writer) {
 	vertexBuffer := new([]v3.Vec)
 	indexBuffer := new([]int)
 	// Populate buffers
 	node.generateVertexIndices(vertexBuffer)
 	node.contourCellProc(indexBuffer)
 	// Return triangles
+	triangles := make([]*sdf.Triangle3, 0, len(*indexBuffer)/3)
 	for tri := 0; tri < len(*indexBuffer)/3; tri++ {
 		triangle := &sdf.Triangle3{
 			(*vertexBuffer)[(*indexBuffer)[tri*3]],
 			(*vertexBuffer)[(*indexBuffer)[tri*3+1]],
 			(*vertexBuffer)[(*indexBuffer)[tri*3+2]],
 		}
-		//log.Println("Outputting triangle:", triangle)
-		output <- triangle
+		// Collapsed nodes may share a vertex
+		if !triangle.Degenerate(0) {
+			triangles = append(triangles, triangle)
+		}
 	}
+	output.Write(triangles)
 }
 
 // dcQefSolver is used for vertex position estimation (sharp edges!)
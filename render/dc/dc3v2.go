@@ -13,10 +13,12 @@ Based on: https://github.com/emilk/Dual-Contouring/
 package dc
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math"
 
+	"github.com/deadsy/sdfx/render"
 	"github.com/deadsy/sdfx/sdf"
 	"github.com/deadsy/sdfx/vec/conv"
 	"github.com/deadsy/sdfx/vec/v2i"
@@ -77,13 +79,23 @@ func (dc *DualContouringV2) Info(s sdf.SDF3) string {
 }
 
 // Render produces a 3d triangle mesh over the bounding volume of an sdf3.
-func (dc *DualContouringV2) Render(sdf3 sdf.SDF3, output chan<- []*sdf.Triangle3) {
+func (dc *DualContouringV2) Render(sdf3 sdf.SDF3, output sdf.Triangle3Writer) {
+	dc.RenderContext(context.Background(), sdf3, output, nil)
+}
+
+// RenderContext produces a 3d triangle mesh over the bounding volume of an sdf3.
+// It reports progress and stops early if the context is cancelled.
+func (dc *DualContouringV2) RenderContext(ctx context.Context, sdf3 sdf.SDF3, output sdf.Triangle3Writer, progress render.Progress) error {
+	m := render.NewProgressMonitor(ctx, progress)
 	// Place one vertex for each cellIndex
 	_, cells := dc.getCells(sdf3)
 	s2 := &dcSdf{sdf3, map[v3.Vec]float64{}}
-	vertexBuffer, vertexVoxelInfo, vertexVoxelInfoIndexed := dc.placeVertices(s2, cells)
+	vertexBuffer, vertexVoxelInfo, vertexVoxelInfoIndexed := dc.placeVertices(s2, cells, m)
 	// Stitch vertices together generating triangles
-	dc.generateTriangles(s2, vertexBuffer, vertexVoxelInfo, vertexVoxelInfoIndexed, output)
+	if !m.Stopped() {
+		dc.generateTriangles(s2, vertexBuffer, vertexVoxelInfo, vertexVoxelInfoIndexed, output, m)
+	}
+	return m.Finish(output)
 }
 
 func (dc *DualContouringV2) getCells(s sdf.SDF3) (float64, v3i.Vec) {
@@ -148,6 +160,12 @@ var dcFarEdges = []v2i.Vec{
 
 var dcAxes = []v3.Vec{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
 
+// dcBlockCells is the number of cells in a block below which interval culling stops.
+const dcBlockCells = 64
+
+// dcPlaceProgress is the fraction of the render progress used by vertex placement.
+const dcPlaceProgress = 0.9
+
 //-----------------------------------------------------------------------------
 // MAIN ALGORITHM
 //-----------------------------------------------------------------------------
@@ -159,7 +177,7 @@ type dcVoxelInfo struct {
 	cellStart, cellSize v3.Vec
 }
 
-func (dc *DualContouringV2) placeVertices(s *dcSdf, cells v3i.Vec) (buf []v3.Vec, bufMap []*dcVoxelInfo, bufMapIndexed map[v3i.Vec]*dcVoxelInfo) {
+func (dc *DualContouringV2) placeVertices(s *dcSdf, cells v3i.Vec, m *render.ProgressMonitor) (buf []v3.Vec, bufMap []*dcVoxelInfo, bufMapIndexed map[v3i.Vec]*dcVoxelInfo) {
 	// Start with big enough buffers for performance avoiding allocations (but not too big, may expand later)
 	buf = make([]v3.Vec, 0, dcMaxI(32, cells.X*cells.Y*cells.Z/100))
 	bufMap = make([]*dcVoxelInfo, 0, dcMaxI(32, cells.X*cells.Y*cells.Z/100))
@@ -171,31 +189,82 @@ func (dc *DualContouringV2) placeVertices(s *dcSdf, cells v3i.Vec) (buf []v3.Vec
 	bb := s.BoundingBox()
 	cellSize := bb.Size().Div(conv.V3iToV3(cells))
 	cellSizeHalf := cellSize.DivScalar(2)
-	cellIndex := v3i.Vec{}
-	// Iterate over all cells (could be parallelized, synchronizing on each vertex positioned)
-	for cellIndex.X = 0; cellIndex.X < cells.X; cellIndex.X++ {
-		for cellIndex.Y = 0; cellIndex.Y < cells.Y; cellIndex.Y++ {
-			for cellIndex.Z = 0; cellIndex.Z < cells.Z; cellIndex.Z++ {
-				// Generate each vertex (if the surface crosses the voxel)
-				cellStart := bb.Min.Add(cellSize.Mul(conv.V3iToV3(cellIndex)))
-				cellCenter := cellStart.Add(cellSizeHalf)
-				vertexPos := dc.placeVertex(s, cellStart, cellCenter, cellSize, normals[:0], planeDs[:0])
-				if !math.IsInf(vertexPos.X, 0) {
-					bufIndex := len(buf)
-					buf = append(buf, vertexPos)
-					info := &dcVoxelInfo{
-						cellIndex: cellIndex,
-						bufIndex:  bufIndex,
-						cellStart: cellStart,
-						cellSize:  cellSize,
+	// Progress for each cell processed
+	cellProgress := dcPlaceProgress / float64(cells.X*cells.Y*cells.Z)
+	// Place vertices for all cells in a block of cells
+	placeBlock := func(min, max v3i.Vec) {
+		cellIndex := v3i.Vec{}
+		for cellIndex.X = min.X; cellIndex.X < max.X; cellIndex.X++ {
+			for cellIndex.Y = min.Y; cellIndex.Y < max.Y; cellIndex.Y++ {
+				for cellIndex.Z = min.Z; cellIndex.Z < max.Z; cellIndex.Z++ {
+					// Generate each vertex (if the surface crosses the voxel)
+					cellStart := bb.Min.Add(cellSize.Mul(conv.V3iToV3(cellIndex)))
+					cellCenter := cellStart.Add(cellSizeHalf)
+					vertexPos := dc.placeVertex(s, cellStart, cellCenter, cellSize, normals[:0], planeDs[:0])
+					if !math.IsInf(vertexPos.X, 0) {
+						bufIndex := len(buf)
+						buf = append(buf, vertexPos)
+						info := &dcVoxelInfo{
+							cellIndex: cellIndex,
+							bufIndex:  bufIndex,
+							cellStart: cellStart,
+							cellSize:  cellSize,
+						}
+						bufMapIndexed[cellIndex] = info
+						bufMap = append(bufMap, info)
 					}
-					bufMapIndexed[cellIndex] = info
-					bufMap = append(bufMap, info)
-					bufIndex++
 				}
 			}
+			if m.Add(float64((max.Y-min.Y)*(max.Z-min.Z)) * cellProgress) {
+				return
+			}
+		}
+	}
+	is, ok := s.impl.(sdf.IntervalSDF3)
+	if !ok {
+		// Iterate over all cells (could be parallelized, synchronizing on each vertex positioned)
+		placeBlock(v3i.Vec{}, cells)
+		return
+	}
+	// The SDF can bound its distance over a block of cells, so blocks that
+	// are entirely inside or outside the surface can be skipped.
+	var placeBlocks func(min, max v3i.Vec)
+	placeBlocks = func(min, max v3i.Vec) {
+		if m.Stopped() {
+			return
+		}
+		size := v3i.Vec{max.X - min.X, max.Y - min.Y, max.Z - min.Z}
+		b := sdf.Box3{
+			Min: bb.Min.Add(cellSize.Mul(conv.V3iToV3(min))),
+			Max: bb.Min.Add(cellSize.Mul(conv.V3iToV3(max))),
+		}
+		d := is.EvaluateInterval(b)
+		if !d.HasSignChange() {
+			// no corner in the block has a sign change
+			m.Add(float64(size.X*size.Y*size.Z) * cellProgress)
+			return
+		}
+		if size.X*size.Y*size.Z <= dcBlockCells {
+			placeBlock(min, max)
+			return
+		}
+		// split the block on the longest axis
+		mid := max
+		if size.X >= size.Y && size.X >= size.Z {
+			mid.X = min.X + size.X/2
+			placeBlocks(min, mid)
+			placeBlocks(v3i.Vec{mid.X, min.Y, min.Z}, max)
+		} else if size.Y >= size.Z {
+			mid.Y = min.Y + size.Y/2
+			placeBlocks(min, mid)
+			placeBlocks(v3i.Vec{min.X, mid.Y, min.Z}, max)
+		} else {
+			mid.Z = min.Z + size.Z/2
+			placeBlocks(min, mid)
+			placeBlocks(v3i.Vec{min.X, min.Y, mid.Z}, max)
 		}
 	}
+	placeBlocks(v3i.Vec{}, cells)
 	return
 }
 
@@ -286,8 +355,12 @@ func (dc *DualContouringV2) computeCornersInside(s *dcSdf, cellStart v3.Vec, cel
 	return inside
 }
 
-func (dc *DualContouringV2) generateTriangles(s *dcSdf, vertices []v3.Vec, info []*dcVoxelInfo, infoI map[v3i.Vec]*dcVoxelInfo, output chan<- []*sdf.Triangle3) {
+func (dc *DualContouringV2) generateTriangles(s *dcSdf, vertices []v3.Vec, info []*dcVoxelInfo, infoI map[v3i.Vec]*dcVoxelInfo, output sdf.Triangle3Writer, m *render.ProgressMonitor) {
+	voxelProgress := (1 - dcPlaceProgress) / float64(len(info))
 	for _, voxelInfo := range info {
+		if m.Add(voxelProgress) {
+			return
+		}
 		k0 := voxelInfo.bufIndex // k0 is the vertex (index) of this voxel, which will be connected to others
 		cellIndex := voxelInfo.cellIndex
 
@@ -336,7 +409,7 @@ func (dc *DualContouringV2) generateTriangles(s *dcSdf, vertices []v3.Vec, info
 			}
 			// Output built triangles (if not degenerate)
 			if !t[0].Degenerate(0) && !t[1].Degenerate(0) {
-				output <- t
+				output.Write(t)
 			}
 		}
 	}
@@ -0,0 +1,131 @@
+//-----------------------------------------------------------------------------
+/*
+
+Dual Contouring Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package dc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Both dual contouring renderers are Render3 objects with cancellation.
+var _ render.Render3Context = &DualContouringV1{}
+var _ render.Render3Context = &DualContouringV2{}
+
+// checkMesh checks that a mesh is closed and returns its volume.
+func checkMesh(t *testing.T, name string, triangles []*sdf.Triangle3) float64 {
+	t.Helper()
+	// every directed edge should be matched by a reversed edge
+	type edge [2]v3.Vec
+	edges := make(map[edge]int)
+	for _, tri := range triangles {
+		for i := 0; i < 3; i++ {
+			edges[edge{tri[i], tri[(i+1)%3]}]++
+		}
+	}
+	for e, n := range edges {
+		if edges[edge{e[1], e[0]}] != n {
+			t.Errorf("%s: mesh is not closed at %v", name, e)
+			break
+		}
+	}
+	volume := 0.0
+	for _, tri := range triangles {
+		volume += tri[0].Dot(tri[1].Cross(tri[2])) / 6
+	}
+	return volume
+}
+
+func Test_DualContouringV1(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 0)
+	sphere, _ := sdf.Sphere3D(5)
+	cylinder, _ := sdf.Cylinder3D(10, 3, 0)
+	tests := []struct {
+		name   string
+		s      sdf.SDF3
+		volume float64
+	}{
+		{"box", box, 480},
+		{"sphere", sphere, 4.0 / 3.0 * math.Pi * 125},
+		{"box-cylinder", sdf.Difference3D(box, cylinder), 480 - math.Pi*9*6},
+	}
+	for _, x := range tests {
+		full := render.ToTriangles(x.s, NewDualContouringV1(40, -1, 0, false))
+		v0 := checkMesh(t, x.name, full)
+		simple := render.ToTriangles(x.s, NewDualContouringV1(40, 1e-3, 0, false))
+		v1 := checkMesh(t, x.name+" (simplified)", simple)
+		if len(simple) >= len(full) {
+			t.Errorf("%s: simplified mesh has %d triangles, full mesh has %d", x.name, len(simple), len(full))
+		}
+		for _, v := range []float64{v0, v1} {
+			if math.Abs(v-x.volume) > 0.02*x.volume {
+				t.Errorf("%s: volume %f expected %f", x.name, v, x.volume)
+			}
+		}
+	}
+	// flat faces collapse to large triangles
+	triangles := render.ToTriangles(box, NewDualContouringV1(40, 0, 0, false))
+	if len(triangles) > 100 {
+		t.Errorf("box: %d triangles", len(triangles))
+	}
+}
+
+func Test_DualContouringV2(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 0)
+	triangles := render.ToTriangles(box, NewDualContouringDefault(40))
+	v := checkMesh(t, "box", triangles)
+	if math.Abs(v-480) > 0.02*480 {
+		t.Errorf("box: volume %f expected %f", v, 480.0)
+	}
+}
+
+func Test_DualContouring_Progress(t *testing.T) {
+	sphere, _ := sdf.Sphere3D(5)
+	renderers := []struct {
+		name string
+		r    render.Render3
+	}{
+		{"v1", NewDualContouringV1(40, 1e-3, 0, false)},
+		{"v2", NewDualContouringDefault(40)},
+	}
+	for _, x := range renderers {
+		// a complete render finishes at 1
+		last := 0.0
+		_, err := render.ToTrianglesContext(context.Background(), sphere, x.r, func(fraction float64) {
+			if fraction < last {
+				t.Errorf("%s: progress %f after %f", x.name, fraction, last)
+			}
+			last = fraction
+		})
+		if err != nil || last != 1 {
+			t.Errorf("%s: progress %f, error %v", x.name, last, err)
+		}
+		// cancel the render half way through
+		ctx, cancel := context.WithCancel(context.Background())
+		last = 0
+		_, err = render.ToTrianglesContext(ctx, sphere, x.r, func(fraction float64) {
+			last = fraction
+			if fraction >= 0.5 {
+				cancel()
+			}
+		})
+		if !errors.Is(err, context.Canceled) || last >= 1 {
+			t.Errorf("%s: progress %f, error %v after cancellation", x.name, last, err)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
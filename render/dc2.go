@@ -20,6 +20,7 @@ https://www.boristhebrave.com/2018/04/15/dual-contouring-tutorial/
 package render
 
 import (
+	"context"
 	"fmt"
 	"math"
 
@@ -53,6 +54,8 @@ type dc2 struct {
 	hdiag      []float64           // lookup table of square half diagonals
 	s          sdf.SDF2            // the SDF2 to be rendered
 	cache      map[v2i.Vec]float64 // cache of distances
+	top        uint                // level of the top node
+	monitor    *ProgressMonitor    // progress and cancellation
 }
 
 func newDualContouring2(s sdf.SDF2, origin v2.Vec, resolution float64, n uint) *dc2 {
@@ -62,6 +65,7 @@ func newDualContouring2(s sdf.SDF2, origin v2.Vec, resolution float64, n uint) *
 		hdiag:      make([]float64, n),
 		s:          s,
 		cache:      make(map[v2i.Vec]float64),
+		top:        n - 1,
 	}
 	// build a lut for cube half diagonal lengths
 	for i := range dc.hdiag {
@@ -107,9 +111,14 @@ func (dc *dc2) isEmpty(c *node2) bool {
 }
 
 func (dc *dc2) processNode(node *node2) {
-	if !dc.isEmpty(node) {
+	if dc.monitor.Stopped() {
+		return
+	}
+	if dc.isEmpty(node) {
+		dc.progress(node)
+	} else {
 		if node.n == 1 {
-
+			dc.progress(node)
 		} else {
 			// create the sub-nodes
 			n := node.n - 1
@@ -132,6 +141,11 @@ func (dc *dc2) processNode(node *node2) {
 	}
 }
 
+// progress reports the area of a completed node as a fraction of the top node.
+func (dc *dc2) progress(node *node2) {
+	dc.monitor.Add(math.Ldexp(1, 2*(int(node.n)-int(dc.top))))
+}
+
 //-----------------------------------------------------------------------------
 
 func (dc *dc2) corner(vi v2i.Vec) v2.Vec {
@@ -170,7 +184,7 @@ func (dc *dc2) qtOutput(node *node2, output sdf.Line2Writer) {
 }
 
 // dualContouring2D generates line segments for an SDF2 using dual contouring.
-func dualContouring2D(s sdf.SDF2, resolution float64, output sdf.Line2Writer) {
+func dualContouring2D(s sdf.SDF2, resolution float64, output sdf.Line2Writer, m *ProgressMonitor) {
 	// Scale the bounding box about the center to make sure the boundaries
 	// aren't on the object surface.
 	bb := s.BoundingBox()
@@ -183,11 +197,14 @@ func dualContouring2D(s sdf.SDF2, resolution float64, output sdf.Line2Writer) {
 	levels := uint(math.Ceil(math.Log2(longAxis/resolution))) + 1
 	// create the dual contouring state
 	dc := newDualContouring2(s, bb.Min, resolution, levels)
+	dc.monitor = m
 	// process the quadtree, start at the top level
 	topNode := node2{v: v2i.Vec{0, 0}, n: levels - 1}
 	dc.processNode(&topNode)
+	if m.Stopped() {
+		return
+	}
 	dc.qtOutput(&topNode, output)
-	output.Close()
 }
 
 //-----------------------------------------------------------------------------
@@ -214,9 +231,17 @@ func (r *DualContouring2D) Info(s sdf.SDF2) string {
 
 // Render produces a 2d line mesh over the bounding area of an sdf2.
 func (r *DualContouring2D) Render(s sdf.SDF2, output sdf.Line2Writer) {
+	r.RenderContext(context.Background(), s, output, nil)
+}
+
+// RenderContext produces a 2d line mesh over the bounding area of an sdf2.
+// It reports progress and stops early if the context is cancelled.
+func (r *DualContouring2D) RenderContext(ctx context.Context, s sdf.SDF2, output sdf.Line2Writer, progress Progress) error {
+	m := NewProgressMonitor(ctx, progress)
 	bbSize := s.BoundingBox().Size()
 	resolution := bbSize.MaxComponent() / float64(r.meshCells)
-	dualContouring2D(s, resolution, output)
+	dualContouring2D(s, resolution, output, m)
+	return m.Finish(output)
 }
 
 //-----------------------------------------------------------------------------
@@ -0,0 +1,536 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesh Decimation
+
+Reduce the triangle count of a mesh by quadric edge collapse.
+See: Garland & Heckbert, "Surface Simplification Using Quadric Error Metrics"
+
+Each vertex has a quadric giving the sum of squared distances to the planes
+of its original faces. Collapsing an edge merges its vertices at the point
+that minimises the sum of their quadrics, and the quadric value at that point
+is the cost of the collapse. The cheapest edges are collapsed first.
+
+Flat regions (e.g. the faces of a box rendered with marching cubes) have zero
+cost, so they collapse down to a few large triangles.
+
+Sharp edges and boundaries:
+
+Edges with a dihedral angle above the feature angle, and boundary edges,
+add heavily weighted constraint planes (perpendicular to the adjacent faces
+and through the edge) to the quadrics of their vertices. The vertices can
+then move along the edge but not away from it.
+
+Topology:
+
+A collapse is rejected if it would make the mesh non-manifold, flip a
+triangle, or pinch a boundary. Vertices on non-manifold edges are not moved.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// decimateFeatureAngle is the default dihedral angle for sharp feature edges.
+var decimateFeatureAngle = sdf.DtoR(30)
+
+// decimatePenalty is the weight of the feature and boundary constraint planes.
+const decimatePenalty = 1e3
+
+// decimateWeld is the vertex welding tolerance (relative to the mesh size).
+const decimateWeld = 1e-9
+
+// decimateRoundoff is the quadric error (for a unit size mesh) treated as zero.
+const decimateRoundoff = 1e-12
+
+//-----------------------------------------------------------------------------
+// Quadrics
+
+// quadric is a symmetric 4x4 matrix (upper triangle, row major).
+type quadric [10]float64
+
+// planeQuadric returns the quadric for the squared distance to a plane.
+func planeQuadric(n, p v3.Vec) quadric {
+	d := -n.Dot(p)
+	return quadric{
+		n.X * n.X, n.X * n.Y, n.X * n.Z, n.X * d,
+		n.Y * n.Y, n.Y * n.Z, n.Y * d,
+		n.Z * n.Z, n.Z * d,
+		d * d,
+	}
+}
+
+func (a quadric) add(b quadric) quadric {
+	for i := range a {
+		a[i] += b[i]
+	}
+	return a
+}
+
+func (a quadric) mulScalar(k float64) quadric {
+	for i := range a {
+		a[i] *= k
+	}
+	return a
+}
+
+// eval returns the quadric error at a point.
+func (a quadric) eval(p v3.Vec) float64 {
+	return a[0]*p.X*p.X + a[4]*p.Y*p.Y + a[7]*p.Z*p.Z +
+		2*(a[1]*p.X*p.Y+a[2]*p.X*p.Z+a[5]*p.Y*p.Z) +
+		2*(a[3]*p.X+a[6]*p.Y+a[8]*p.Z) + a[9]
+}
+
+// minimum returns the point with the minimum quadric error.
+// It returns false if there is no unique minimum.
+func (a quadric) minimum() (v3.Vec, bool) {
+	// cofactors of the 3x3 matrix
+	c00 := a[4]*a[7] - a[5]*a[5]
+	c01 := a[2]*a[5] - a[1]*a[7]
+	c02 := a[1]*a[5] - a[2]*a[4]
+	c11 := a[0]*a[7] - a[2]*a[2]
+	c12 := a[1]*a[2] - a[0]*a[5]
+	c22 := a[0]*a[4] - a[1]*a[1]
+	det := a[0]*c00 + a[1]*c01 + a[2]*c02
+	// the trace sets the scale of the determinant
+	tr := (a[0] + a[4] + a[7]) / 3
+	if math.Abs(det) <= 1e-9*tr*tr*tr {
+		return v3.Vec{}, false
+	}
+	return v3.Vec{
+		-(c00*a[3] + c01*a[6] + c02*a[8]) / det,
+		-(c01*a[3] + c11*a[6] + c12*a[8]) / det,
+		-(c02*a[3] + c12*a[6] + c22*a[8]) / det,
+	}, true
+}
+
+//-----------------------------------------------------------------------------
+// Collapse Queue
+
+// collapse is a candidate edge collapse.
+type collapse struct {
+	cost   float64 // quadric error of the collapse
+	u, v   int     // the edge (v is merged into u)
+	p      v3.Vec  // new position of u
+	vu, vv int     // vertex versions when the collapse was queued
+}
+
+type collapseHeap []*collapse
+
+func (h collapseHeap) Len() int            { return len(h) }
+func (h collapseHeap) Less(i, j int) bool  { return h[i].cost < h[j].cost }
+func (h collapseHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *collapseHeap) Push(x interface{}) { *h = append(*h, x.(*collapse)) }
+
+func (h *collapseHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+//-----------------------------------------------------------------------------
+// Decimation State
+
+type decimateMesh struct {
+	vertex   []v3.Vec
+	q        []quadric
+	version  []int   // incremented when a vertex changes
+	locked   []bool  // the vertex can't be moved
+	boundary []bool  // the vertex is on a boundary edge
+	vface    [][]int // faces using each vertex (may include dead faces)
+	face     [][3]int
+	dead     []bool // the face has been removed
+	faces    int    // number of live faces
+	queue    collapseHeap
+}
+
+func newDecimateMesh(m *sdf.Mesh3, featureAngle float64) *decimateMesh {
+	d := &decimateMesh{
+		vertex:   m.Vertex,
+		q:        make([]quadric, len(m.Vertex)),
+		version:  make([]int, len(m.Vertex)),
+		locked:   make([]bool, len(m.Vertex)),
+		boundary: make([]bool, len(m.Vertex)),
+		vface:    make([][]int, len(m.Vertex)),
+		face:     m.Index,
+		dead:     make([]bool, len(m.Index)),
+		faces:    len(m.Index),
+	}
+	normal := m.TriangleNormals()
+	// the faces on each edge
+	edges := make(map[sdf.Edge3][]int)
+	for i, f := range d.face {
+		q := planeQuadric(normal[i], d.vertex[f[0]])
+		for j, k := range f {
+			d.q[k] = d.q[k].add(q)
+			d.vface[k] = append(d.vface[k], i)
+			e := sdf.Edge3{k, f[(j+1)%3]}
+			if e[0] > e[1] {
+				e[0], e[1] = e[1], e[0]
+			}
+			edges[e] = append(edges[e], i)
+		}
+	}
+	// constraint planes for boundary and feature edges
+	cosFeature := math.Cos(featureAngle)
+	for e, faces := range edges {
+		var constrain []int
+		switch len(faces) {
+		case 1:
+			constrain = faces
+			d.boundary[e[0]] = true
+			d.boundary[e[1]] = true
+		case 2:
+			n0, n1 := normal[faces[0]], normal[faces[1]]
+			// zero area faces have zero normals, so they aren't features
+			if n0.Length2() > 0 && n1.Length2() > 0 && n0.Dot(n1) < cosFeature {
+				constrain = faces
+			}
+		default:
+			d.locked[e[0]] = true
+			d.locked[e[1]] = true
+		}
+		p0 := d.vertex[e[0]]
+		dir := d.vertex[e[1]].Sub(p0)
+		for _, i := range constrain {
+			n := dir.Cross(normal[i])
+			if n.Length2() == 0 {
+				continue
+			}
+			n = n.Normalize()
+			q := planeQuadric(n, p0).mulScalar(decimatePenalty)
+			d.q[e[0]] = d.q[e[0]].add(q)
+			d.q[e[1]] = d.q[e[1]].add(q)
+		}
+	}
+	// queue the edge collapses
+	for e := range edges {
+		d.push(e[0], e[1])
+	}
+	heap.Init(&d.queue)
+	return d
+}
+
+// newCollapse returns the collapse of edge uv, or nil if the edge can't be collapsed.
+func (d *decimateMesh) newCollapse(u, v int) *collapse {
+	if d.locked[u] || d.locked[v] {
+		return nil
+	}
+	q := d.q[u].add(d.q[v])
+	pu := d.vertex[u]
+	pv := d.vertex[v]
+	mid := pu.Add(pv).MulScalar(0.5)
+	p, ok := q.minimum()
+	// an ill-conditioned solution may be a long way off
+	if !ok || p.Sub(mid).Length() > pu.Sub(pv).Length() {
+		// pick the best of the end points and mid point
+		p = mid
+		for _, x := range []v3.Vec{pu, pv} {
+			if q.eval(x) < q.eval(p) {
+				p = x
+			}
+		}
+	}
+	return &collapse{
+		cost: math.Max(q.eval(p), 0),
+		u:    u,
+		v:    v,
+		p:    p,
+		vu:   d.version[u],
+		vv:   d.version[v],
+	}
+}
+
+// push queues the collapse of edge uv.
+func (d *decimateMesh) push(u, v int) {
+	if c := d.newCollapse(u, v); c != nil {
+		d.queue = append(d.queue, c)
+	}
+}
+
+// stale returns true if the vertices of a queued collapse have changed.
+func (d *decimateMesh) stale(c *collapse) bool {
+	return c.vu != d.version[c.u] || c.vv != d.version[c.v]
+}
+
+// neighbours returns the vertices connected to a vertex.
+func (d *decimateMesh) neighbours(u int) map[int]bool {
+	n := make(map[int]bool)
+	for _, i := range d.vface[u] {
+		if d.dead[i] {
+			continue
+		}
+		for _, k := range d.face[i] {
+			if k != u {
+				n[k] = true
+			}
+		}
+	}
+	return n
+}
+
+// valid returns true if a collapse leaves a manifold mesh without flipped triangles.
+func (d *decimateMesh) valid(c *collapse) bool {
+	nu := d.neighbours(c.u)
+	if !nu[c.v] {
+		return false
+	}
+	// the number of faces on the edge
+	shared := 0
+	for _, i := range d.vface[c.u] {
+		if !d.dead[i] && (d.face[i][0] == c.v || d.face[i][1] == c.v || d.face[i][2] == c.v) {
+			shared++
+		}
+	}
+	// don't pinch a boundary
+	if d.boundary[c.u] && d.boundary[c.v] && shared != 1 {
+		return false
+	}
+	// link condition: the common neighbours are the opposite vertices of the edge faces
+	common := 0
+	for k := range d.neighbours(c.v) {
+		if nu[k] {
+			common++
+		}
+	}
+	if common != shared {
+		return false
+	}
+	// the faces that remain must not flip
+	for _, x := range [2]int{c.u, c.v} {
+		for _, i := range d.vface[x] {
+			if d.dead[i] {
+				continue
+			}
+			f := d.face[i]
+			var p [3]v3.Vec
+			n := 0
+			for j, k := range f {
+				p[j] = d.vertex[k]
+				if k == c.u || k == c.v {
+					p[j] = c.p
+					n++
+				}
+			}
+			if n == 2 {
+				// this face is removed
+				continue
+			}
+			a := d.vertex[f[1]].Sub(d.vertex[f[0]]).Cross(d.vertex[f[2]].Sub(d.vertex[f[0]]))
+			b := p[1].Sub(p[0]).Cross(p[2].Sub(p[0]))
+			// zero area faces may be fixed by the collapse, but don't make new ones
+			if b.Length2() == 0 || (a.Length2() > 0 && a.Dot(b) <= 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// apply merges vertex v into vertex u.
+func (d *decimateMesh) apply(c *collapse) {
+	u, v := c.u, c.v
+	d.vertex[u] = c.p
+	d.q[u] = d.q[u].add(d.q[v])
+	d.boundary[u] = d.boundary[u] || d.boundary[v]
+	for _, i := range d.vface[v] {
+		if d.dead[i] {
+			continue
+		}
+		f := &d.face[i]
+		if f[0] == u || f[1] == u || f[2] == u {
+			d.dead[i] = true
+			d.faces--
+			continue
+		}
+		for j := range f {
+			if f[j] == v {
+				f[j] = u
+			}
+		}
+		d.vface[u] = append(d.vface[u], i)
+	}
+	d.vface[v] = nil
+	d.version[u]++
+	d.version[v]++
+	// remove the dead faces from u
+	faces := d.vface[u][:0]
+	for _, i := range d.vface[u] {
+		if !d.dead[i] {
+			faces = append(faces, i)
+		}
+	}
+	d.vface[u] = faces
+	// queue the new edges
+	for k := range d.neighbours(u) {
+		if c := d.newCollapse(u, k); c != nil {
+			heap.Push(&d.queue, c)
+		}
+	}
+}
+
+// triangles returns the live faces.
+func (d *decimateMesh) triangles() []*sdf.Triangle3 {
+	triangles := make([]*sdf.Triangle3, 0, d.faces)
+	for i, f := range d.face {
+		if !d.dead[i] {
+			triangles = append(triangles, &sdf.Triangle3{d.vertex[f[0]], d.vertex[f[1]], d.vertex[f[2]]})
+		}
+	}
+	return triangles
+}
+
+//-----------------------------------------------------------------------------
+
+// Decimator reduces the triangle count of a mesh by quadric edge collapse.
+type Decimator struct {
+	target       int     // stop at this number of triangles
+	maxError     float64 // stop when the collapse error exceeds this distance
+	featureAngle float64 // dihedral angle of sharp feature edges
+}
+
+// NewDecimator returns a mesh decimator.
+// Decimation stops when the mesh is reduced to the target number of triangles,
+// or when the next edge collapse would move the surface by more than maxError.
+// A target or maxError <= 0 is not used as a stopping condition.
+func NewDecimator(target int, maxError float64) (*Decimator, error) {
+	if target <= 0 && maxError <= 0 {
+		return nil, errors.New("no target triangle count or error bound")
+	}
+	return &Decimator{
+		target:       target,
+		maxError:     maxError,
+		featureAngle: decimateFeatureAngle,
+	}, nil
+}
+
+// SetFeatureAngle sets the dihedral angle (radians) above which edges are preserved as sharp features.
+func (d *Decimator) SetFeatureAngle(a float64) {
+	d.featureAngle = a
+}
+
+// Decimate returns a decimated copy of a triangle mesh.
+func (d *Decimator) Decimate(triangles []*sdf.Triangle3) ([]*sdf.Triangle3, error) {
+	if len(triangles) == 0 {
+		return nil, nil
+	}
+	bb := triangles[0].BoundingBox()
+	for _, t := range triangles[1:] {
+		bb = bb.Extend(t.BoundingBox())
+	}
+	size := bb.Size().MaxComponent()
+	if size == 0 {
+		return nil, errors.New("mesh has zero size")
+	}
+	m, err := sdf.NewMesh3(triangles, decimateWeld*size)
+	if err != nil {
+		return nil, err
+	}
+	// Quadrics are evaluated in expanded form, so round-off is relative to the
+	// distance from the origin. Work in a unit box centered on the origin.
+	center := bb.Center()
+	vertex := m.Vertex
+	m.Vertex = make([]v3.Vec, len(vertex))
+	for i, v := range vertex {
+		m.Vertex[i] = v.Sub(center).DivScalar(size)
+	}
+	dm := newDecimateMesh(m, d.featureAngle)
+	// quadric errors are squared distances
+	maxCost := math.Inf(1)
+	if d.maxError > 0 {
+		maxCost = (d.maxError / size) * (d.maxError / size)
+	}
+	// allow for round-off in collapses with zero cost
+	maxCost += decimateRoundoff
+	for dm.queue.Len() > 0 && dm.faces > d.target {
+		c := heap.Pop(&dm.queue).(*collapse)
+		if dm.stale(c) {
+			continue
+		}
+		if c.cost > maxCost {
+			break
+		}
+		if dm.valid(c) {
+			dm.apply(c)
+		}
+	}
+	// back to the original coordinates (vertices that haven't moved are unchanged)
+	for i, v := range dm.vertex {
+		if dm.version[i] == 0 {
+			dm.vertex[i] = vertex[i]
+		} else {
+			dm.vertex[i] = v.MulScalar(size).Add(center)
+		}
+	}
+	return dm.triangles(), nil
+}
+
+// Decimate returns a decimated copy of a triangle mesh.
+// See NewDecimator for the stopping conditions.
+func Decimate(triangles []*sdf.Triangle3, target int, maxError float64) ([]*sdf.Triangle3, error) {
+	d, err := NewDecimator(target, maxError)
+	if err != nil {
+		return nil, err
+	}
+	return d.Decimate(triangles)
+}
+
+//-----------------------------------------------------------------------------
+
+// decimateWriter collects triangles and writes a decimated mesh when closed.
+type decimateWriter struct {
+	d         *Decimator
+	output    sdf.Triangle3Writer
+	triangles []*sdf.Triangle3
+	lock      sync.Mutex
+}
+
+// Writer returns a triangle writer that decimates the mesh written to it.
+// The decimated mesh is written to the output when the writer is closed.
+// Renderers close the writer, and RenderContext returns the error (Render can't,
+// so use RenderContext to see decimation and output errors).
+func (d *Decimator) Writer(output sdf.Triangle3Writer) sdf.Triangle3Writer {
+	return &decimateWriter{
+		d:      d,
+		output: output,
+	}
+}
+
+func (w *decimateWriter) Write(in []*sdf.Triangle3) error {
+	w.lock.Lock()
+	w.triangles = append(w.triangles, in...)
+	w.lock.Unlock()
+	return nil
+}
+
+// Close decimates the mesh and writes it to the output.
+func (w *decimateWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	triangles, err := w.d.Decimate(w.triangles)
+	w.triangles = nil
+	if err != nil {
+		return err
+	}
+	err = w.output.Write(triangles)
+	if err != nil {
+		return err
+	}
+	return w.output.Close()
+}
+
+//-----------------------------------------------------------------------------
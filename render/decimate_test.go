@@ -0,0 +1,212 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesh Decimation Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// meshVolume returns the signed volume of a closed triangle mesh.
+func meshVolume(triangles []*sdf.Triangle3) float64 {
+	volume := 0.0
+	for _, t := range triangles {
+		volume += t[0].Dot(t[1].Cross(t[2])) / 6
+	}
+	return volume
+}
+
+// checkGenus checks that a mesh is closed with the expected genus.
+func checkGenus(t *testing.T, name string, triangles []*sdf.Triangle3, genus int) {
+	t.Helper()
+	m, err := sdf.NewMesh3(triangles, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := m.Genus()
+	if err != nil {
+		t.Errorf("%s: %s", name, err)
+	} else if g != genus {
+		t.Errorf("%s: genus %d, expected %d", name, g, genus)
+	}
+}
+
+func Test_Decimate(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 0)
+	sphere, _ := sdf.Sphere3D(5)
+	cylinder, _ := sdf.Cylinder3D(10, 3, 0)
+	tests := []struct {
+		name     string
+		s        sdf.SDF3
+		target   int
+		maxError float64
+		genus    int
+	}{
+		{"box", box, 0, 1e-3, 0},
+		{"sphere", sphere, 1000, 0, 0},
+		{"box-cylinder", sdf.Difference3D(box, cylinder), 0, 0.05, 1},
+	}
+	for _, x := range tests {
+		full := ToTriangles(x.s, NewMarchingCubesUniform(60))
+		simple, err := Decimate(full, x.target, x.maxError)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x.target > 0 && len(simple) > x.target {
+			t.Errorf("%s: %d triangles, target %d", x.name, len(simple), x.target)
+		}
+		if len(simple) > len(full)/5 {
+			t.Errorf("%s: %d triangles, full mesh has %d", x.name, len(simple), len(full))
+		}
+		checkGenus(t, x.name, simple, x.genus)
+		v0 := meshVolume(full)
+		v1 := meshVolume(simple)
+		if math.Abs(v1-v0) > 0.01*v0 {
+			t.Errorf("%s: volume %f, full mesh %f", x.name, v1, v0)
+		}
+		// the surface doesn't move far
+		bb0 := x.s.BoundingBox()
+		for _, tri := range simple {
+			for _, v := range tri {
+				if math.Abs(x.s.Evaluate(v)) > 0.02*bb0.Size().MaxComponent() {
+					t.Errorf("%s: vertex %v is %f from the surface", x.name, v, x.s.Evaluate(v))
+					break
+				}
+			}
+		}
+	}
+}
+
+func Test_Decimate_Features(t *testing.T) {
+	// a cube rendered at a resolution aligned with its faces has sharp edges
+	box, _ := sdf.Box3D(v3.Vec{10, 10, 10}, 0)
+	full := ToTriangles(box, NewMarchingCubesUniform(21))
+	simple, err := Decimate(full, 0, 1e-6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGenus(t, "box", simple, 0)
+	bb0 := sdf.Box3{Min: full[0][0], Max: full[0][0]}
+	for _, tri := range full {
+		bb0 = bb0.Extend(tri.BoundingBox())
+	}
+	bb1 := sdf.Box3{Min: simple[0][0], Max: simple[0][0]}
+	for _, tri := range simple {
+		bb1 = bb1.Extend(tri.BoundingBox())
+	}
+	if !bb0.Equals(bb1, 1e-6) {
+		t.Errorf("bounding box %v, expected %v", bb1, bb0)
+	}
+	if len(simple) > 100 {
+		t.Errorf("%d triangles, full mesh has %d", len(simple), len(full))
+	}
+}
+
+func Test_Decimate_Boundary(t *testing.T) {
+	// a flat square grid
+	n := 10
+	var full []*sdf.Triangle3
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			p0 := v3.Vec{float64(i), float64(j), 0}
+			p1 := v3.Vec{float64(i + 1), float64(j), 0}
+			p2 := v3.Vec{float64(i + 1), float64(j + 1), 0}
+			p3 := v3.Vec{float64(i), float64(j + 1), 0}
+			full = append(full, &sdf.Triangle3{p0, p1, p2}, &sdf.Triangle3{p0, p2, p3})
+		}
+	}
+	simple, err := Decimate(full, 0, 1e-6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(simple) >= len(full)/10 {
+		t.Errorf("%d triangles, full mesh has %d", len(simple), len(full))
+	}
+	m, _ := sdf.NewMesh3(simple, 0)
+	// the boundary stays on the square
+	length := 0.0
+	for _, e := range m.BoundaryEdges() {
+		p0 := m.Vertex[e[0]]
+		p1 := m.Vertex[e[1]]
+		length += p1.Sub(p0).Length()
+		for _, p := range []v3.Vec{p0, p1} {
+			if p.X != 0 && p.Y != 0 && p.X != float64(n) && p.Y != float64(n) {
+				t.Errorf("boundary vertex %v is inside the square", p)
+			}
+		}
+	}
+	if math.Abs(length-float64(4*n)) > 1e-6 {
+		t.Errorf("boundary length %f, expected %d", length, 4*n)
+	}
+	area := 0.0
+	for _, tri := range simple {
+		area += tri[1].Sub(tri[0]).Cross(tri[2].Sub(tri[0])).Z / 2
+	}
+	if math.Abs(area-float64(n*n)) > 1e-6 {
+		t.Errorf("area %f, expected %d", area, n*n)
+	}
+}
+
+func Test_Decimate_Writer(t *testing.T) {
+	sphere, _ := sdf.Sphere3D(5)
+	r := NewMarchingCubesUniform(40)
+	d, err := NewDecimator(500, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0, _ := d.Decimate(ToTriangles(sphere, r))
+	t1 := ToTriangles(sphere, &decimateRender{r, d})
+	if len(t0) != len(t1) {
+		t.Errorf("%d triangles from the writer, expected %d", len(t1), len(t0))
+	}
+	if _, err := NewDecimator(0, 0); err == nil {
+		t.Errorf("expected an error with no stopping condition")
+	}
+	// output errors are returned by the renderer
+	err = r.RenderContext(context.Background(), sphere, d.Writer(&failTriangles{}), nil)
+	if err != errFailTriangles {
+		t.Errorf("expected the output error, got %v", err)
+	}
+}
+
+var errFailTriangles = errors.New("write failed")
+
+// failTriangles is a triangle writer that fails.
+type failTriangles struct{}
+
+func (w *failTriangles) Write(in []*sdf.Triangle3) error {
+	return errFailTriangles
+}
+
+func (w *failTriangles) Close() error {
+	return nil
+}
+
+// decimateRender renders through a decimating writer.
+type decimateRender struct {
+	r Render3
+	d *Decimator
+}
+
+func (x *decimateRender) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
+	x.r.Render(s, x.d.Writer(output))
+}
+
+func (x *decimateRender) Info(s sdf.SDF3) string {
+	return x.r.Info(s)
+}
+
+//-----------------------------------------------------------------------------
@@ -9,8 +9,10 @@ Output a 2D line set to a DXF file.
 package render
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"sync"
 
 	"github.com/deadsy/sdfx/sdf"
@@ -53,6 +55,23 @@ func (d *DXF) Lines(lines []*sdf.Line2) {
 	}
 }
 
+// Contours adds a set of contours (as polylines) to a dxf drawing object.
+func (d *DXF) Contours(contours []*Contour) {
+	d.drawing.ChangeLayer("Lines")
+	d.polylines(contours)
+}
+
+// polylines adds a set of contours to the current layer of a dxf drawing object.
+func (d *DXF) polylines(contours []*Contour) {
+	for _, c := range contours {
+		vertices := make([][]float64, len(c.Points))
+		for i, p := range c.Points {
+			vertices[i] = []float64{p.X, p.Y}
+		}
+		d.drawing.LwPolyline(c.Closed, vertices...)
+	}
+}
+
 // Points adds a set of points to a dxf drawing object.
 func (d *DXF) Points(s v2.VecSet, r float64) {
 	d.drawing.ChangeLayer("Points")
@@ -108,11 +127,8 @@ func SaveDXF(path string, mesh []*sdf.Line2) error {
 //-----------------------------------------------------------------------------
 
 // writeDXF writes a stream of line segments to a DXF file.
-func writeDXF(wg *sync.WaitGroup, path string) (chan<- []*sdf.Line2, error) {
-
-	d := NewDXF(path)
-	d.drawing.ChangeLayer("Lines")
-
+// Any error (including cancellation of the context) is stored in *err by the time wg.Wait() returns.
+func writeDXF(ctx context.Context, wg *sync.WaitGroup, w io.Writer, err *error) chan<- []*sdf.Line2 {
 	// External code writes line segments to this channel.
 	// This goroutine reads the channel and writes line segments to the file.
 	c := make(chan []*sdf.Line2)
@@ -120,21 +136,31 @@ func writeDXF(wg *sync.WaitGroup, path string) (chan<- []*sdf.Line2, error) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for ls := range c {
-			for _, l := range ls {
-				p0 := l[0]
-				p1 := l[1]
-				d.drawing.Line(p0.X, p0.Y, 0, p1.X, p1.Y, 0)
-			}
-		}
-		err := d.Save()
-		if err != nil {
-			fmt.Printf("%s\n", err)
-			return
+		*err = streamDXF(ctx, w, c)
+		// keep reading the channel so the renderer doesn't block
+		for range c {
 		}
 	}()
 
-	return c, nil
+	return c
+}
+
+// streamDXF reads line segments from a channel and writes them to a DXF file as polylines.
+func streamDXF(ctx context.Context, w io.Writer, c <-chan []*sdf.Line2) error {
+	var lines []*sdf.Line2
+	for ls := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lines = append(lines, ls...)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d := NewDXF("")
+	d.Contours(Contours(lines, 0))
+	_, err := d.drawing.WriteTo(w)
+	return err
 }
 
 //-----------------------------------------------------------------------------
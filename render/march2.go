@@ -11,6 +11,7 @@ Convert an SDF2 boundary to a set of line segments.
 package render
 
 import (
+	"context"
 	"fmt"
 	"math"
 
@@ -74,7 +75,7 @@ func (l *lineCache) get(x, y int) float64 {
 
 //-----------------------------------------------------------------------------
 
-func marchingSquares(s sdf.SDF2, resolution float64, output sdf.Line2Writer) {
+func marchingSquares(s sdf.SDF2, resolution float64, output sdf.Line2Writer, m *ProgressMonitor) {
 	// Scale the bounding box about the center to make sure the boundaries
 	// aren't on the object surface.
 	bb := s.BoundingBox()
@@ -119,8 +120,10 @@ func marchingSquares(s sdf.SDF2, resolution float64, output sdf.Line2Writer) {
 			p.Y += dy
 		}
 		p.X += dx
+		if m.Add(1 / float64(nx)) {
+			break
+		}
 	}
-	output.Close()
 }
 
 //-----------------------------------------------------------------------------
@@ -147,9 +150,17 @@ func (r *MarchingSquaresUniform) Info(s sdf.SDF2) string {
 
 // Render produces a 2d line mesh over the bounding area of an sdf2.
 func (r *MarchingSquaresUniform) Render(s sdf.SDF2, output sdf.Line2Writer) {
+	r.RenderContext(context.Background(), s, output, nil)
+}
+
+// RenderContext produces a 2d line mesh over the bounding area of an sdf2.
+// It reports progress and stops early if the context is cancelled.
+func (r *MarchingSquaresUniform) RenderContext(ctx context.Context, s sdf.SDF2, output sdf.Line2Writer, progress Progress) error {
+	m := NewProgressMonitor(ctx, progress)
 	bbSize := s.BoundingBox().Size()
 	resolution := bbSize.MaxComponent() / float64(r.meshCells)
-	marchingSquares(s, resolution, output)
+	marchingSquares(s, resolution, output, m)
+	return m.Finish(output)
 }
 
 //-----------------------------------------------------------------------------
@@ -12,6 +12,7 @@ Uses quadtree space subdivision.
 package render
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sync"
@@ -39,6 +40,8 @@ type dcache2 struct {
 	s          sdf.SDF2            // the SDF2 to be rendered
 	cache      map[v2i.Vec]float64 // cache of distances
 	lock       sync.RWMutex        // lock the the cache during reads/writes
+	top        uint                // level of the top square
+	monitor    *ProgressMonitor    // progress and cancellation
 }
 
 func newDcache2(s sdf.SDF2, origin v2.Vec, resolution float64, n uint) *dcache2 {
@@ -48,6 +51,7 @@ func newDcache2(s sdf.SDF2, origin v2.Vec, resolution float64, n uint) *dcache2
 		hdiag:      make([]float64, n),
 		s:          s,
 		cache:      make(map[v2i.Vec]float64),
+		top:        n - 1,
 	}
 	// build a lut for cube half diagonal lengths
 	for i := range dc.hdiag {
@@ -89,6 +93,13 @@ func (dc *dcache2) evaluate(vi v2i.Vec) (v2.Vec, float64) {
 
 // isEmpty returns true if the square contains no SDF surface
 func (dc *dcache2) isEmpty(c *square) bool {
+	if s, ok := dc.s.(sdf.IntervalSDF2); ok {
+		// bound the SDF2 over the square
+		s0 := dc.origin.Add(conv.V2iToV2(c.v).MulScalar(dc.resolution))
+		s1 := s0.AddScalar(float64(int(1)<<c.n) * dc.resolution)
+		d := s.EvaluateInterval(sdf.Box2{Min: s0, Max: s1})
+		return !d.HasSignChange()
+	}
 	// evaluate the SDF2 at the center of the square
 	s := 1 << (c.n - 1) // half side
 	_, d := dc.evaluate(c.v.AddScalar(s))
@@ -98,7 +109,12 @@ func (dc *dcache2) isEmpty(c *square) bool {
 
 // Process a square. Generate line segments, or more squares.
 func (dc *dcache2) processSquare(c *square, output sdf.Line2Writer) {
-	if !dc.isEmpty(c) {
+	if dc.monitor.Stopped() {
+		return
+	}
+	if dc.isEmpty(c) {
+		dc.progress(c)
+	} else {
 		if c.n == 1 {
 			// this square is at the required resolution
 			c0, d0 := dc.evaluate(c.v.Add(v2i.Vec{0, 0}))
@@ -109,6 +125,7 @@ func (dc *dcache2) processSquare(c *square, output sdf.Line2Writer) {
 			values := [4]float64{d0, d1, d2, d3}
 			// output the line(s) for this square
 			output.Write(msToLines(corners, values, 0))
+			dc.progress(c)
 		} else {
 			// process the sub squares
 			n := c.n - 1
@@ -122,10 +139,15 @@ func (dc *dcache2) processSquare(c *square, output sdf.Line2Writer) {
 	}
 }
 
+// progress reports the area of a completed square as a fraction of the top square.
+func (dc *dcache2) progress(c *square) {
+	dc.monitor.Add(math.Ldexp(1, 2*(int(c.n)-int(dc.top))))
+}
+
 //-----------------------------------------------------------------------------
 
 // marchingSquaresQuadtree generates line segments for an SDF2 using quadtree subdivision.
-func marchingSquaresQuadtree(s sdf.SDF2, resolution float64, output sdf.Line2Writer) {
+func marchingSquaresQuadtree(s sdf.SDF2, resolution float64, output sdf.Line2Writer, m *ProgressMonitor) {
 	// Scale the bounding box about the center to make sure the boundaries
 	// aren't on the object surface.
 	bb := s.BoundingBox()
@@ -138,9 +160,9 @@ func marchingSquaresQuadtree(s sdf.SDF2, resolution float64, output sdf.Line2Wri
 	levels := uint(math.Ceil(math.Log2(longAxis/resolution))) + 1
 	// create the distance cache
 	dc := newDcache2(s, bb.Min, resolution, levels)
+	dc.monitor = m
 	// process the quadtree, start at the top level
 	dc.processSquare(&square{v2i.Vec{0, 0}, levels - 1}, output)
-	output.Close()
 }
 
 //-----------------------------------------------------------------------------
@@ -167,9 +189,17 @@ func (r *MarchingSquaresQuadtree) Info(s sdf.SDF2) string {
 
 // Render produces a 2d line mesh over the bounding area of an sdf2.
 func (r *MarchingSquaresQuadtree) Render(s sdf.SDF2, output sdf.Line2Writer) {
+	r.RenderContext(context.Background(), s, output, nil)
+}
+
+// RenderContext produces a 2d line mesh over the bounding area of an sdf2.
+// It reports progress and stops early if the context is cancelled.
+func (r *MarchingSquaresQuadtree) RenderContext(ctx context.Context, s sdf.SDF2, output sdf.Line2Writer, progress Progress) error {
+	m := NewProgressMonitor(ctx, progress)
 	bbSize := s.BoundingBox().Size()
 	resolution := bbSize.MaxComponent() / float64(r.meshCells)
-	marchingSquaresQuadtree(s, resolution, output)
+	marchingSquaresQuadtree(s, resolution, output, m)
+	return m.Finish(output)
 }
 
 //-----------------------------------------------------------------------------
@@ -11,6 +11,7 @@ Convert an SDF3 to a triangle mesh.
 package render
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"runtime"
@@ -132,7 +133,7 @@ func (l *layerYZ) Get(x, y, z int) float64 {
 
 //-----------------------------------------------------------------------------
 
-func marchingCubes(s sdf.SDF3, box sdf.Box3, step float64, output sdf.Triangle3Writer) {
+func marchingCubes(s sdf.SDF3, box sdf.Box3, step float64, output sdf.Triangle3Writer, m *ProgressMonitor) {
 
 	size := box.Size()
 	base := box.Min
@@ -186,6 +187,9 @@ func marchingCubes(s sdf.SDF3, box sdf.Box3, step float64, output sdf.Triangle3W
 			p.Y += dy
 		}
 		p.X += dx
+		if m.Add(1 / float64(nx)) {
+			return
+		}
 	}
 }
 
@@ -287,6 +291,13 @@ func (r *MarchingCubesUniform) Info(s sdf.SDF3) string {
 
 // Render produces a 3d triangle mesh over the bounding volume of an sdf3.
 func (r *MarchingCubesUniform) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
+	r.RenderContext(context.Background(), s, output, nil)
+}
+
+// RenderContext produces a 3d triangle mesh over the bounding volume of an sdf3.
+// It reports progress and stops early if the context is cancelled.
+func (r *MarchingCubesUniform) RenderContext(ctx context.Context, s sdf.SDF3, output sdf.Triangle3Writer, progress Progress) error {
+	m := NewProgressMonitor(ctx, progress)
 	// work out the region we will sample
 	bb0 := s.BoundingBox()
 	bb0Size := bb0.Size()
@@ -295,8 +306,8 @@ func (r *MarchingCubesUniform) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
 	bb1Size = bb1Size.Ceil().AddScalar(1)
 	bb1Size = bb1Size.MulScalar(meshInc)
 	bb := sdf.NewBox3(bb0.Center(), bb1Size)
-	marchingCubes(s, bb, meshInc, output)
-	output.Close()
+	marchingCubes(s, bb, meshInc, output, m)
+	return m.Finish(output)
 }
 
 //-----------------------------------------------------------------------------
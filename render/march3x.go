@@ -12,6 +12,7 @@ Uses octree space subdivision.
 package render
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sync"
@@ -41,6 +42,8 @@ type dcache3 struct {
 	s          sdf.SDF3            // the SDF3 to be rendered
 	cache      map[v3i.Vec]float64 // cache of distances
 	lock       sync.RWMutex        // lock the the cache during reads/writes
+	top        uint                // level of the top cube
+	monitor    *ProgressMonitor    // progress and cancellation
 }
 
 func newDcache3(s sdf.SDF3, origin v3.Vec, resolution float64, n uint) *dcache3 {
@@ -52,6 +55,7 @@ func newDcache3(s sdf.SDF3, origin v3.Vec, resolution float64, n uint) *dcache3
 		hdiag:      make([]float64, n),
 		s:          s,
 		cache:      make(map[v3i.Vec]float64),
+		top:        n - 1,
 	}
 	// build a lut for cube half diagonal lengths
 	for i := range dc.hdiag {
@@ -93,6 +97,13 @@ func (dc *dcache3) evaluate(vi v3i.Vec) (v3.Vec, float64) {
 
 // isEmpty returns true if the cube contains no SDF surface
 func (dc *dcache3) isEmpty(c *cube) bool {
+	if s, ok := dc.s.(sdf.IntervalSDF3); ok {
+		// bound the SDF3 over the cube
+		s0 := dc.origin.Add(conv.V3iToV3(c.v).MulScalar(dc.resolution))
+		s1 := s0.AddScalar(float64(int(1)<<c.n) * dc.resolution)
+		d := s.EvaluateInterval(sdf.Box3{Min: s0, Max: s1})
+		return !d.HasSignChange()
+	}
 	// evaluate the SDF3 at the center of the cube
 	s := 1 << (c.n - 1) // half side
 	_, d := dc.evaluate(c.v.AddScalar(s))
@@ -102,7 +113,12 @@ func (dc *dcache3) isEmpty(c *cube) bool {
 
 // Process a cube. Generate triangles, or more cubes.
 func (dc *dcache3) processCube(c *cube, output sdf.Triangle3Writer) {
-	if !dc.isEmpty(c) {
+	if dc.monitor.Stopped() {
+		return
+	}
+	if dc.isEmpty(c) {
+		dc.progress(c)
+	} else {
 		if c.n == 1 {
 			// this cube is at the required resolution
 			c0, d0 := dc.evaluate(c.v.Add(v3i.Vec{0, 0, 0}))
@@ -117,6 +133,7 @@ func (dc *dcache3) processCube(c *cube, output sdf.Triangle3Writer) {
 			values := [8]float64{d0, d1, d2, d3, d4, d5, d6, d7}
 			// output the triangle(s) for this cube
 			output.Write(mcToTriangles(corners, values, 0))
+			dc.progress(c)
 		} else {
 			// process the sub cubes
 			n := c.n - 1
@@ -134,10 +151,15 @@ func (dc *dcache3) processCube(c *cube, output sdf.Triangle3Writer) {
 	}
 }
 
+// progress reports the volume of a completed cube as a fraction of the top cube.
+func (dc *dcache3) progress(c *cube) {
+	dc.monitor.Add(math.Ldexp(1, 3*(int(c.n)-int(dc.top))))
+}
+
 //-----------------------------------------------------------------------------
 
 // marchingCubesOctree generates a triangle mesh for an SDF3 using octree subdivision.
-func marchingCubesOctree(s sdf.SDF3, resolution float64, output sdf.Triangle3Writer) {
+func marchingCubesOctree(s sdf.SDF3, resolution float64, output sdf.Triangle3Writer, m *ProgressMonitor) {
 	// Scale the bounding box about the center to make sure the boundaries
 	// aren't on the object surface.
 	bb := s.BoundingBox()
@@ -150,9 +172,9 @@ func marchingCubesOctree(s sdf.SDF3, resolution float64, output sdf.Triangle3Wri
 	levels := uint(math.Ceil(math.Log2(longAxis/resolution))) + 1
 	// create the distance cache
 	dc := newDcache3(s, bb.Min, resolution, levels)
+	dc.monitor = m
 	// process the octree, start at the top level
 	dc.processCube(&cube{v3i.Vec{0, 0, 0}, levels - 1}, output)
-	output.Close()
 }
 
 //-----------------------------------------------------------------------------
@@ -179,10 +201,18 @@ func (r *MarchingCubesOctree) Info(s sdf.SDF3) string {
 
 // Render produces a 3d triangle mesh over the bounding volume of an sdf3.
 func (r *MarchingCubesOctree) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
+	r.RenderContext(context.Background(), s, output, nil)
+}
+
+// RenderContext produces a 3d triangle mesh over the bounding volume of an sdf3.
+// It reports progress and stops early if the context is cancelled.
+func (r *MarchingCubesOctree) RenderContext(ctx context.Context, s sdf.SDF3, output sdf.Triangle3Writer, progress Progress) error {
+	m := NewProgressMonitor(ctx, progress)
 	// work out the sampling resolution to use
 	bbSize := s.BoundingBox().Size()
 	resolution := bbSize.MaxComponent() / float64(r.meshCells)
-	marchingCubesOctree(s, resolution, output)
+	marchingCubesOctree(s, resolution, output, m)
+	return m.Finish(output)
 }
 
 //-----------------------------------------------------------------------------
@@ -0,0 +1,104 @@
+//-----------------------------------------------------------------------------
+/*
+
+Indexed Mesh Output
+
+OBJ and PLY files have a list of vertices and a list of faces that index
+into the vertex list. The rendered triangles are collected into an indexed
+mesh (with vertex welding) before the file is written.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// MeshOptions are the options for indexed mesh (OBJ and PLY) output.
+// A nil *MeshOptions uses the defaults (the zero value).
+type MeshOptions struct {
+	Tolerance float64 // vertex welding distance (0 for a default relative to the mesh size)
+	Normals   bool    // write per-vertex normals
+	ASCII     bool    // write an ASCII PLY file (the default is binary)
+}
+
+// meshOptions returns the options to use for indexed mesh output.
+func meshOptions(opts *MeshOptions) *MeshOptions {
+	if opts == nil {
+		return &MeshOptions{}
+	}
+	return opts
+}
+
+// meshWeld is the default vertex welding distance relative to the mesh size.
+// Renderers can generate the same vertex with small round-off differences
+// in adjacent cells, so the vertices aren't welded exactly.
+const meshWeld = 1e-9
+
+// meshEncoder writes an indexed mesh (with optional vertex normals) to a file.
+type meshEncoder func(w io.Writer, m *sdf.Mesh3, normals []v3.Vec) error
+
+//-----------------------------------------------------------------------------
+
+// writeMesh3 writes a stream of triangles to an indexed mesh file.
+// Any error (including cancellation of the context) is stored in *err by the time wg.Wait() returns.
+func writeMesh3(ctx context.Context, wg *sync.WaitGroup, w io.Writer, opts *MeshOptions, encode meshEncoder, err *error) chan<- []*sdf.Triangle3 {
+	// External code writes triangles to this channel.
+	// This goroutine reads the channel and writes the mesh to the file.
+	c := make(chan []*sdf.Triangle3)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		*err = streamMesh3(ctx, w, c, opts, encode)
+		// keep reading the channel so the renderer doesn't block
+		for range c {
+		}
+	}()
+
+	return c
+}
+
+// streamMesh3 reads triangles from a channel, welds them into an indexed mesh and encodes it.
+func streamMesh3(ctx context.Context, w io.Writer, c <-chan []*sdf.Triangle3, opts *MeshOptions, encode meshEncoder) error {
+	// read triangles from the channel
+	var triangles []*sdf.Triangle3
+	for ts := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		triangles = append(triangles, ts...)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// build the indexed mesh
+	tolerance := opts.Tolerance
+	if tolerance == 0 && len(triangles) != 0 {
+		bb := triangles[0].BoundingBox()
+		for _, t := range triangles {
+			bb = bb.Extend(t.BoundingBox())
+		}
+		tolerance = meshWeld * bb.Size().MaxComponent()
+	}
+	m, err := sdf.NewMesh3(triangles, tolerance)
+	if err != nil {
+		return err
+	}
+	var normals []v3.Vec
+	if opts.Normals {
+		normals = m.VertexNormals()
+	}
+	return encode(w, m, normals)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,284 @@
+//-----------------------------------------------------------------------------
+/*
+
+Indexed Mesh Output Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// readOBJ reads the vertices, normals and faces from an OBJ file.
+func readOBJ(t *testing.T, data []byte) ([]v3.Vec, []v3.Vec, [][3]int) {
+	t.Helper()
+	var vs, ns []v3.Vec
+	var fs [][3]int
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		var v v3.Vec
+		var f [3]int
+		var k [3]int
+		switch {
+		case strings.HasPrefix(line, "v "):
+			fmt.Sscanf(line, "v %g %g %g", &v.X, &v.Y, &v.Z)
+			vs = append(vs, v)
+		case strings.HasPrefix(line, "vn "):
+			fmt.Sscanf(line, "vn %g %g %g", &v.X, &v.Y, &v.Z)
+			ns = append(ns, v)
+		case strings.Contains(line, "//"):
+			fmt.Sscanf(line, "f %d//%d %d//%d %d//%d", &f[0], &k[0], &f[1], &k[1], &f[2], &k[2])
+			if f != k {
+				t.Errorf("vertex and normal indices differ: %s", line)
+			}
+			fs = append(fs, [3]int{f[0] - 1, f[1] - 1, f[2] - 1})
+		case strings.HasPrefix(line, "f "):
+			fmt.Sscanf(line, "f %d %d %d", &f[0], &f[1], &f[2])
+			fs = append(fs, [3]int{f[0] - 1, f[1] - 1, f[2] - 1})
+		}
+	}
+	return vs, ns, fs
+}
+
+// readPLY reads the vertex data and faces from a PLY file.
+func readPLY(t *testing.T, data []byte) ([][]float32, [][3]int) {
+	t.Helper()
+	r := bufio.NewReader(bytes.NewReader(data))
+	var ascii bool
+	var nv, nf, nprop int
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "end_header" {
+			break
+		}
+		fmt.Sscanf(line, "element vertex %d", &nv)
+		fmt.Sscanf(line, "element face %d", &nf)
+		if line == "format ascii 1.0" {
+			ascii = true
+		}
+		if strings.HasPrefix(line, "property float") {
+			nprop++
+		}
+	}
+	vs := make([][]float32, nv)
+	fs := make([][3]int, nf)
+	for i := range vs {
+		vs[i] = make([]float32, nprop)
+		if ascii {
+			for j := range vs[i] {
+				fmt.Fscan(r, &vs[i][j])
+			}
+		} else {
+			binary.Read(r, binary.LittleEndian, vs[i])
+		}
+	}
+	for i := range fs {
+		var n int
+		if ascii {
+			fmt.Fscan(r, &n, &fs[i][0], &fs[i][1], &fs[i][2])
+		} else {
+			var f plyFace
+			binary.Read(r, binary.LittleEndian, &f)
+			n = int(f.Count)
+			fs[i] = [3]int{int(f.Index[0]), int(f.Index[1]), int(f.Index[2])}
+		}
+		if n != 3 {
+			t.Fatalf("face %d has %d vertices", i, n)
+		}
+	}
+	if !ascii {
+		if _, err := r.ReadByte(); err != io.EOF {
+			t.Errorf("trailing data in binary file")
+		}
+	}
+	return vs, fs
+}
+
+// checkIndexedMesh checks that an indexed mesh is closed with the expected size.
+func checkIndexedMesh(t *testing.T, name string, vs []v3.Vec, fs [][3]int, m *sdf.Mesh3) {
+	t.Helper()
+	if len(vs) != len(m.Vertex) || len(fs) != len(m.Index) {
+		t.Errorf("%s: %d vertices, %d faces, expected %d, %d", name, len(vs), len(fs), len(m.Vertex), len(m.Index))
+		return
+	}
+	closed := &sdf.Mesh3{Vertex: vs, Index: fs}
+	if !closed.IsClosed() || !closed.IsOriented() {
+		t.Errorf("%s: expected a closed, oriented mesh", name)
+	}
+}
+
+func Test_WriteOBJ(t *testing.T) {
+	s, _ := sdf.Sphere3D(5)
+	r := NewMarchingCubesOctree(30)
+	m, _ := sdf.NewMesh3(ToTriangles(s, r), 1e-6)
+	for _, normals := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := WriteOBJ(context.Background(), &buf, s, r, &MeshOptions{Normals: normals}, nil); err != nil {
+			t.Fatal(err)
+		}
+		vs, ns, fs := readOBJ(t, buf.Bytes())
+		checkIndexedMesh(t, "obj", vs, fs, m)
+		if !normals && len(ns) != 0 {
+			t.Errorf("unexpected normals")
+		}
+		if normals {
+			if len(ns) != len(vs) {
+				t.Fatalf("%d normals, %d vertices", len(ns), len(vs))
+			}
+			// sphere normals point away from the center
+			for i, n := range ns {
+				if n.Dot(vs[i].Normalize()) < 0.95 {
+					t.Errorf("%v: normal %v", vs[i], n)
+					break
+				}
+			}
+		}
+	}
+	// tolerance based welding merges more vertices
+	var buf bytes.Buffer
+	if err := WriteOBJ(context.Background(), &buf, s, r, &MeshOptions{Tolerance: 0.2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if vs, _, _ := readOBJ(t, buf.Bytes()); len(vs) >= len(m.Vertex) {
+		t.Errorf("%d vertices after welding, %d before", len(vs), len(m.Vertex))
+	}
+	if err := WriteOBJ(context.Background(), &buf, s, r, &MeshOptions{Tolerance: -1}, nil); err == nil {
+		t.Errorf("expected an error for a negative tolerance")
+	}
+}
+
+func Test_WritePLY(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 1)
+	r := NewMarchingCubesOctree(30)
+	m, _ := sdf.NewMesh3(ToTriangles(s, r), 1e-6)
+	var data [2][][]float32
+	var faces [2][][3]int
+	for i, ascii := range []bool{true, false} {
+		var buf bytes.Buffer
+		if err := WritePLY(context.Background(), &buf, s, r, &MeshOptions{ASCII: ascii, Normals: true}, nil); err != nil {
+			t.Fatal(err)
+		}
+		data[i], faces[i] = readPLY(t, buf.Bytes())
+		vs := make([]v3.Vec, len(data[i]))
+		for j, d := range data[i] {
+			if len(d) != 6 {
+				t.Fatalf("%d vertex properties", len(d))
+			}
+			vs[j] = v3.Vec{float64(d[0]), float64(d[1]), float64(d[2])}
+		}
+		checkIndexedMesh(t, fmt.Sprintf("ply (ascii %v)", ascii), vs, faces[i], m)
+	}
+	// ascii and binary files have the same content
+	for i := range data[0] {
+		for j := range data[0][i] {
+			if data[0][i][j] != data[1][i][j] {
+				t.Fatalf("vertex %d: ascii %v, binary %v", i, data[0][i], data[1][i])
+			}
+		}
+	}
+	for i := range faces[0] {
+		if faces[0][i] != faces[1][i] {
+			t.Fatalf("face %d: ascii %v, binary %v", i, faces[0][i], faces[1][i])
+		}
+	}
+	// write errors are returned
+	if err := WritePLY(context.Background(), &failWriter{n: 100}, s, r, nil, nil); err != errWrite {
+		t.Errorf("expected a write error, got %v", err)
+	}
+}
+
+func Test_LoadOBJ(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sdfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// round trip
+	s, _ := sdf.Sphere3D(5)
+	r := NewMarchingCubesOctree(30)
+	path := filepath.Join(dir, "sphere.obj")
+	if err := ToOBJ(s, path, r, &MeshOptions{Normals: true}); err != nil {
+		t.Fatal(err)
+	}
+	mesh := ToTriangles(s, r)
+	loaded, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(mesh) {
+		t.Fatalf("%d triangles, expected %d", len(loaded), len(mesh))
+	}
+	for i := range mesh {
+		if !loaded[i].Equals(mesh[i], 1e-9) {
+			t.Errorf("triangle %d is %v, expected %v", i, loaded[i], mesh[i])
+			break
+		}
+	}
+
+	// polygons, texture/normal indices and relative indices
+	obj := `# a unit square and a triangle
+o square
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vn 0 0 1
+f 1/1/1 2/1/1 3/1/1 4/1/1
+v 0 0 1
+v 1 0 1
+v 0 1 1
+usemtl red
+f -3//1 -2//1 -1//1
+`
+	loaded, err = loadOBJ(strings.NewReader(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*sdf.Triangle3{
+		{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}},
+		{{0, 0, 0}, {1, 1, 0}, {0, 1, 0}},
+		{{0, 0, 1}, {1, 0, 1}, {0, 1, 1}},
+	}
+	if len(loaded) != len(expected) {
+		t.Fatalf("%d triangles, expected %d", len(loaded), len(expected))
+	}
+	for i := range expected {
+		if !loaded[i].Equals(expected[i], 0) {
+			t.Errorf("triangle %d is %v, expected %v", i, loaded[i], expected[i])
+		}
+	}
+
+	// errors
+	for _, bad := range []string{"v 0 0 0\nf 1 2 3\n", "v 0 0\n", "v 0 0 0\nf 1 x 1\n", "# empty\n"} {
+		if _, err := loadOBJ(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
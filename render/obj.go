@@ -0,0 +1,134 @@
+//-----------------------------------------------------------------------------
+/*
+
+Output a 3D triangle mesh to a Wavefront OBJ file.
+
+http://paulbourke.net/dataformats/obj/
+
+Only the vertex (v), vertex normal (vn) and face (f) records are written.
+Indices are 1-based. With normals each vertex has a normal with the same index.
+
+Loading an OBJ file reads the vertex and face records. Faces with more than
+3 vertices are split into a fan of triangles. Other records are ignored.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// encodeOBJ writes an indexed mesh to an OBJ file.
+func encodeOBJ(w io.Writer, m *sdf.Mesh3, normals []v3.Vec) error {
+	buf := bufio.NewWriter(w)
+	ew := &errWriter{w: buf}
+	fmt.Fprintf(ew, "# sdfx: %d vertices, %d faces\n", len(m.Vertex), len(m.Index))
+	for _, v := range m.Vertex {
+		fmt.Fprintf(ew, "v %g %g %g\n", v.X, v.Y, v.Z)
+	}
+	for _, n := range normals {
+		fmt.Fprintf(ew, "vn %g %g %g\n", n.X, n.Y, n.Z)
+	}
+	for _, t := range m.Index {
+		if normals != nil {
+			fmt.Fprintf(ew, "f %d//%d %d//%d %d//%d\n", t[0]+1, t[0]+1, t[1]+1, t[1]+1, t[2]+1, t[2]+1)
+		} else {
+			fmt.Fprintf(ew, "f %d %d %d\n", t[0]+1, t[1]+1, t[2]+1)
+		}
+	}
+	if ew.err != nil {
+		return ew.err
+	}
+	return buf.Flush()
+}
+
+//-----------------------------------------------------------------------------
+
+// parseOBJIndex returns the 0-based vertex index for an OBJ face vertex (v, v/vt, v/vt/vn or v//vn).
+// Negative indices are relative to the end of the vertex list.
+func parseOBJIndex(field string, n int) (int, error) {
+	i, err := strconv.Atoi(strings.SplitN(field, "/", 2)[0])
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		i += n
+	} else {
+		i--
+	}
+	if i < 0 || i >= n {
+		return 0, fmt.Errorf("obj vertex index %s out of range", field)
+	}
+	return i, nil
+}
+
+// loadOBJ reads the triangles from an OBJ file.
+func loadOBJ(r io.Reader) ([]*sdf.Triangle3, error) {
+	var v []v3.Vec
+	var mesh []*sdf.Triangle3
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("line %d: bad vertex", line)
+			}
+			f, err := parseFloats(fields[1:4])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			v = append(v, v3.Vec{f[0], f[1], f[2]})
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("line %d: bad face", line)
+			}
+			k := make([]int, len(fields)-1)
+			for i := range k {
+				var err error
+				k[i], err = parseOBJIndex(fields[i+1], len(v))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s", line, err)
+				}
+			}
+			for i := 1; i < len(k)-1; i++ {
+				mesh = append(mesh, &sdf.Triangle3{v[k[0]], v[k[i]], v[k[i+1]]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(mesh) == 0 {
+		return nil, errors.New("obj file has no faces")
+	}
+	return mesh, nil
+}
+
+// LoadOBJ loads a Wavefront OBJ file and returns the triangle mesh.
+func LoadOBJ(path string) ([]*sdf.Triangle3, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return loadOBJ(file)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,219 @@
+//-----------------------------------------------------------------------------
+/*
+
+Page Layout
+
+Lay out the contours of a 2D render on a page for PDF and EPS output.
+The drawing is at full scale (with a configurable drawing unit) and is
+centered on the page. An optional title block has text rendered with Text2D.
+
+The page content is drawn with PDF path operators. The EPS prolog defines
+the same operators in PostScript, so both formats share the content.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/golang/freetype/truetype"
+)
+
+//-----------------------------------------------------------------------------
+
+// Page sizes (portrait, in mm).
+var (
+	PageA4     = v2.Vec{210, 297}
+	PageA3     = v2.Vec{297, 420}
+	PageLetter = v2.Vec{215.9, 279.4}
+	PageLegal  = v2.Vec{215.9, 355.6}
+)
+
+// PageOptions sets the page layout for PDF and EPS output.
+type PageOptions struct {
+	Size      v2.Vec         // page size in mm (zero for a page that fits the drawing)
+	Landscape bool           // swap the width and height of the page
+	Unit      float64        // size of a drawing unit in mm (0 for 1mm, E.g. 25.4 for inches)
+	Margin    float64        // page margin in mm (0 for 10mm)
+	LineWidth float64        // line width in mm (0 for 0.1mm)
+	Title     string         // title block text ("" for no title block)
+	Font      *truetype.Font // font for the title block text
+	TitleSize float64        // title block text height in mm (0 for 5mm)
+}
+
+// mmToPoints converts mm to points (1/72 inch).
+const mmToPoints = 72 / 25.4
+
+// titleResolution is the rendering resolution for title text (in mm).
+const titleResolution = 0.05
+
+// page is the page content (in mm).
+type page struct {
+	size      v2.Vec     // page size
+	lineWidth float64    // line width
+	strokes   []*Contour // stroked contours
+	fills     []*Contour // filled contours (even-odd)
+}
+
+// pageValue returns a layout value (or the default).
+func pageValue(x, defaultValue float64) float64 {
+	if x <= 0 {
+		return defaultValue
+	}
+	return x
+}
+
+// contoursBox returns the bounding box of a set of contours.
+func contoursBox(contours []*Contour) sdf.Box2 {
+	bb := contours[0].boundingBox()
+	for _, c := range contours {
+		bb = bb.Extend(c.boundingBox())
+	}
+	return bb
+}
+
+// transformContours returns the contours with a function applied to the points.
+func transformContours(contours []*Contour, f func(v2.Vec) v2.Vec) []*Contour {
+	out := make([]*Contour, len(contours))
+	for i, c := range contours {
+		pts := make(v2.VecSet, len(c.Points))
+		for j, v := range c.Points {
+			pts[j] = f(v)
+		}
+		out[i] = &Contour{Points: pts, Closed: c.Closed}
+	}
+	return out
+}
+
+// newPage lays out the contours of a drawing (with bounding box bb) on a page.
+func newPage(bb sdf.Box2, contours []*Contour, opts *PageOptions) (*page, error) {
+	if opts == nil {
+		opts = &PageOptions{}
+	}
+	unit := pageValue(opts.Unit, 1)
+	margin := pageValue(opts.Margin, 10)
+	titleSize := pageValue(opts.TitleSize, 5)
+	drawing := bb.Size().MulScalar(unit)
+
+	// render the title text
+	var title []*Contour
+	titleWidth := 0.0
+	if opts.Title != "" {
+		if opts.Font == nil {
+			return nil, sdf.ErrMsg("no font for the title")
+		}
+		s, err := sdf.Text2D(opts.Font, sdf.NewText(opts.Title), titleSize)
+		if err != nil {
+			return nil, err
+		}
+		cells := int(math.Ceil(s.BoundingBox().Size().MaxComponent() / titleResolution))
+		title = ToContours(s, NewMarchingSquaresQuadtree(cells), 0.5*titleResolution)
+		if len(title) == 0 {
+			return nil, sdf.ErrMsg("empty title")
+		}
+		// with space either side of the text
+		titleWidth = contoursBox(title).Size().X + titleSize
+	}
+	// the title block and the space above it
+	titleHeight := 0.0
+	if title != nil {
+		titleHeight = 3 * titleSize
+	}
+
+	size := opts.Size
+	if size.X <= 0 || size.Y <= 0 {
+		// fit the page to the drawing
+		size = drawing.AddScalar(2 * margin).Add(v2.Vec{0, titleHeight})
+		size.X = math.Max(size.X, titleWidth+2*margin)
+	} else if opts.Landscape {
+		size = v2.Vec{size.Y, size.X}
+	}
+	area := sdf.Box2{Min: v2.Vec{margin, margin + titleHeight}, Max: size.SubScalar(margin)}
+	if drawing.X > area.Size().X+tolerance || drawing.Y > area.Size().Y+tolerance {
+		return nil, fmt.Errorf("the drawing (%.1fx%.1fmm) doesn't fit on the page (%.1fx%.1fmm)",
+			drawing.X, drawing.Y, size.X, size.Y)
+	}
+	if titleWidth > area.Size().X+tolerance {
+		return nil, sdf.ErrMsg("the title doesn't fit on the page")
+	}
+
+	// the drawing is centered in its area
+	center := bb.Center()
+	areaCenter := area.Center()
+	p := &page{
+		size:      size,
+		lineWidth: pageValue(opts.LineWidth, 0.1),
+		strokes: transformContours(contours, func(v v2.Vec) v2.Vec {
+			return v.Sub(center).MulScalar(unit).Add(areaCenter)
+		}),
+	}
+
+	if title != nil {
+		block := sdf.Box2{Min: v2.Vec{margin, margin}, Max: v2.Vec{size.X - margin, margin + 2*titleSize}}
+		p.strokes = append(p.strokes, &Contour{
+			Points: v2.VecSet{block.Min, {block.Max.X, block.Min.Y}, block.Max, {block.Min.X, block.Max.Y}},
+			Closed: true,
+		})
+		// the text is left aligned and vertically centered in the block
+		tbb := contoursBox(title)
+		ofs := v2.Vec{block.Min.X + 0.5*titleSize - tbb.Min.X, block.Center().Y - tbb.Center().Y}
+		p.fills = transformContours(title, func(v v2.Vec) v2.Vec {
+			return v.Add(ofs)
+		})
+	}
+	return p, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// pageNumber formats a number for page content.
+func pageNumber(x float64) string {
+	return strconv.FormatFloat(x, 'f', 2, 64)
+}
+
+// pagePath writes the path operators for a contour (converted to points).
+func pagePath(w io.Writer, c *Contour) {
+	for i, v := range c.Points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		v = v.MulScalar(mmToPoints)
+		fmt.Fprintf(w, "%s %s %s\n", pageNumber(v.X), pageNumber(v.Y), op)
+	}
+	if c.Closed {
+		fmt.Fprintln(w, "h")
+	}
+}
+
+// mediaBox returns the page size in points.
+func (p *page) mediaBox() v2.Vec {
+	return p.size.MulScalar(mmToPoints)
+}
+
+// content writes the page content with PDF operators.
+func (p *page) content(w io.Writer) {
+	// round caps and joins
+	fmt.Fprintf(w, "%s w 1 J 1 j\n", pageNumber(p.lineWidth*mmToPoints))
+	for _, c := range p.strokes {
+		if len(c.Points) > 1 {
+			pagePath(w, c)
+			fmt.Fprintln(w, "S")
+		}
+	}
+	if len(p.fills) > 0 {
+		for _, c := range p.fills {
+			pagePath(w, c)
+		}
+		fmt.Fprintln(w, "f*")
+	}
+}
+
+//-----------------------------------------------------------------------------
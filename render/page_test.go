@@ -0,0 +1,134 @@
+//-----------------------------------------------------------------------------
+/*
+
+PDF and EPS Output Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_WritePDF(t *testing.T) {
+	s, _ := sdf.Circle2D(10)
+	r := NewMarchingSquaresQuadtree(100)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := WritePDF(ctx, &buf, s, r, &PageOptions{Size: PageA4, Landscape: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("bad pdf header or trailer")
+	}
+	if !bytes.Contains(out, []byte("/MediaBox [0 0 841.89 595.28]")) {
+		t.Error("expected a landscape A4 page")
+	}
+	// the cross reference table has the object offsets
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n0 5\n")) {
+		t.Fatal("bad xref offset")
+	}
+	entries := strings.Split(string(out[xref:]), "\n")[3:7]
+	for i, e := range entries {
+		ofs, _ := strconv.Atoi(e[:10])
+		if !bytes.HasPrefix(out[ofs:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))) {
+			t.Errorf("bad offset for object %d", i+1)
+		}
+	}
+	// the content is the stroked circle at full scale in the center of the page
+	start := bytes.Index(out, []byte("stream\n")) + 7
+	end := bytes.Index(out, []byte("\nendstream"))
+	zr, err := zlib.NewReader(bytes.NewReader(out[start:end]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(content, []byte("\nS\n")); n != 1 {
+		t.Errorf("expected 1 stroke, got %d", n)
+	}
+	center := v2.Vec{297, 210}.MulScalar(0.5)
+	for _, line := range strings.Split(string(content), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 3 && (f[2] == "m" || f[2] == "l") {
+			x, _ := strconv.ParseFloat(f[0], 64)
+			y, _ := strconv.ParseFloat(f[1], 64)
+			// points to mm
+			p := v2.Vec{x, y}.MulScalar(25.4 / 72)
+			if d := p.Sub(center).Length(); !sdf.EqualFloat64(d, 10, 0.05) {
+				t.Fatalf("point %v is %f from the center", p, d)
+			}
+		}
+	}
+
+	// doesn't fit
+	if err := WritePDF(ctx, &buf, s, r, &PageOptions{Size: PageA4, Unit: 25.4}, nil); err == nil {
+		t.Error("expected an error for a drawing bigger than the page")
+	}
+	// a title needs a font
+	if err := WritePDF(ctx, &buf, s, r, &PageOptions{Title: "part"}, nil); err == nil {
+		t.Error("expected an error for no font")
+	}
+}
+
+func Test_WriteEPS(t *testing.T) {
+	s, _ := sdf.Circle2D(10)
+	r := NewMarchingSquaresQuadtree(100)
+	font, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	opts := &PageOptions{Title: "Part 1234 rev A", Font: font, Unit: 2}
+	if err := WriteEPS(context.Background(), &buf, s, r, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "%!PS-Adobe-3.0 EPSF-3.0\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatal("bad eps header or trailer")
+	}
+	// the page fits the drawing (40mm at 2mm per unit) with margins and the title block
+	var w, h float64
+	if _, err := fmt.Sscanf(out[strings.Index(out, "%%HiResBoundingBox:"):], "%%%%HiResBoundingBox: 0 0 %f %f", &w, &h); err != nil {
+		t.Fatal(err)
+	}
+	if !sdf.EqualFloat64(h*25.4/72, 40+20+15, 0.01) {
+		t.Errorf("expected a height of 75mm, got %f", h*25.4/72)
+	}
+	// the title text is filled
+	if !strings.Contains(out, "\nf*\n") {
+		t.Error("expected filled title text")
+	}
+	// circle and title block
+	if n := strings.Count(out, "\nS\n"); n != 2 {
+		t.Errorf("expected 2 strokes, got %d", n)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,112 @@
+//-----------------------------------------------------------------------------
+/*
+
+Output a 2D drawing to a PDF or EPS file.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+)
+
+//-----------------------------------------------------------------------------
+
+// countWriter counts the bytes written to a writer.
+type countWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
+// writePDF writes a page as a single page PDF file.
+func writePDF(w io.Writer, p *page) error {
+	// compress the page content
+	var content bytes.Buffer
+	zw := zlib.NewWriter(&content)
+	p.content(zw)
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(w)
+	ew := &errWriter{w: buf}
+	cw := &countWriter{w: ew}
+	// a binary comment marks the file as binary
+	io.WriteString(cw, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	box := p.mediaBox()
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents 4 0 R /Resources << >> >>",
+			pageNumber(box.X), pageNumber(box.Y)),
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n", content.Len()),
+	}
+	offset := make([]int, len(objects))
+	for i, obj := range objects {
+		offset[i] = cw.n
+		fmt.Fprintf(cw, "%d 0 obj\n%s", i+1, obj)
+		if i == len(objects)-1 {
+			cw.Write(content.Bytes())
+			io.WriteString(cw, "\nendstream")
+		}
+		io.WriteString(cw, "\nendobj\n")
+	}
+	// cross reference table (each entry is 20 bytes)
+	xref := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, ofs := range offset {
+		fmt.Fprintf(cw, "%010d 00000 n \n", ofs)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	if ew.err != nil {
+		return ew.err
+	}
+	return buf.Flush()
+}
+
+//-----------------------------------------------------------------------------
+
+// epsProlog defines the PDF path operators used by the page content.
+const epsProlog = `/m {moveto} bind def
+/l {lineto} bind def
+/h {closepath} bind def
+/S {stroke} bind def
+/f* {eofill} bind def
+/w {setlinewidth} bind def
+/J {setlinecap} bind def
+/j {setlinejoin} bind def
+`
+
+// writeEPS writes a page as an EPS file.
+func writeEPS(w io.Writer, p *page) error {
+	buf := bufio.NewWriter(w)
+	ew := &errWriter{w: buf}
+	box := p.mediaBox()
+	io.WriteString(ew, "%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(ew, "%%%%BoundingBox: 0 0 %d %d\n", int(math.Ceil(box.X)), int(math.Ceil(box.Y)))
+	fmt.Fprintf(ew, "%%%%HiResBoundingBox: 0 0 %s %s\n", pageNumber(box.X), pageNumber(box.Y))
+	io.WriteString(ew, "%%Creator: sdfx\n%%EndComments\n")
+	// keep the operator definitions local to the file
+	io.WriteString(ew, "save\n10 dict begin\n"+epsProlog)
+	p.content(ew)
+	io.WriteString(ew, "showpage\nend\nrestore\n%%EOF\n")
+	if ew.err != nil {
+		return ew.err
+	}
+	return buf.Flush()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,93 @@
+//-----------------------------------------------------------------------------
+/*
+
+Output a 3D triangle mesh to a PLY (Polygon File Format) file.
+
+http://paulbourke.net/dataformats/ply/
+
+Vertex positions (and normals) are 32-bit floats.
+Faces are a list of 32-bit vertex indices with an 8-bit count.
+Binary files are little endian.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// plyFace is the binary data for a triangle face.
+type plyFace struct {
+	Count uint8
+	Index [3]int32
+}
+
+// plyEncoder returns the PLY encoder for ASCII or binary files.
+func plyEncoder(ascii bool) meshEncoder {
+	return func(w io.Writer, m *sdf.Mesh3, normals []v3.Vec) error {
+		return encodePLY(w, m, normals, ascii)
+	}
+}
+
+// encodePLY writes an indexed mesh to a PLY file.
+func encodePLY(w io.Writer, m *sdf.Mesh3, normals []v3.Vec, ascii bool) error {
+	buf := bufio.NewWriter(w)
+	ew := &errWriter{w: buf}
+
+	// header
+	format := "binary_little_endian"
+	if ascii {
+		format = "ascii"
+	}
+	fmt.Fprintf(ew, "ply\nformat %s 1.0\ncomment sdfx\n", format)
+	fmt.Fprintf(ew, "element vertex %d\n", len(m.Vertex))
+	fmt.Fprintf(ew, "property float x\nproperty float y\nproperty float z\n")
+	if normals != nil {
+		fmt.Fprintf(ew, "property float nx\nproperty float ny\nproperty float nz\n")
+	}
+	fmt.Fprintf(ew, "element face %d\n", len(m.Index))
+	fmt.Fprintf(ew, "property list uchar int vertex_indices\nend_header\n")
+
+	if ascii {
+		for i, v := range m.Vertex {
+			fmt.Fprintf(ew, "%g %g %g", float32(v.X), float32(v.Y), float32(v.Z))
+			if normals != nil {
+				n := normals[i]
+				fmt.Fprintf(ew, " %g %g %g", float32(n.X), float32(n.Y), float32(n.Z))
+			}
+			fmt.Fprintf(ew, "\n")
+		}
+		for _, t := range m.Index {
+			fmt.Fprintf(ew, "3 %d %d %d\n", t[0], t[1], t[2])
+		}
+	} else {
+		for i, v := range m.Vertex {
+			data := []float32{float32(v.X), float32(v.Y), float32(v.Z)}
+			if normals != nil {
+				n := normals[i]
+				data = append(data, float32(n.X), float32(n.Y), float32(n.Z))
+			}
+			binary.Write(ew, binary.LittleEndian, data)
+		}
+		for _, t := range m.Index {
+			binary.Write(ew, binary.LittleEndian, &plyFace{3, [3]int32{int32(t[0]), int32(t[1]), int32(t[2])}})
+		}
+	}
+
+	if ew.err != nil {
+		return ew.err
+	}
+	return buf.Flush()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,134 @@
+//-----------------------------------------------------------------------------
+/*
+
+Render Progress and Cancellation
+
+Renders of complex objects at high resolution can take a long time.
+Renderers that implement Render3Context/Render2Context report the fraction
+of the render that is complete, and stop early if their context is cancelled.
+
+Renderers track their progress with a ProgressMonitor. The context is checked
+whenever progress is reported, so cancellation takes effect within a small
+fraction of the render.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"context"
+	"io"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Progress is called with the fraction (0 to 1) of a render that is complete.
+type Progress func(fraction float64)
+
+// progressStep is the minimum change in the completed fraction between progress reports.
+const progressStep = 0.001
+
+// ProgressMonitor tracks the progress of a render and checks for cancellation.
+// A nil monitor reports nothing and never stops.
+type ProgressMonitor struct {
+	ctx      context.Context
+	progress Progress
+	done     float64 // fraction of the render completed
+	reported float64 // fraction last reported
+	err      error   // context error (the render has been cancelled)
+}
+
+// NewProgressMonitor returns a progress monitor. The progress function may be nil.
+func NewProgressMonitor(ctx context.Context, progress Progress) *ProgressMonitor {
+	return &ProgressMonitor{
+		ctx:      ctx,
+		progress: progress,
+	}
+}
+
+// Add adds to the fraction of the render that is complete.
+// It returns true if the render has been cancelled.
+func (m *ProgressMonitor) Add(fraction float64) bool {
+	if m == nil {
+		return false
+	}
+	if m.err != nil {
+		return true
+	}
+	m.done += fraction
+	if m.done-m.reported < progressStep {
+		return false
+	}
+	m.reported = m.done
+	m.err = m.ctx.Err()
+	if m.err != nil {
+		return true
+	}
+	if m.progress != nil {
+		m.progress(math.Min(m.done, 1))
+	}
+	return false
+}
+
+// Stopped returns true if the render has been cancelled.
+func (m *ProgressMonitor) Stopped() bool {
+	return m != nil && m.err != nil
+}
+
+// Done is called at the end of a render.
+// It returns the context error if the render was cancelled.
+func (m *ProgressMonitor) Done() error {
+	if m == nil {
+		return nil
+	}
+	if m.err != nil {
+		return m.err
+	}
+	if m.progress != nil {
+		m.progress(1)
+	}
+	return nil
+}
+
+// Finish closes the output of a render and is then the same as Done.
+// If the render wasn't cancelled it returns the error from closing the output,
+// E.g. from a writer that does its work when closed.
+func (m *ProgressMonitor) Finish(output io.Closer) error {
+	err := output.Close()
+	if derr := m.Done(); derr != nil {
+		return derr
+	}
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+// render3 runs a 3d renderer with cancellation and progress reporting.
+// Renderers that don't implement Render3Context run to completion.
+func render3(ctx context.Context, r Render3, s sdf.SDF3, output sdf.Triangle3Writer, progress Progress) error {
+	if rc, ok := r.(Render3Context); ok {
+		return rc.RenderContext(ctx, s, output, progress)
+	}
+	r.Render(s, output)
+	m := NewProgressMonitor(ctx, progress)
+	m.err = ctx.Err()
+	return m.Done()
+}
+
+// render2 runs a 2d renderer with cancellation and progress reporting.
+// Renderers that don't implement Render2Context run to completion.
+func render2(ctx context.Context, r Render2, s sdf.SDF2, output sdf.Line2Writer, progress Progress) error {
+	if rc, ok := r.(Render2Context); ok {
+		return rc.RenderContext(ctx, s, output, progress)
+	}
+	r.Render(s, output)
+	m := NewProgressMonitor(ctx, progress)
+	m.err = ctx.Err()
+	return m.Done()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,125 @@
+//-----------------------------------------------------------------------------
+/*
+
+Render Progress and Cancellation Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// The marching cubes/squares and dual contouring renderers support cancellation and progress reporting.
+var _ Render3Context = &MarchingCubesUniform{}
+var _ Render3Context = &MarchingCubesOctree{}
+var _ Render2Context = &MarchingSquaresUniform{}
+var _ Render2Context = &MarchingSquaresQuadtree{}
+var _ Render2Context = &DualContouring2D{}
+
+// progressCheck checks the progress reported by a render.
+type progressCheck struct {
+	t       *testing.T
+	name    string
+	last    float64
+	reports int
+	cancel  context.CancelFunc // called once the render is half done
+}
+
+func (p *progressCheck) progress(fraction float64) {
+	if fraction < p.last || fraction > 1 {
+		p.t.Errorf("%s: progress %f after %f", p.name, fraction, p.last)
+	}
+	p.last = fraction
+	p.reports++
+	if p.cancel != nil && fraction >= 0.5 {
+		p.cancel()
+	}
+}
+
+func Test_Progress(t *testing.T) {
+	s3, _ := sdf.Sphere3D(5)
+	s2, _ := sdf.Circle2D(5)
+	renderers := []struct {
+		name   string
+		render func(ctx context.Context, progress Progress) error
+	}{
+		{"mc uniform", func(ctx context.Context, progress Progress) error {
+			_, err := ToTrianglesContext(ctx, s3, NewMarchingCubesUniform(50), progress)
+			return err
+		}},
+		{"mc octree", func(ctx context.Context, progress Progress) error {
+			_, err := ToTrianglesContext(ctx, s3, NewMarchingCubesOctree(50), progress)
+			return err
+		}},
+		{"ms uniform", func(ctx context.Context, progress Progress) error {
+			return WriteSVG(ctx, &failWriter{n: 1 << 30}, s2, NewMarchingSquaresUniform(200), progress)
+		}},
+		{"ms quadtree", func(ctx context.Context, progress Progress) error {
+			return WriteSVG(ctx, &failWriter{n: 1 << 30}, s2, NewMarchingSquaresQuadtree(200), progress)
+		}},
+		{"dc 2d", func(ctx context.Context, progress Progress) error {
+			return WriteSVG(ctx, &failWriter{n: 1 << 30}, s2, NewDualContouring2D(200), progress)
+		}},
+	}
+	for _, x := range renderers {
+		// a complete render finishes at 1
+		p := &progressCheck{t: t, name: x.name}
+		err := x.render(context.Background(), p.progress)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", x.name, err)
+		}
+		if p.last != 1 || p.reports < 10 {
+			t.Errorf("%s: %d progress reports, last %f", x.name, p.reports, p.last)
+		}
+		// cancel the render half way through
+		ctx, cancel := context.WithCancel(context.Background())
+		p = &progressCheck{t: t, name: x.name, cancel: cancel}
+		err = x.render(ctx, p.progress)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected a cancellation error, got %v", x.name, err)
+		}
+		if p.last >= 1 {
+			t.Errorf("%s: render completed after cancellation", x.name)
+		}
+	}
+}
+
+func Test_ProgressMonitor(t *testing.T) {
+	// a nil monitor does nothing
+	var m *ProgressMonitor
+	if m.Add(1) || m.Stopped() || m.Done() != nil {
+		t.Errorf("nil monitor stopped")
+	}
+	// small steps are reported in batches
+	reports := 0
+	m = NewProgressMonitor(context.Background(), func(float64) { reports++ })
+	for i := 0; i < 10000; i++ {
+		m.Add(1e-4)
+	}
+	if reports > 1000 {
+		t.Errorf("%d progress reports", reports)
+	}
+	if err := m.Done(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	// renderers without context support run to completion
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	box, _ := sdf.Box3D(v3.Vec{1, 1, 1}, 0)
+	r := struct{ Render3 }{NewMarchingCubesUniform(10)}
+	if _, err := ToTrianglesContext(ctx, box, r, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+}
+
+//-----------------------------------------------------------------------------
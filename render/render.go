@@ -9,7 +9,11 @@ Top-Level Rendering Routines
 package render
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sync"
 
 	"github.com/deadsy/sdfx/sdf"
@@ -29,6 +33,24 @@ type Render2 interface {
 	Info(s sdf.SDF2) string
 }
 
+// Render3Context is a Render3 with cancellation and progress reporting.
+type Render3Context interface {
+	Render3
+	// RenderContext renders until done or the context is cancelled.
+	// It returns the context error if the render was cancelled.
+	// The progress function may be nil.
+	RenderContext(ctx context.Context, sdf3 sdf.SDF3, output sdf.Triangle3Writer, progress Progress) error
+}
+
+// Render2Context is a Render2 with cancellation and progress reporting.
+type Render2Context interface {
+	Render2
+	// RenderContext renders until done or the context is cancelled.
+	// It returns the context error if the render was cancelled.
+	// The progress function may be nil.
+	RenderContext(ctx context.Context, s sdf.SDF2, output sdf.Line2Writer, progress Progress) error
+}
+
 //-----------------------------------------------------------------------------
 
 // ToTriangles renders an SDF3 to a triangle mesh.
@@ -50,6 +72,49 @@ func ToTriangles(
 	return triangles
 }
 
+// ToTrianglesContext renders an SDF3 to a triangle mesh with cancellation and progress reporting.
+func ToTrianglesContext(
+	ctx context.Context, // context for cancellation
+	s sdf.SDF3, // sdf3 to render
+	r Render3, // rendering method
+	progress Progress, // progress function (may be nil)
+) ([]*sdf.Triangle3, error) {
+	triangles := make([]*sdf.Triangle3, 0)
+	var wg sync.WaitGroup
+	// To write the triangles.
+	output := sdf.WriteTriangles(&wg, &triangles)
+	// Run the renderer.
+	err := render3(ctx, r, s, sdf.NewTriangle3Buffer(output), progress)
+	// Stop the writer reading on the channel.
+	close(output)
+	// Wait for the write to complete.
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	// return all the triangles
+	return triangles, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// toFile creates a file and writes to it.
+// The file is removed if there is an error, so a partial file isn't left behind.
+func toFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
 //-----------------------------------------------------------------------------
 
 // ToSTL renders an SDF3 to an STL file.
@@ -57,21 +122,44 @@ func ToSTL(
 	s sdf.SDF3, // sdf3 to render
 	path string, // path to filename
 	r Render3, // rendering method
-) {
+) error {
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	return toFile(path, func(w io.Writer) error {
+		return WriteSTL(context.Background(), w, s, r, nil, nil)
+	})
+}
+
+// WriteSTL renders an SDF3 and writes it to w as an STL file.
+// The info sets the format and metadata, nil writes a binary file with an empty header.
+// Rendering stops with the context error if the context is cancelled.
+func WriteSTL(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	s sdf.SDF3, // sdf3 to render
+	r Render3, // rendering method
+	info *STLInfo, // file format and metadata (may be nil)
+	progress Progress, // progress function (may be nil)
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := info.binaryHeader(); err != nil {
+		return err
+	}
 	// write the triangles to an STL file
 	var wg sync.WaitGroup
-	output, err := writeSTL(&wg, path)
-	if err != nil {
-		fmt.Printf("%s", err)
-		return
-	}
+	var err error
+	output := writeSTL(ctx, &wg, w, info, &err)
 	// run the renderer
-	r.Render(s, sdf.NewTriangle3Buffer(output))
+	rerr := render3(ctx, r, s, sdf.NewTriangle3Buffer(output), progress)
 	// stop the STL writer reading on the channel
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	if rerr != nil {
+		return rerr
+	}
+	return err
 }
 
 //-----------------------------------------------------------------------------
@@ -81,21 +169,118 @@ func To3MF(
 	s sdf.SDF3, // sdf3 to render
 	path string, // path to filename
 	r Render3, // rendering method
-) {
+) error {
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	return toFile(path, func(w io.Writer) error {
+		return Write3MF(context.Background(), w, s, r, nil)
+	})
+}
+
+// Write3MF renders an SDF3 and writes it to w as a 3MF file.
+// Rendering stops with the context error if the context is cancelled.
+func Write3MF(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	s sdf.SDF3, // sdf3 to render
+	r Render3, // rendering method
+	progress Progress, // progress function (may be nil)
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	// write the triangles to a 3MF file
 	var wg sync.WaitGroup
-	output, err := write3MF(&wg, path)
-	if err != nil {
-		fmt.Printf("%s", err)
-		return
+	var err error
+	output := write3MF(ctx, &wg, w, &err)
+	// run the renderer
+	rerr := render3(ctx, r, s, sdf.NewTriangle3Buffer(output), progress)
+	// stop the 3MF writer reading on the channel
+	close(output)
+	// wait for the file write to complete
+	wg.Wait()
+	if rerr != nil {
+		return rerr
 	}
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+// ToOBJ renders an SDF3 to a Wavefront OBJ file.
+func ToOBJ(
+	s sdf.SDF3, // sdf3 to render
+	path string, // path to filename
+	r Render3, // rendering method
+	opts *MeshOptions, // output options (nil for defaults)
+) error {
+	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	return toFile(path, func(w io.Writer) error {
+		return WriteOBJ(context.Background(), w, s, r, opts, nil)
+	})
+}
+
+// WriteOBJ renders an SDF3 and writes it to w as a Wavefront OBJ file.
+// Rendering stops with the context error if the context is cancelled.
+func WriteOBJ(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	s sdf.SDF3, // sdf3 to render
+	r Render3, // rendering method
+	opts *MeshOptions, // output options (nil for defaults)
+	progress Progress, // progress function (may be nil)
+) error {
+	return writeMesh(ctx, w, s, r, meshOptions(opts), encodeOBJ, progress)
+}
+
+// ToPLY renders an SDF3 to a PLY file.
+func ToPLY(
+	s sdf.SDF3, // sdf3 to render
+	path string, // path to filename
+	r Render3, // rendering method
+	opts *MeshOptions, // output options (nil for defaults)
+) error {
+	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	return toFile(path, func(w io.Writer) error {
+		return WritePLY(context.Background(), w, s, r, opts, nil)
+	})
+}
+
+// WritePLY renders an SDF3 and writes it to w as a PLY file.
+// Rendering stops with the context error if the context is cancelled.
+func WritePLY(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	s sdf.SDF3, // sdf3 to render
+	r Render3, // rendering method
+	opts *MeshOptions, // output options (nil for defaults)
+	progress Progress, // progress function (may be nil)
+) error {
+	opts = meshOptions(opts)
+	return writeMesh(ctx, w, s, r, opts, plyEncoder(opts.ASCII), progress)
+}
+
+// writeMesh renders an SDF3 and writes it to w as an indexed mesh file.
+func writeMesh(ctx context.Context, w io.Writer, s sdf.SDF3, r Render3, opts *MeshOptions, encode meshEncoder, progress Progress) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.Tolerance < 0 {
+		return errors.New("tolerance < 0")
+	}
+	// write the triangles to an indexed mesh file
+	var wg sync.WaitGroup
+	var err error
+	output := writeMesh3(ctx, &wg, w, opts, encode, &err)
 	// run the renderer
-	r.Render(s, sdf.NewTriangle3Buffer(output))
-	// stop the STL writer reading on the channel
+	rerr := render3(ctx, r, s, sdf.NewTriangle3Buffer(output), progress)
+	// stop the mesh writer reading on the channel
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	if rerr != nil {
+		return rerr
+	}
+	return err
 }
 
 //-----------------------------------------------------------------------------
@@ -105,21 +290,40 @@ func ToDXF(
 	s sdf.SDF2, // sdf2 to render
 	path string, // path to filename
 	r Render2, // rendering method
-) {
+) error {
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	return toFile(path, func(w io.Writer) error {
+		return WriteDXF(context.Background(), w, s, r, nil)
+	})
+}
+
+// WriteDXF renders an SDF2 and writes it to w as a DXF file.
+// The line segments are chained into contours and written as polylines (see Contours).
+// Rendering stops with the context error if the context is cancelled.
+func WriteDXF(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	s sdf.SDF2, // sdf2 to render
+	r Render2, // rendering method
+	progress Progress, // progress function (may be nil)
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	// write the line segments to a DXF file
 	var wg sync.WaitGroup
-	output, err := writeDXF(&wg, path)
-	if err != nil {
-		fmt.Printf("%s", err)
-		return
-	}
+	var err error
+	output := writeDXF(ctx, &wg, w, &err)
 	// run the renderer
-	r.Render(s, sdf.NewLine2Buffer(output))
+	rerr := render2(ctx, r, s, sdf.NewLine2Buffer(output), progress)
 	// stop the DXF writer reading on the channel
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	if rerr != nil {
+		return rerr
+	}
+	return err
 }
 
 //-----------------------------------------------------------------------------
@@ -131,20 +335,109 @@ func ToSVG(
 	s sdf.SDF2, // sdf2 to render
 	path string, // path to filename
 	r Render2, // rendering method
-) {
+) error {
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	return toFile(path, func(w io.Writer) error {
+		return WriteSVG(context.Background(), w, s, r, nil)
+	})
+}
+
+// WriteSVG renders an SDF2 and writes it to w as an SVG file.
+// The line segments are chained into contours and written as paths (see Contours).
+// Rendering stops with the context error if the context is cancelled.
+func WriteSVG(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	s sdf.SDF2, // sdf2 to render
+	r Render2, // rendering method
+	progress Progress, // progress function (may be nil)
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	// write the line segments to an SVG file
 	var wg sync.WaitGroup
-	output, err := writeSVG(&wg, path, svgLineStyle)
-	if err != nil {
-		fmt.Printf("%s", err)
-	}
+	var err error
+	output := writeSVG(ctx, &wg, w, svgLineStyle, &err)
 	// run the renderer
-	r.Render(s, sdf.NewLine2Buffer(output))
+	rerr := render2(ctx, r, s, sdf.NewLine2Buffer(output), progress)
 	// stop the SVG writer reading on the channel
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	if rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+// ToPDF renders an SDF2 to a PDF file.
+// The drawing is at full scale on a page set by the options (nil for defaults).
+func ToPDF(
+	s sdf.SDF2, // sdf2 to render
+	path string, // path to filename
+	r Render2, // rendering method
+	opts *PageOptions, // page layout (nil for defaults)
+) error {
+	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	return toFile(path, func(w io.Writer) error {
+		return WritePDF(context.Background(), w, s, r, opts, nil)
+	})
+}
+
+// WritePDF renders an SDF2 and writes it to w as a PDF file.
+// Rendering stops with the context error if the context is cancelled.
+func WritePDF(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	s sdf.SDF2, // sdf2 to render
+	r Render2, // rendering method
+	opts *PageOptions, // page layout (nil for defaults)
+	progress Progress, // progress function (may be nil)
+) error {
+	return writePage(ctx, w, s, r, opts, writePDF, progress)
+}
+
+// ToEPS renders an SDF2 to an EPS file.
+// The drawing is at full scale on a page set by the options (nil for defaults).
+func ToEPS(
+	s sdf.SDF2, // sdf2 to render
+	path string, // path to filename
+	r Render2, // rendering method
+	opts *PageOptions, // page layout (nil for defaults)
+) error {
+	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	return toFile(path, func(w io.Writer) error {
+		return WriteEPS(context.Background(), w, s, r, opts, nil)
+	})
+}
+
+// WriteEPS renders an SDF2 and writes it to w as an EPS file.
+// Rendering stops with the context error if the context is cancelled.
+func WriteEPS(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	s sdf.SDF2, // sdf2 to render
+	r Render2, // rendering method
+	opts *PageOptions, // page layout (nil for defaults)
+	progress Progress, // progress function (may be nil)
+) error {
+	return writePage(ctx, w, s, r, opts, writeEPS, progress)
+}
+
+// writePage renders an SDF2 to contours and writes them to w as a page.
+func writePage(ctx context.Context, w io.Writer, s sdf.SDF2, r Render2, opts *PageOptions, encode func(io.Writer, *page) error, progress Progress) error {
+	contours, err := ToContoursContext(ctx, s, r, 0, progress)
+	if err != nil {
+		return err
+	}
+	p, err := newPage(s.BoundingBox(), contours, opts)
+	if err != nil {
+		return err
+	}
+	return encode(w, p)
 }
 
 //-----------------------------------------------------------------------------
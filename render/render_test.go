@@ -0,0 +1,152 @@
+//-----------------------------------------------------------------------------
+/*
+
+Rendering Output Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+var errWrite = errors.New("write failed")
+
+// failWriter fails after n bytes have been written.
+type failWriter struct {
+	n int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errWrite
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func Test_WriteSTL(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 1)
+	r := NewMarchingCubesOctree(50)
+
+	// a file (seekable) and a buffer (not seekable) have the same content
+	dir, err := ioutil.TempDir("", "sdfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.stl")
+	if err := ToSTL(s, path, r); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSTL(context.Background(), &buf, s, r, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Errorf("file and buffer output differ")
+	}
+	mesh, err := LoadSTL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mesh) == 0 || len(data) != 84+50*len(mesh) {
+		t.Errorf("%d bytes for %d triangles", len(data), len(mesh))
+	}
+
+	// bad path
+	if err := ToSTL(s, filepath.Join(dir, "missing", "test.stl"), r); err == nil {
+		t.Errorf("expected an error for a bad path")
+	}
+}
+
+func Test_WriteErrors(t *testing.T) {
+	s3, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 1)
+	r3 := NewMarchingCubesOctree(50)
+	s2 := sdf.Box2D(v2.Vec{10, 8}, 1)
+	r2 := NewMarchingSquaresQuadtree(50)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writers := []struct {
+		name  string
+		write func(ctx context.Context, w *failWriter) error
+	}{
+		{"stl", func(ctx context.Context, w *failWriter) error { return WriteSTL(ctx, w, s3, r3, nil, nil) }},
+		{"3mf", func(ctx context.Context, w *failWriter) error { return Write3MF(ctx, w, s3, r3, nil) }},
+		{"dxf", func(ctx context.Context, w *failWriter) error { return WriteDXF(ctx, w, s2, r2, nil) }},
+		{"svg", func(ctx context.Context, w *failWriter) error { return WriteSVG(ctx, w, s2, r2, nil) }},
+		{"pdf", func(ctx context.Context, w *failWriter) error { return WritePDF(ctx, w, s2, r2, nil, nil) }},
+		{"eps", func(ctx context.Context, w *failWriter) error { return WriteEPS(ctx, w, s2, r2, nil, nil) }},
+	}
+	for _, x := range writers {
+		// write errors are returned
+		if err := x.write(context.Background(), &failWriter{n: 100}); !errors.Is(err, errWrite) {
+			t.Errorf("%s: expected a write error, got %v", x.name, err)
+		}
+		// cancelled renders return the context error
+		if err := x.write(cancelled, &failWriter{n: 1 << 30}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected a cancellation error, got %v", x.name, err)
+		}
+		// no errors
+		if err := x.write(context.Background(), &failWriter{n: 1 << 30}); err != nil {
+			t.Errorf("%s: unexpected error %v", x.name, err)
+		}
+	}
+}
+
+func Test_ToFileError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sdfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.stl")
+
+	// a failed write removes the partial file
+	err = toFile(path, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Errorf("expected a write error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the file to be removed, got %v", err)
+	}
+
+	// a successful write keeps the file
+	err = toFile(path, func(w io.Writer) error {
+		_, err := w.Write([]byte("complete"))
+		return err
+	})
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the file to exist, got %v", err)
+	}
+}
+
+//-----------------------------------------------------------------------------
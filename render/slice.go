@@ -0,0 +1,222 @@
+//-----------------------------------------------------------------------------
+/*
+
+Slice Stacks
+
+Cut an SDF3 into parallel slices (E.g. for stacked laser cut sheets or the
+layer images of a resin printer). The slices share a 2D coordinate system and
+area, so the output for each layer lines up with the others.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/yofu/dxf"
+)
+
+//-----------------------------------------------------------------------------
+
+// SliceStack is a set of evenly spaced parallel slices through an SDF3.
+type SliceStack struct {
+	s         sdf.SDF3
+	axis      v3.Vec    // unit normal to the slicing planes
+	height    []float64 // distance of each slicing plane from the origin along the axis
+	thickness float64   // layer thickness
+	bb        sdf.Box2  // area of the slices
+}
+
+// NewSliceStack returns n slices through an SDF3 along an axis.
+// The bounding box of the SDF3 is divided into n equal layers along the axis
+// and each slice is through the middle of its layer.
+func NewSliceStack(s sdf.SDF3, axis v3.Vec, n int) (*SliceStack, error) {
+	if n <= 0 {
+		return nil, sdf.ErrMsg("n <= 0")
+	}
+	if axis.Length() == 0 {
+		return nil, sdf.ErrMsg("axis has zero length")
+	}
+	axis = axis.Normalize()
+	// the extent of the bounding box along the axis
+	hmin, hmax := math.Inf(1), math.Inf(-1)
+	for _, v := range s.BoundingBox().Vertices() {
+		h := v.Dot(axis)
+		hmin = math.Min(hmin, h)
+		hmax = math.Max(hmax, h)
+	}
+	ss := &SliceStack{
+		s:         s,
+		axis:      axis,
+		height:    make([]float64, n),
+		thickness: (hmax - hmin) / float64(n),
+	}
+	for i := range ss.height {
+		ss.height[i] = hmin + (float64(i)+0.5)*ss.thickness
+	}
+	// the slices all have the same area
+	ss.bb = ss.Slice(0).BoundingBox()
+	return ss, nil
+}
+
+// Layers returns the number of slices.
+func (ss *SliceStack) Layers() int {
+	return len(ss.height)
+}
+
+// Thickness returns the distance between slices.
+func (ss *SliceStack) Thickness() float64 {
+	return ss.thickness
+}
+
+// Height returns the distance of a slicing plane from the origin along the axis.
+func (ss *SliceStack) Height(i int) float64 {
+	return ss.height[i]
+}
+
+// BoundingBox returns the area covered by the slices.
+func (ss *SliceStack) BoundingBox() sdf.Box2 {
+	return ss.bb
+}
+
+// Slice returns the SDF2 for a slice.
+// The 2D coordinates are the projection of the 3D coordinates onto the slicing plane.
+func (ss *SliceStack) Slice(i int) sdf.SDF2 {
+	return sdf.Slice2D(ss.s, ss.axis.MulScalar(ss.height[i]), ss.axis)
+}
+
+// Contours renders a slice to contours. See Contours.
+func (ss *SliceStack) Contours(i int, r Render2, simplify float64) []*Contour {
+	return ToContours(ss.Slice(i), r, simplify)
+}
+
+// ContoursContext renders a slice to contours. See ToContoursContext.
+func (ss *SliceStack) ContoursContext(ctx context.Context, i int, r Render2, simplify float64, progress Progress) ([]*Contour, error) {
+	return ToContoursContext(ctx, ss.Slice(i), r, simplify, progress)
+}
+
+// layerProgress returns the progress function for rendering a slice as part of the whole stack.
+func (ss *SliceStack) layerProgress(i int, progress Progress) Progress {
+	if progress == nil {
+		return nil
+	}
+	n := float64(len(ss.height))
+	return func(fraction float64) {
+		progress((float64(i) + fraction) / n)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// ToDXF renders the slices to a DXF file with a layer for each slice.
+func (ss *SliceStack) ToDXF(path string, r Render2, simplify float64) error {
+	fmt.Printf("rendering %s (%d layers, %s)\n", path, ss.Layers(), r.Info(ss.Slice(0)))
+	return toFile(path, func(w io.Writer) error {
+		return ss.WriteDXF(context.Background(), w, r, simplify, nil)
+	})
+}
+
+// WriteDXF renders the slices and writes them to w as a DXF file with a layer for each slice.
+// Rendering stops with the context error if the context is cancelled.
+func (ss *SliceStack) WriteDXF(
+	ctx context.Context, // context for cancellation
+	w io.Writer, // output writer
+	r Render2, // rendering method
+	simplify float64, // simplification tolerance (0 for none)
+	progress Progress, // progress function for the whole stack (may be nil)
+) error {
+	d := NewDXF("")
+	for i := range ss.height {
+		contours, err := ss.ContoursContext(ctx, i, r, simplify, ss.layerProgress(i, progress))
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("Slice%d", i)
+		if _, err := d.drawing.AddLayer(name, dxf.DefaultColor, dxf.DefaultLineType, true); err != nil {
+			return err
+		}
+		d.polylines(contours)
+	}
+	_, err := d.drawing.WriteTo(w)
+	return err
+}
+
+// ToSVG renders each slice to an SVG file.
+// The file names are made with a format containing an integer verb for the slice number (E.g. "slice%03d.svg").
+func (ss *SliceStack) ToSVG(format string, r Render2, simplify float64) error {
+	for i := range ss.height {
+		path := fmt.Sprintf(format, i)
+		fmt.Printf("rendering %s (%s)\n", path, r.Info(ss.Slice(i)))
+		err := toFile(path, func(w io.Writer) error {
+			s := NewSVG("", svgLineStyle)
+			// the same area for every slice
+			s.include(ss.bb.Min)
+			s.include(ss.bb.Max)
+			s.Contours(ss.Contours(i, r, simplify))
+			return s.write(w)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Image returns a black and white image of a slice (white is solid).
+// The pixels are square and pixelSize wide.
+func (ss *SliceStack) Image(i int, pixelSize float64) (*image.Gray, error) {
+	if pixelSize <= 0 {
+		return nil, sdf.ErrMsg("pixelSize <= 0")
+	}
+	size := ss.bb.Size().DivScalar(pixelSize).Ceil()
+	nx, ny := int(size.X), int(size.Y)
+	// center the slice area in the image
+	base := ss.bb.Center().Sub(v2.Vec{float64(nx), float64(ny)}.MulScalar(0.5 * pixelSize))
+	s := ss.Slice(i)
+	img := image.NewGray(image.Rect(0, 0, nx, ny))
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			// image rows go down, slice y goes up
+			p := base.Add(v2.Vec{float64(x) + 0.5, float64(ny-1-y) + 0.5}.MulScalar(pixelSize))
+			if s.Evaluate(p) <= 0 {
+				img.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	return img, nil
+}
+
+// ToPNG writes an image of each slice to a PNG file (white is solid). See Image.
+// The file names are made with a format containing an integer verb for the slice number (E.g. "slice%03d.png").
+func (ss *SliceStack) ToPNG(format string, pixelSize float64) error {
+	for i := range ss.height {
+		path := fmt.Sprintf(format, i)
+		img, err := ss.Image(i, pixelSize)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rendering %s (%dx%d)\n", path, img.Bounds().Dx(), img.Bounds().Dy())
+		err = toFile(path, func(w io.Writer) error {
+			return png.Encode(w, img)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,152 @@
+//-----------------------------------------------------------------------------
+/*
+
+Slice Stack Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_SliceStack(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	s = sdf.Transform3D(s, sdf.Translate3d(v3.Vec{5, 0, 0}))
+	r := NewMarchingSquaresQuadtree(100)
+
+	if _, err := NewSliceStack(s, v3.Vec{0, 0, 1}, 0); err == nil {
+		t.Error("expected error for no slices")
+	}
+	if _, err := NewSliceStack(s, v3.Vec{}, 4); err == nil {
+		t.Error("expected error for no axis")
+	}
+
+	// slice along x (the axis needn't be a unit vector)
+	ss, err := NewSliceStack(s, v3.Vec{2, 0, 0}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ss.Layers() != 4 || !sdf.EqualFloat64(ss.Thickness(), 5, 1e-9) {
+		t.Fatalf("expected 4 layers 5 thick, got %d %f", ss.Layers(), ss.Thickness())
+	}
+	for i := 0; i < ss.Layers(); i++ {
+		h := ss.Height(i)
+		if !sdf.EqualFloat64(h, -2.5+5*float64(i), 1e-9) {
+			t.Errorf("slice %d: bad height %f", i, h)
+		}
+		contours := ss.Contours(i, r, 0)
+		if len(contours) != 1 || !contours[0].Closed {
+			t.Fatalf("slice %d: expected 1 closed contour", i)
+		}
+		// the contour is a circle
+		radius := math.Sqrt(100 - (h-5)*(h-5))
+		for _, p := range contours[0].Points {
+			if math.Abs(p.Length()-radius) > 0.05 {
+				t.Fatalf("slice %d: expected radius %f, got %f", i, radius, p.Length())
+			}
+		}
+	}
+
+	// multi-layer dxf
+	var buf bytes.Buffer
+	if err := ss.WriteDXF(context.Background(), &buf, r, 0.01, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < ss.Layers(); i++ {
+		if !strings.Contains(buf.String(), fmt.Sprintf("\nSlice%d\n", i)) {
+			t.Errorf("missing layer %d", i)
+		}
+	}
+	if n := strings.Count(buf.String(), "LWPOLYLINE"); n != 4 {
+		t.Errorf("expected 4 polylines, got %d", n)
+	}
+	// progress over the whole stack, and cancellation
+	p := &progressCheck{t: t, name: "slice stack"}
+	if err := ss.WriteDXF(context.Background(), ioutil.Discard, r, 0, p.progress); err != nil {
+		t.Fatal(err)
+	}
+	if p.last != 1 || p.reports < 4 {
+		t.Errorf("%d progress reports, last %f", p.reports, p.last)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p = &progressCheck{t: t, name: "slice stack", cancel: cancel}
+	if err := ss.WriteDXF(ctx, ioutil.Discard, r, 0, p.progress); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+
+	// svg and png files
+	dir, err := ioutil.TempDir("", "sdfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ss.ToSVG(filepath.Join(dir, "slice%d.svg"), r, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.ToPNG(filepath.Join(dir, "slice%d.png"), 0.5); err != nil {
+		t.Fatal(err)
+	}
+	var header string
+	for i := 0; i < ss.Layers(); i++ {
+		b, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("slice%d.svg", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// every slice has the same size
+		h := strings.SplitN(string(b), "<path", 2)[0]
+		if i == 0 {
+			header = h
+			// the area of the slice stack
+			var width, height float64
+			if _, err := fmt.Sscanf(header[strings.Index(header, "<svg"):], `<svg width="%f" height="%f"`, &width, &height); err != nil {
+				t.Fatal(err)
+			}
+			size := ss.BoundingBox().Size()
+			if !sdf.EqualFloat64(width, size.X, 0.01) || !sdf.EqualFloat64(height, size.Y, 0.01) {
+				t.Errorf("expected a %fx%f svg, got %fx%f", size.X, size.Y, width, height)
+			}
+		} else if h != header {
+			t.Errorf("slice %d: different svg header", i)
+		}
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("slice%d.png", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b0 := img.Bounds()
+		if b0.Dx() != 40 || b0.Dy() != 40 {
+			t.Errorf("slice %d: expected 40x40 image, got %dx%d", i, b0.Dx(), b0.Dy())
+		}
+		// solid at the center, empty at the corner
+		if r, _, _, _ := img.At(20, 20).RGBA(); r != 0xffff {
+			t.Errorf("slice %d: expected a solid center", i)
+		}
+		if r, _, _, _ := img.At(0, 0).RGBA(); r != 0 {
+			t.Errorf("slice %d: expected an empty corner", i)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -3,6 +3,10 @@
 
 STL Load/Save
 
+Binary STL files have an 80 byte header that is free form text.
+ASCII STL files have a solid name on the first line.
+Both are kept in an STLInfo when a file is loaded, and can be set when a file is written.
+
 */
 //-----------------------------------------------------------------------------
 
@@ -10,8 +14,12 @@ package render
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -25,8 +33,8 @@ import (
 
 // STLHeader defines the STL file header.
 type STLHeader struct {
-	_     [80]uint8 // Header
-	Count uint32    // Number of triangles
+	Header [80]uint8 // Header
+	Count  uint32    // Number of triangles
 }
 
 // STLTriangle defines the triangle data within an STL file.
@@ -35,6 +43,34 @@ type STLTriangle struct {
 	_                                 uint16 // Attribute byte count
 }
 
+// STLInfo is the metadata for an STL file.
+type STLInfo struct {
+	ASCII  bool   // ASCII format (binary otherwise)
+	Header string // header text for a binary file (at most 80 bytes)
+	Name   string // solid name for an ASCII file
+}
+
+// binaryHeader returns the STL header for a binary file.
+func (info *STLInfo) binaryHeader() (STLHeader, error) {
+	hdr := STLHeader{}
+	if info != nil {
+		if len(info.Header) > len(hdr.Header) {
+			return hdr, errors.New("stl header is longer than 80 bytes")
+		}
+		copy(hdr.Header[:], info.Header)
+	}
+	return hdr, nil
+}
+
+// solidName returns the solid name for an ASCII file.
+func (info *STLInfo) solidName() string {
+	if info == nil {
+		return ""
+	}
+	// the name is on a single line
+	return strings.Join(strings.Fields(info.Name), " ")
+}
+
 //-----------------------------------------------------------------------------
 
 // parseFloats converts float value strings to []float64.
@@ -51,16 +87,21 @@ func parseFloats(in []string) ([]float64, error) {
 }
 
 // loadSTLAscii loads an STL file created in ASCII format.
-func loadSTLAscii(file *os.File) ([]*sdf.Triangle3, error) {
+func loadSTLAscii(file *os.File) ([]*sdf.Triangle3, *STLInfo, error) {
 	var v []v3.Vec
+	info := &STLInfo{ASCII: true}
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
 		fields := strings.Fields(line)
+		if len(fields) >= 1 && fields[0] == "solid" && len(v) == 0 {
+			info.Name = strings.Join(fields[1:], " ")
+			continue
+		}
 		if len(fields) == 4 && fields[0] == "vertex" {
 			f, err := parseFloats(fields[1:])
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			v = append(v, v3.Vec{f[0], f[1], f[2]})
 		}
@@ -70,57 +111,69 @@ func loadSTLAscii(file *os.File) ([]*sdf.Triangle3, error) {
 	for i := 0; i < len(v); i += 3 {
 		mesh = append(mesh, &sdf.Triangle3{v[i+0], v[i+1], v[i+2]})
 	}
-	return mesh, scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return mesh, info, nil
 }
 
 // loadSTLBinary loads an STL file created in binary format.
-func loadSTLBinary(file *os.File) ([]*sdf.Triangle3, error) {
+func loadSTLBinary(file *os.File) ([]*sdf.Triangle3, *STLInfo, error) {
 	r := bufio.NewReader(file)
 	header := STLHeader{}
 	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	// the header text is padded with zeroes or spaces
+	info := &STLInfo{Header: strings.TrimRight(string(header.Header[:]), "\x00 ")}
 	mesh := make([]*sdf.Triangle3, int(header.Count))
 	for i := range mesh {
 		d := STLTriangle{}
 		if err := binary.Read(r, binary.LittleEndian, &d); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		v1 := v3.Vec{float64(d.Vertex1[0]), float64(d.Vertex1[1]), float64(d.Vertex1[2])}
 		v2 := v3.Vec{float64(d.Vertex2[0]), float64(d.Vertex2[1]), float64(d.Vertex2[2])}
 		v3 := v3.Vec{float64(d.Vertex3[0]), float64(d.Vertex3[1]), float64(d.Vertex3[2])}
 		mesh[i] = &sdf.Triangle3{v1, v2, v3}
 	}
-	return mesh, nil
+	return mesh, info, nil
 }
 
 // LoadSTL loads an STL file (ascii or binary) and returns the triangle mesh.
 func LoadSTL(path string) ([]*sdf.Triangle3, error) {
+	mesh, _, err := LoadSTLInfo(path)
+	return mesh, err
+}
+
+// LoadSTLInfo loads an STL file (ascii or binary) and returns the triangle mesh
+// and the file metadata (binary header text or ascii solid name).
+func LoadSTLInfo(path string) ([]*sdf.Triangle3, *STLInfo, error) {
 	// open file
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
 	// get file size
-	info, err := file.Stat()
+	stat, err := file.Stat()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	size := info.Size()
+	size := stat.Size()
 
 	// read header, get expected binary size
 	header := STLHeader{}
 	if err := binary.Read(file, binary.LittleEndian, &header); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	expectedSize := int64(header.Count)*50 + 84
 
 	// rewind to start of file
 	_, err = file.Seek(0, 0)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// parse ascii or binary stl
@@ -132,37 +185,72 @@ func LoadSTL(path string) ([]*sdf.Triangle3, error) {
 
 //-----------------------------------------------------------------------------
 
-// SaveSTL writes a triangle mesh to an STL file.
-func SaveSTL(path string, mesh []*sdf.Triangle3) error {
-	file, err := os.Create(path)
+// newSTLTriangle returns the STL data for a triangle.
+func newSTLTriangle(t *sdf.Triangle3) *STLTriangle {
+	var d STLTriangle
+	n := t.Normal()
+	d.Normal[0] = float32(n.X)
+	d.Normal[1] = float32(n.Y)
+	d.Normal[2] = float32(n.Z)
+	d.Vertex1[0] = float32(t[0].X)
+	d.Vertex1[1] = float32(t[0].Y)
+	d.Vertex1[2] = float32(t[0].Z)
+	d.Vertex2[0] = float32(t[1].X)
+	d.Vertex2[1] = float32(t[1].Y)
+	d.Vertex2[2] = float32(t[1].Z)
+	d.Vertex3[0] = float32(t[2].X)
+	d.Vertex3[1] = float32(t[2].Y)
+	d.Vertex3[2] = float32(t[2].Z)
+	return &d
+}
+
+// formatSTLFloat formats a float for an ASCII STL file.
+// Values have the same (float32) precision as a binary file.
+func formatSTLFloat(x float64) string {
+	return strconv.FormatFloat(float64(float32(x)), 'e', -1, 32)
+}
+
+// writeSTLAsciiFacet writes a triangle to an ASCII STL file.
+func writeSTLAsciiFacet(w io.Writer, t *sdf.Triangle3) {
+	n := t.Normal()
+	fmt.Fprintf(w, "  facet normal %s %s %s\n", formatSTLFloat(n.X), formatSTLFloat(n.Y), formatSTLFloat(n.Z))
+	fmt.Fprintf(w, "    outer loop\n")
+	for _, v := range t {
+		fmt.Fprintf(w, "      vertex %s %s %s\n", formatSTLFloat(v.X), formatSTLFloat(v.Y), formatSTLFloat(v.Z))
+	}
+	fmt.Fprintf(w, "    endloop\n")
+	fmt.Fprintf(w, "  endfacet\n")
+}
+
+// EncodeSTL writes a triangle mesh to w as an STL file.
+// The info sets the format and metadata, nil writes a binary file with an empty header.
+func EncodeSTL(w io.Writer, mesh []*sdf.Triangle3, info *STLInfo) error {
+	buf := bufio.NewWriter(w)
+	if info != nil && info.ASCII {
+		ew := &errWriter{w: buf}
+		name := info.solidName()
+		fmt.Fprintf(ew, "solid %s\n", name)
+		for _, t := range mesh {
+			writeSTLAsciiFacet(ew, t)
+		}
+		fmt.Fprintf(ew, "endsolid %s\n", name)
+		if ew.err != nil {
+			return ew.err
+		}
+		return buf.Flush()
+	}
+
+	header, err := info.binaryHeader()
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	buf := bufio.NewWriter(file)
-	header := STLHeader{}
 	header.Count = uint32(len(mesh))
 	if err := binary.Write(buf, binary.LittleEndian, &header); err != nil {
 		return err
 	}
 
-	var d STLTriangle
 	for _, triangle := range mesh {
-		n := triangle.Normal()
-		d.Normal[0] = float32(n.X)
-		d.Normal[1] = float32(n.Y)
-		d.Normal[2] = float32(n.Z)
-		d.Vertex1[0] = float32(triangle[0].X)
-		d.Vertex1[1] = float32(triangle[0].Y)
-		d.Vertex1[2] = float32(triangle[0].Z)
-		d.Vertex2[0] = float32(triangle[1].X)
-		d.Vertex2[1] = float32(triangle[1].Y)
-		d.Vertex2[2] = float32(triangle[1].Z)
-		d.Vertex3[0] = float32(triangle[2].X)
-		d.Vertex3[1] = float32(triangle[2].Y)
-		d.Vertex3[2] = float32(triangle[2].Z)
-		if err := binary.Write(buf, binary.LittleEndian, &d); err != nil {
+		if err := binary.Write(buf, binary.LittleEndian, newSTLTriangle(triangle)); err != nil {
 			return err
 		}
 	}
@@ -170,77 +258,133 @@ func SaveSTL(path string, mesh []*sdf.Triangle3) error {
 	return buf.Flush()
 }
 
+// SaveSTL writes a triangle mesh to a binary STL file.
+func SaveSTL(path string, mesh []*sdf.Triangle3) error {
+	return toFile(path, func(w io.Writer) error {
+		return EncodeSTL(w, mesh, nil)
+	})
+}
+
 //-----------------------------------------------------------------------------
 
 // writeSTL writes a stream of triangles to an STL file.
-func writeSTL(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error) {
+// Any error (including cancellation of the context) is stored in *err by the time wg.Wait() returns.
+func writeSTL(ctx context.Context, wg *sync.WaitGroup, w io.Writer, info *STLInfo, err *error) chan<- []*sdf.Triangle3 {
+	// External code writes triangles to this channel.
+	// This goroutine reads the channel and writes triangles to the file.
+	c := make(chan []*sdf.Triangle3)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if info != nil && info.ASCII {
+			*err = streamSTLAscii(ctx, w, c, info)
+		} else {
+			*err = streamSTL(ctx, w, c, info)
+		}
+		// keep reading the channel so the renderer doesn't block
+		for range c {
+		}
+	}()
+
+	return c
+}
+
+// streamSTLAscii reads triangles from a channel and writes them to an ASCII STL file.
+func streamSTLAscii(ctx context.Context, w io.Writer, c <-chan []*sdf.Triangle3, info *STLInfo) error {
+	buf := bufio.NewWriter(w)
+	ew := &errWriter{w: buf}
+	name := info.solidName()
+	fmt.Fprintf(ew, "solid %s\n", name)
+	for ts := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, t := range ts {
+			writeSTLAsciiFacet(ew, t)
+		}
+		if ew.err != nil {
+			return ew.err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(ew, "endsolid %s\n", name)
+	if ew.err != nil {
+		return ew.err
+	}
+	return buf.Flush()
+}
+
+// streamSTL reads triangles from a channel and writes them to a binary STL file.
+func streamSTL(ctx context.Context, w io.Writer, c <-chan []*sdf.Triangle3, info *STLInfo) error {
 
-	f, err := os.Create(path)
+	hdr, err := info.binaryHeader()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	// The header has the triangle count. If we can seek we write the header and
+	// rewrite it at the end, otherwise the triangles are buffered until we have the count.
+	ws, seekable := w.(io.WriteSeeker)
+	var start int64
+	if seekable {
+		start, err = ws.Seek(0, io.SeekCurrent)
+		seekable = err == nil
 	}
 
 	// Use buffered IO for optimal IO writes.
 	// The default buffer size doesn't appear to limit performance.
-	buf := bufio.NewWriter(f)
-
-	// write an empty header
-	hdr := STLHeader{}
-	if err := binary.Write(buf, binary.LittleEndian, &hdr); err != nil {
-		return nil, err
+	var body bytes.Buffer
+	var buf *bufio.Writer
+	if seekable {
+		buf = bufio.NewWriter(w)
+		// write the header without the count
+		if err := binary.Write(buf, binary.LittleEndian, &hdr); err != nil {
+			return err
+		}
+	} else {
+		buf = bufio.NewWriter(&body)
 	}
 
-	// External code writes triangles to this channel.
-	// This goroutine reads the channel and writes triangles to the file.
-	c := make(chan []*sdf.Triangle3)
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		defer f.Close()
-
-		var count uint32
-		var d STLTriangle
-		// read triangles from the channel and write them to the file
-		for ts := range c {
-			for _, t := range ts {
-				n := t.Normal()
-				d.Normal[0] = float32(n.X)
-				d.Normal[1] = float32(n.Y)
-				d.Normal[2] = float32(n.Z)
-				d.Vertex1[0] = float32(t[0].X)
-				d.Vertex1[1] = float32(t[0].Y)
-				d.Vertex1[2] = float32(t[0].Z)
-				d.Vertex2[0] = float32(t[1].X)
-				d.Vertex2[1] = float32(t[1].Y)
-				d.Vertex2[2] = float32(t[1].Z)
-				d.Vertex3[0] = float32(t[2].X)
-				d.Vertex3[1] = float32(t[2].Y)
-				d.Vertex3[2] = float32(t[2].Z)
-				if err := binary.Write(buf, binary.LittleEndian, &d); err != nil {
-					fmt.Printf("%s\n", err)
-					return
-				}
-				count++
+	// read triangles from the channel and write them to the file
+	var count uint32
+	for ts := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, t := range ts {
+			if err := binary.Write(buf, binary.LittleEndian, newSTLTriangle(t)); err != nil {
+				return err
 			}
+			count++
 		}
-		// flush the triangles
-		buf.Flush()
+	}
+	// flush the triangles
+	if err := buf.Flush(); err != nil {
+		return err
+	}
 
-		// back to the start of the file
-		if _, err := f.Seek(0, 0); err != nil {
-			fmt.Printf("%s\n", err)
-			return
-		}
-		// rewrite the header with the correct mesh count
-		hdr.Count = count
-		if err := binary.Write(f, binary.LittleEndian, &hdr); err != nil {
-			fmt.Printf("%s\n", err)
-			return
+	hdr.Count = count
+	if !seekable {
+		if err := binary.Write(w, binary.LittleEndian, &hdr); err != nil {
+			return err
 		}
-	}()
+		_, err := body.WriteTo(w)
+		return err
+	}
 
-	return c, nil
+	// back to the start of the file
+	if _, err := ws.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	// rewrite the header with the correct mesh count
+	if err := binary.Write(ws, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	_, err = ws.Seek(0, io.SeekEnd)
+	return err
 }
 
 //-----------------------------------------------------------------------------
@@ -9,7 +9,16 @@ STL File Load/Save Testing
 package render
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
 )
 
 //-----------------------------------------------------------------------------
@@ -18,19 +27,99 @@ func Test_LoadSTL(t *testing.T) {
 	loadTests := []struct {
 		path     string
 		meshSize int
+		info     STLInfo
 	}{
-		{"../files/bottle.stl", 1240},
-		{"../files/monkey.stl", 366},
-		{"../files/teapot.stl", 9438},
+		{"../files/bottle.stl", 1240, STLInfo{ASCII: true, Name: "FLIRIS"}},
+		{"../files/monkey.stl", 366, STLInfo{Header: "Exported from Blender-3.0.0"}},
+		{"../files/teapot.stl", 9438, STLInfo{Header: "Exported from Blender-2.74 (sub 5)"}},
 	}
 	for _, test := range loadTests {
-		mesh, err := LoadSTL(test.path)
+		mesh, info, err := LoadSTLInfo(test.path)
 		if err != nil {
 			t.Errorf("%s", err)
+			continue
 		}
 		if len(mesh) != test.meshSize {
 			t.Errorf("%s expected %d triangles (got %d)", test.path, test.meshSize, len(mesh))
 		}
+		if *info != test.info {
+			t.Errorf("%s expected info %+v (got %+v)", test.path, test.info, *info)
+		}
+	}
+}
+
+func Test_SaveSTL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sdfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, _ := sdf.Box3D(v3.Vec{10, 8, 6}, 1)
+	r := NewMarchingCubesOctree(30)
+	mesh := ToTriangles(s, r)
+
+	tests := []struct {
+		name string
+		info *STLInfo
+		load STLInfo
+	}{
+		{"binary", nil, STLInfo{}},
+		{"header", &STLInfo{Header: "part: box, units: mm"}, STLInfo{Header: "part: box, units: mm"}},
+		{"ascii", &STLInfo{ASCII: true, Name: "box\nv1"}, STLInfo{ASCII: true, Name: "box v1"}},
+	}
+	for _, x := range tests {
+		// rendered and saved meshes are the same
+		var rendered, saved bytes.Buffer
+		if err := WriteSTL(context.Background(), &rendered, s, r, x.info, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := EncodeSTL(&saved, mesh, x.info); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rendered.Bytes(), saved.Bytes()) {
+			t.Errorf("%s: rendered and saved files differ", x.name)
+		}
+		// load the file
+		path := filepath.Join(dir, x.name+".stl")
+		if err := ioutil.WriteFile(path, saved.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		loaded, info, err := LoadSTLInfo(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *info != x.load {
+			t.Errorf("%s: info %+v, expected %+v", x.name, *info, x.load)
+		}
+		if len(loaded) != len(mesh) {
+			t.Fatalf("%s: %d triangles, expected %d", x.name, len(loaded), len(mesh))
+		}
+		for i := range mesh {
+			if !loaded[i].Equals(mesh[i], 1e-5) {
+				t.Errorf("%s: triangle %d is %v, expected %v", x.name, i, loaded[i], mesh[i])
+				break
+			}
+		}
+	}
+
+	// ascii and binary files have the same (float32) values
+	a, _ := LoadSTL(filepath.Join(dir, "ascii.stl"))
+	b, _ := LoadSTL(filepath.Join(dir, "binary.stl"))
+	for i := range a {
+		if !a[i].Equals(b[i], 1e-6) {
+			t.Errorf("triangle %d: ascii %v, binary %v", i, a[i], b[i])
+			break
+		}
+	}
+
+	// the header has a limited size
+	info := &STLInfo{Header: strings.Repeat("x", 81)}
+	if err := EncodeSTL(&bytes.Buffer{}, mesh, info); err == nil {
+		t.Errorf("expected an error for a long header")
+	}
+	if err := WriteSTL(context.Background(), &bytes.Buffer{}, s, r, info, nil); err == nil {
+		t.Errorf("expected an error for a long header")
 	}
 }
 
@@ -9,8 +9,12 @@ Output a 2D line set to an SVG file.
 package render
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"sync"
 
 	svg "github.com/ajstarks/svgo/float"
@@ -25,7 +29,9 @@ type SVG struct {
 	filename  string
 	lineStyle string
 	p0s, p1s  []v2.Vec
+	contours  []*Contour
 	min, max  v2.Vec
+	points    int // number of points in the drawing area
 }
 
 // NewSVG returns an SVG renderer.
@@ -36,38 +42,84 @@ func NewSVG(filename, lineStyle string) *SVG {
 	}
 }
 
-// Line outputs a line to the SVG file.
-func (s *SVG) Line(p0, p1 v2.Vec) {
-	if len(s.p0s) == 0 {
-		s.min = p0.Min(p1)
-		s.max = p0.Max(p1)
+// include extends the drawing area to include a point.
+func (s *SVG) include(p v2.Vec) {
+	if s.points == 0 {
+		s.min = p
+		s.max = p
 	} else {
-		s.min = s.min.Min(p0)
-		s.min = s.min.Min(p1)
-		s.max = s.max.Max(p0)
-		s.max = s.max.Max(p1)
+		s.min = s.min.Min(p)
+		s.max = s.max.Max(p)
 	}
+	s.points++
+}
+
+// Line outputs a line to the SVG file.
+func (s *SVG) Line(p0, p1 v2.Vec) {
+	s.include(p0)
+	s.include(p1)
 	s.p0s = append(s.p0s, p0)
 	s.p1s = append(s.p1s, p1)
 }
 
+// Contours outputs a set of contours (as paths) to the SVG file.
+func (s *SVG) Contours(contours []*Contour) {
+	for _, c := range contours {
+		if len(c.Points) == 0 {
+			continue
+		}
+		for _, p := range c.Points {
+			s.include(p)
+		}
+		s.contours = append(s.contours, c)
+	}
+}
+
 // Save closes the SVG file.
 func (s *SVG) Save() error {
 	f, err := os.Create(s.filename)
 	if err != nil {
 		return err
 	}
+	err = s.write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
 
+// write outputs the SVG data to a writer.
+func (s *SVG) write(w io.Writer) error {
+	// svgo doesn't return errors, so keep track of them
+	buf := bufio.NewWriter(w)
+	ew := &errWriter{w: buf}
 	width := s.max.X - s.min.X
 	height := s.max.Y - s.min.Y
-	canvas := svg.New(f)
+	canvas := svg.New(ew)
 	canvas.Start(width, height)
 	for i, p0 := range s.p0s {
 		p1 := s.p1s[i]
 		canvas.Line(p0.X-s.min.X, s.max.Y-p0.Y, p1.X-s.min.X, s.max.Y-p1.Y, s.lineStyle)
 	}
+	for _, c := range s.contours {
+		var d strings.Builder
+		for i, p := range c.Points {
+			cmd := "L"
+			if i == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&d, "%s%.*f %.*f ", cmd, canvas.Decimals, p.X-s.min.X, canvas.Decimals, s.max.Y-p.Y)
+		}
+		if c.Closed {
+			d.WriteString("Z")
+		}
+		canvas.Path(strings.TrimSpace(d.String()), s.lineStyle)
+	}
 	canvas.End()
-	return f.Close()
+	if ew.err != nil {
+		return ew.err
+	}
+	return buf.Flush()
 }
 
 //-----------------------------------------------------------------------------
@@ -87,10 +139,8 @@ func SaveSVG(path, lineStyle string, mesh []*sdf.Line2) error {
 //-----------------------------------------------------------------------------
 
 // writeSVG writes a stream of line segments to an SVG file.
-func writeSVG(wg *sync.WaitGroup, path, lineStyle string) (chan<- []*sdf.Line2, error) {
-
-	s := NewSVG(path, lineStyle)
-
+// Any error (including cancellation of the context) is stored in *err by the time wg.Wait() returns.
+func writeSVG(ctx context.Context, wg *sync.WaitGroup, w io.Writer, lineStyle string, err *error) chan<- []*sdf.Line2 {
 	// External code writes line segments to this channel.
 	// This goroutine reads the channel and writes line segments to the file.
 	c := make(chan []*sdf.Line2)
@@ -98,19 +148,30 @@ func writeSVG(wg *sync.WaitGroup, path, lineStyle string) (chan<- []*sdf.Line2,
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for ls := range c {
-			for _, l := range ls {
-				s.Line(l[0], l[1])
-			}
-		}
-
-		if err := s.Save(); err != nil {
-			fmt.Printf("%s\n", err)
-			return
+		*err = streamSVG(ctx, w, lineStyle, c)
+		// keep reading the channel so the renderer doesn't block
+		for range c {
 		}
 	}()
 
-	return c, nil
+	return c
+}
+
+// streamSVG reads line segments from a channel and writes them to an SVG file as paths.
+func streamSVG(ctx context.Context, w io.Writer, lineStyle string, c <-chan []*sdf.Line2) error {
+	var lines []*sdf.Line2
+	for ls := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lines = append(lines, ls...)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s := NewSVG("", lineStyle)
+	s.Contours(Contours(lines, 0))
+	return s.write(w)
 }
 
 //-----------------------------------------------------------------------------
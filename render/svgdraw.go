@@ -0,0 +1,294 @@
+//-----------------------------------------------------------------------------
+/*
+
+SVG Drawings
+
+Output filled 2D shapes to an SVG file. Each shape is a single path (the
+outer contours and their holes) with its own fill, stroke and fill rule.
+Items can be put into layers (SVG groups with an id), E.g. to separate the
+cut and engrave operations of a laser cutter.
+
+Bounding boxes and linear dimensions can be overlaid on the drawing.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// SVGStyle is the style of an item in an SVG drawing.
+type SVGStyle struct {
+	Fill        string       // fill color (E.g. "black", "#ff0000", "" for none)
+	Stroke      string       // stroke color ("" for none)
+	StrokeWidth float64      // stroke width (in drawing units)
+	FillRule    sdf.FillRule // fill rule for the holes of shapes
+	Layer       string       // id of the group for the item ("" for none)
+	FontSize    float64      // text size for dimensions (in drawing units)
+}
+
+// default styles for shapes and overlays
+var (
+	svgShapeStyle   = SVGStyle{Fill: "black"}
+	svgOverlayStyle = SVGStyle{Stroke: "blue", StrokeWidth: 0.1}
+)
+
+// svgMargin is the space about the drawing (relative to the drawing size).
+const svgMargin = 0.02
+
+// css returns the style as an svg style attribute value.
+func (s *SVGStyle) css() string {
+	fill := s.Fill
+	if fill == "" {
+		fill = "none"
+	}
+	style := []string{"fill:" + fill}
+	if s.FillRule == sdf.FillEvenOdd {
+		style = append(style, "fill-rule:evenodd")
+	}
+	if s.Stroke != "" {
+		style = append(style, "stroke:"+s.Stroke)
+		style = append(style, "stroke-width:"+strconv.FormatFloat(s.strokeWidth(), 'g', -1, 64))
+	}
+	return strings.Join(style, ";")
+}
+
+func (s *SVGStyle) strokeWidth() float64 {
+	if s.StrokeWidth <= 0 {
+		return 0.1
+	}
+	return s.StrokeWidth
+}
+
+//-----------------------------------------------------------------------------
+
+// svgItem is something drawn as svg elements.
+type svgItem struct {
+	style SVGStyle
+	draw  func(canvas *svg.SVG, m func(v2.Vec) v2.Vec, style string)
+}
+
+// SVGDrawing is an SVG drawing of filled shapes and overlays.
+type SVGDrawing struct {
+	unit   string
+	items  []svgItem
+	bb     sdf.Box2
+	points int // number of points in the bounding box
+}
+
+// NewSVGDrawing returns an empty SVG drawing.
+// The unit (E.g. "mm", "in", or "" for none) gives the drawing its physical size.
+func NewSVGDrawing(unit string) *SVGDrawing {
+	return &SVGDrawing{unit: unit}
+}
+
+// include extends the drawing area to include a point.
+func (d *SVGDrawing) include(p v2.Vec) {
+	if d.points == 0 {
+		d.bb = sdf.Box2{Min: p, Max: p}
+	} else {
+		d.bb = d.bb.Include(p)
+	}
+	d.points++
+}
+
+// add adds an item to the drawing.
+func (d *SVGDrawing) add(style *SVGStyle, defaultStyle SVGStyle, draw func(*svg.SVG, func(v2.Vec) v2.Vec, string)) {
+	if style == nil {
+		style = &defaultStyle
+	}
+	d.items = append(d.items, svgItem{*style, draw})
+}
+
+// svgPathData returns the path data for a set of contours.
+func svgPathData(contours []*Contour, m func(v2.Vec) v2.Vec, decimals int) string {
+	var b strings.Builder
+	for _, c := range contours {
+		for i, p := range c.Points {
+			cmd := "L"
+			if i == 0 {
+				cmd = "M"
+			}
+			p = m(p)
+			fmt.Fprintf(&b, "%s%.*f %.*f ", cmd, decimals, p.X, decimals, p.Y)
+		}
+		if c.Closed {
+			b.WriteString("Z ")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// addContours adds a set of contours to the drawing.
+func (d *SVGDrawing) addContours(contours []*Contour, style *SVGStyle, defaultStyle SVGStyle) {
+	for _, c := range contours {
+		for _, p := range c.Points {
+			d.include(p)
+		}
+	}
+	d.add(style, defaultStyle, func(canvas *svg.SVG, m func(v2.Vec) v2.Vec, css string) {
+		if data := svgPathData(contours, m, canvas.Decimals); data != "" {
+			canvas.Path(data, css)
+		}
+	})
+}
+
+// AddContours adds a shape (a set of contours) to the drawing.
+// The style may be nil for the default (filled black).
+func (d *SVGDrawing) AddContours(contours []*Contour, style *SVGStyle) {
+	d.addContours(contours, style, svgShapeStyle)
+}
+
+// AddSDF2 renders an SDF2 and adds it to the drawing as a shape.
+// The style may be nil for the default (filled black).
+func (d *SVGDrawing) AddSDF2(s sdf.SDF2, r Render2, style *SVGStyle) {
+	d.AddContours(ToContours(s, r, 0), style)
+}
+
+// AddBox adds a box (E.g. a bounding box) to the drawing.
+// The style may be nil for the default (a thin blue outline).
+func (d *SVGDrawing) AddBox(bb sdf.Box2, style *SVGStyle) {
+	c := &Contour{
+		Points: v2.VecSet{bb.Min, {bb.Max.X, bb.Min.Y}, bb.Max, {bb.Min.X, bb.Max.Y}},
+		Closed: true,
+	}
+	d.addContours([]*Contour{c}, style, svgOverlayStyle)
+}
+
+// AddDimension adds a linear dimension between two points to the drawing.
+// The dimension line is offset to the left of p0 to p1 (to the right if offset < 0).
+// The style may be nil for the default (a thin blue line).
+func (d *SVGDrawing) AddDimension(p0, p1 v2.Vec, offset float64, style *SVGStyle) {
+	st := svgOverlayStyle
+	if style != nil {
+		st = *style
+	}
+	u := p1.Sub(p0)
+	length := u.Length()
+	if length == 0 {
+		return
+	}
+	u = u.DivScalar(length)
+	// unit vector towards the dimension line
+	n := v2.Vec{-u.Y, u.X}
+	if offset < 0 {
+		n = n.Neg()
+	}
+	fontSize := st.FontSize
+	if fontSize <= 0 {
+		fontSize = 0.05 * length
+	}
+	a := p0.Add(n.MulScalar(math.Abs(offset)))
+	b := p1.Add(n.MulScalar(math.Abs(offset)))
+	// extension lines go a little past the dimension line
+	ext := n.MulScalar(0.5 * fontSize)
+	mid := a.Add(b).MulScalar(0.5)
+	// the label may be on either side of the dimension line
+	for _, p := range []v2.Vec{p0, p1, a.Add(ext), b.Add(ext), mid.Add(n.MulScalar(1.5 * fontSize)), mid.Sub(n.MulScalar(1.5 * fontSize))} {
+		d.include(p)
+	}
+	label := strconv.FormatFloat(math.Round(length*100)/100, 'f', -1, 64)
+	d.add(&st, svgOverlayStyle, func(canvas *svg.SVG, m func(v2.Vec) v2.Vec, css string) {
+		line := func(p0, p1 v2.Vec) {
+			p0, p1 = m(p0), m(p1)
+			canvas.Line(p0.X, p0.Y, p1.X, p1.Y, css)
+		}
+		line(p0, a.Add(ext))
+		line(p1, b.Add(ext))
+		line(a, b)
+		// arrow heads
+		color := st.Stroke
+		if color == "" {
+			color = "black"
+		}
+		w := n.MulScalar(0.2 * fontSize)
+		for _, arrow := range [][2]v2.Vec{{a, u}, {b, u.Neg()}} {
+			tip := arrow[0]
+			base := tip.Add(arrow[1].MulScalar(0.6 * fontSize))
+			c := &Contour{Points: v2.VecSet{tip, base.Add(w), base.Sub(w)}, Closed: true}
+			canvas.Path(svgPathData([]*Contour{c}, m, canvas.Decimals), "fill:"+color)
+		}
+		// the label is above the dimension line and reads left to right (or upwards)
+		v := m(b).Sub(m(a))
+		theta := math.Atan2(v.Y, v.X)
+		if theta > sdf.DtoR(90.5) || theta < sdf.DtoR(-89.5) {
+			theta += math.Pi
+		}
+		up := v2.Vec{math.Sin(theta), -math.Cos(theta)}
+		t := m(mid).Add(up.MulScalar(0.3 * fontSize))
+		angle := sdf.RtoD(theta)
+		canvas.Text(t.X, t.Y, label,
+			fmt.Sprintf(`transform="rotate(%.*f %.*f %.*f)"`, canvas.Decimals, angle, canvas.Decimals, t.X, canvas.Decimals, t.Y),
+			fmt.Sprintf("text-anchor:middle;font-family:sans-serif;font-size:%gpx;fill:%s", fontSize, color))
+	})
+}
+
+//-----------------------------------------------------------------------------
+
+// Write writes the drawing to w as an SVG file.
+func (d *SVGDrawing) Write(w io.Writer) error {
+	if len(d.items) == 0 {
+		return sdf.ErrMsg("empty drawing")
+	}
+	margin := svgMargin * d.bb.Size().MaxComponent()
+	bb := d.bb.Enlarge(v2.Vec{2 * margin, 2 * margin})
+	size := bb.Size()
+	// svg y is down
+	m := func(p v2.Vec) v2.Vec {
+		return v2.Vec{p.X - bb.Min.X, bb.Max.Y - p.Y}
+	}
+
+	// svgo doesn't return errors, so keep track of them
+	buf := bufio.NewWriter(w)
+	ew := &errWriter{w: buf}
+	canvas := svg.New(ew)
+	canvas.StartviewUnit(size.X, size.Y, d.unit, 0, 0, size.X, size.Y)
+
+	// items with the same layer are in the same group (in the order of the first item)
+	var layers []string
+	seen := make(map[string]bool)
+	for _, item := range d.items {
+		if !seen[item.style.Layer] {
+			seen[item.style.Layer] = true
+			layers = append(layers, item.style.Layer)
+		}
+	}
+	for _, layer := range layers {
+		if layer != "" {
+			canvas.Gid(layer)
+		}
+		for _, item := range d.items {
+			if item.style.Layer == layer {
+				item.draw(canvas, m, item.style.css())
+			}
+		}
+		if layer != "" {
+			canvas.Gend()
+		}
+	}
+	canvas.End()
+	if ew.err != nil {
+		return ew.err
+	}
+	return buf.Flush()
+}
+
+// Save writes the drawing to an SVG file.
+func (d *SVGDrawing) Save(path string) error {
+	return toFile(path, d.Write)
+}
+
+//-----------------------------------------------------------------------------
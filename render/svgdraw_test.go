@@ -0,0 +1,108 @@
+//-----------------------------------------------------------------------------
+/*
+
+SVG Drawing Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_SVGDrawing(t *testing.T) {
+	outer, _ := sdf.Circle2D(10)
+	inner, _ := sdf.Circle2D(5)
+	washer := sdf.Difference2D(outer, inner)
+	square := sdf.Transform2D(sdf.Box2D(v2.Vec{8, 8}, 1), sdf.Translate2d(v2.Vec{20.1, 0.1}))
+	r := NewMarchingSquaresQuadtree(200)
+
+	d := NewSVGDrawing("mm")
+	if err := d.Write(&bytes.Buffer{}); err == nil {
+		t.Error("expected error for an empty drawing")
+	}
+	d.AddSDF2(washer, r, &SVGStyle{Fill: "red", FillRule: sdf.FillEvenOdd, Layer: "cut"})
+	d.AddSDF2(square, r, &SVGStyle{Fill: "blue", Stroke: "black", Layer: "engrave"})
+	var buf bytes.Buffer
+	if err := d.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	// the filled shapes read back
+	shapes, err := sdf.DecodeSVG(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shapes) != 2 {
+		t.Fatalf("expected 2 shapes, got %d", len(shapes))
+	}
+	// the svg origin is the top left of the drawing (with a margin)
+	if !strings.Contains(out, `width="`) || !strings.Contains(out, `mm"`) {
+		t.Error("expected a size in mm")
+	}
+	bb := sdf.Union2D(shapes...).BoundingBox()
+	size := bb.Size()
+	if !sdf.EqualFloat64(size.X, 34.1, 0.1) || !sdf.EqualFloat64(size.Y, 20, 0.1) {
+		t.Errorf("bad size %v", size)
+	}
+	// hole in the washer
+	center := bb.Min.Add(v2.Vec{10, 10})
+	if d := shapes[0].Evaluate(center); !sdf.EqualFloat64(d, 5, 0.05) {
+		t.Errorf("expected 5 at the washer center, got %f", d)
+	}
+	if d := shapes[0].Evaluate(center.Add(v2.Vec{7.5, 0})); !sdf.EqualFloat64(d, -2.5, 0.05) {
+		t.Errorf("expected -2.5 in the washer, got %f", d)
+	}
+
+	// styles and layers
+	for _, s := range []string{
+		`<g id="cut">`,
+		`<g id="engrave">`,
+		"fill:red;fill-rule:evenodd",
+		"fill:blue;stroke:black;stroke-width:0.1",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("missing %q", s)
+		}
+	}
+	if n := strings.Count(out, "<path"); n != 2 {
+		t.Errorf("expected 2 paths, got %d", n)
+	}
+
+	// overlays
+	d.AddBox(washer.BoundingBox(), nil)
+	d.AddDimension(v2.Vec{-10, -10}, v2.Vec{10, -10}, -3, &SVGStyle{Stroke: "green", Layer: "dimensions", FontSize: 2})
+	buf.Reset()
+	if err := d.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out = buf.String()
+	for _, s := range []string{`<g id="dimensions">`, ">20</text>", "stroke:blue", "stroke:green"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("missing %q", s)
+		}
+	}
+	// well formed xml
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
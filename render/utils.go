@@ -4,6 +4,8 @@
 
 package render
 
+import "io"
+
 //-----------------------------------------------------------------------------
 
 const tolerance = 1e-9
@@ -49,3 +51,21 @@ func mapCombinations(n, k int, f func([]int)) {
 }
 
 //-----------------------------------------------------------------------------
+
+// errWriter is a writer that keeps the first error from the underlying writer.
+// Subsequent writes are dropped.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
+
+//-----------------------------------------------------------------------------
@@ -80,6 +80,12 @@ func (a Box2) Contains(v v2.Vec) bool {
 		v.Y <= a.Max.Y
 }
 
+// Overlap returns true if two 2d boxes overlap.
+func (a Box2) Overlap(b Box2) bool {
+	return a.Min.X <= b.Max.X && b.Min.X <= a.Max.X &&
+		a.Min.Y <= b.Max.Y && b.Min.Y <= a.Max.Y
+}
+
 // Vertices returns a slice of 2d box corner vertices.
 func (a Box2) Vertices() v2.VecSet {
 	return []v2.Vec{
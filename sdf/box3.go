@@ -79,6 +79,13 @@ func (a Box3) Contains(v v3.Vec) bool {
 		v.Z <= a.Max.Z
 }
 
+// Overlap returns true if two 3d boxes overlap.
+func (a Box3) Overlap(b Box3) bool {
+	return a.Min.X <= b.Max.X && b.Min.X <= a.Max.X &&
+		a.Min.Y <= b.Max.Y && b.Min.Y <= a.Max.Y &&
+		a.Min.Z <= b.Max.Z && b.Min.Z <= a.Max.Z
+}
+
 // Vertices returns a slice of 3d box corner vertices.
 func (a Box3) Vertices() v3.VecSet {
 	return []v3.Vec{
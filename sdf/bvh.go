@@ -0,0 +1,294 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bounding Volume Hierarchies
+
+A binary tree of bounding boxes over a set of items (e.g. the SDFs in a union).
+Queries descend only into the nodes whose boxes are of interest, so they
+visit a small fraction of the items.
+
+The tree is built top down. The items in a node are sorted by the center of
+their bounding boxes along the longest axis and split at the median.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"sort"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// bvhLeafSize is the maximum number of items in a leaf node.
+const bvhLeafSize = 4
+
+// bvhStackSize bounds the depth of the tree traversal.
+// Median splits give a depth of log2(n), so this is ample.
+const bvhStackSize = 64
+
+//-----------------------------------------------------------------------------
+// 3D
+
+type bvhNode3 struct {
+	bb          Box3 // bounding box of all items in the node
+	left, right int  // child node indices (leaf nodes: -1)
+	start, end  int  // leaf node item range
+}
+
+// bvh3 is a bounding volume hierarchy over a set of 3d boxes.
+type bvh3 struct {
+	node []bvhNode3
+	bb   []Box3 // item bounding boxes
+	item []int  // item indices, in leaf order
+}
+
+// newBVH3 returns a bounding volume hierarchy for a set of 3d boxes.
+func newBVH3(bb []Box3) *bvh3 {
+	t := &bvh3{
+		bb:   bb,
+		item: make([]int, len(bb)),
+	}
+	for i := range t.item {
+		t.item[i] = i
+	}
+	t.build(0, len(bb))
+	return t
+}
+
+// build adds the node for items [start, end) and returns its index.
+func (t *bvh3) build(start, end int) int {
+	bb := t.bb[t.item[start]]
+	cb := Box3{bb.Center(), bb.Center()}
+	for _, i := range t.item[start+1 : end] {
+		bb = bb.Extend(t.bb[i])
+		cb = cb.Include(t.bb[i].Center())
+	}
+	k := len(t.node)
+	t.node = append(t.node, bvhNode3{bb: bb, left: -1, right: -1, start: start, end: end})
+	if end-start <= bvhLeafSize {
+		return k
+	}
+	// split on the longest axis of the item centers
+	size := cb.Size()
+	axis := 0
+	if size.Y > size.X && size.Y >= size.Z {
+		axis = 1
+	} else if size.Z > size.X && size.Z > size.Y {
+		axis = 2
+	}
+	items := t.item[start:end]
+	sort.Slice(items, func(i, j int) bool {
+		return t.bb[items[i]].Center().Get(axis) < t.bb[items[j]].Center().Get(axis)
+	})
+	mid := (start + end) / 2
+	left := t.build(start, mid)
+	right := t.build(mid, end)
+	t.node[k].left = left
+	t.node[k].right = right
+	return k
+}
+
+// minimum returns the minimum of f(i) over the items, skipping the items
+// whose boxes don't contain p and are further away than the current minimum.
+// The distance to an item is assumed to be no less than the distance to its box.
+func (t *bvh3) minimum(p v3.Vec, f func(i int) float64) float64 {
+	d := math.Inf(1)
+	// distance squared from p to a box
+	dist2 := func(bb Box3) float64 {
+		return p.Clamp(bb.Min, bb.Max).Sub(p).Length2()
+	}
+	// skip returns true if the box is further away than the current minimum
+	skip := func(bb Box3) bool {
+		d2 := dist2(bb)
+		return d2 > 0 && (d <= 0 || d2 >= d*d)
+	}
+	var stack [bvhStackSize]int
+	n := 1
+	for n > 0 {
+		n--
+		node := &t.node[stack[n]]
+		if skip(node.bb) {
+			continue
+		}
+		if node.left < 0 {
+			for _, i := range t.item[node.start:node.end] {
+				if !skip(t.bb[i]) {
+					d = math.Min(d, f(i))
+				}
+			}
+			continue
+		}
+		// visit the nearer child first
+		l, r := node.left, node.right
+		if dist2(t.node[l].bb) < dist2(t.node[r].bb) {
+			l, r = r, l
+		}
+		stack[n] = l
+		stack[n+1] = r
+		n += 2
+	}
+	return d
+}
+
+// overlap calls f for the items with boxes that overlap a box.
+func (t *bvh3) overlap(bb Box3, f func(i int)) {
+	var stack [bvhStackSize]int
+	n := 1
+	for n > 0 {
+		n--
+		node := &t.node[stack[n]]
+		if !node.bb.Overlap(bb) {
+			continue
+		}
+		if node.left < 0 {
+			for _, i := range t.item[node.start:node.end] {
+				if t.bb[i].Overlap(bb) {
+					f(i)
+				}
+			}
+			continue
+		}
+		stack[n] = node.left
+		stack[n+1] = node.right
+		n += 2
+	}
+}
+
+//-----------------------------------------------------------------------------
+// 2D
+
+type bvhNode2 struct {
+	bb          Box2 // bounding box of all items in the node
+	left, right int  // child node indices (leaf nodes: -1)
+	start, end  int  // leaf node item range
+}
+
+// bvh2 is a bounding volume hierarchy over a set of 2d boxes.
+type bvh2 struct {
+	node []bvhNode2
+	bb   []Box2 // item bounding boxes
+	item []int  // item indices, in leaf order
+}
+
+// newBVH2 returns a bounding volume hierarchy for a set of 2d boxes.
+func newBVH2(bb []Box2) *bvh2 {
+	t := &bvh2{
+		bb:   bb,
+		item: make([]int, len(bb)),
+	}
+	for i := range t.item {
+		t.item[i] = i
+	}
+	t.build(0, len(bb))
+	return t
+}
+
+// build adds the node for items [start, end) and returns its index.
+func (t *bvh2) build(start, end int) int {
+	bb := t.bb[t.item[start]]
+	cb := Box2{bb.Center(), bb.Center()}
+	for _, i := range t.item[start+1 : end] {
+		bb = bb.Extend(t.bb[i])
+		cb = cb.Include(t.bb[i].Center())
+	}
+	k := len(t.node)
+	t.node = append(t.node, bvhNode2{bb: bb, left: -1, right: -1, start: start, end: end})
+	if end-start <= bvhLeafSize {
+		return k
+	}
+	// split on the longest axis of the item centers
+	size := cb.Size()
+	axis := 0
+	if size.Y > size.X {
+		axis = 1
+	}
+	items := t.item[start:end]
+	sort.Slice(items, func(i, j int) bool {
+		ci := t.bb[items[i]].Center()
+		cj := t.bb[items[j]].Center()
+		if axis == 0 {
+			return ci.X < cj.X
+		}
+		return ci.Y < cj.Y
+	})
+	mid := (start + end) / 2
+	left := t.build(start, mid)
+	right := t.build(mid, end)
+	t.node[k].left = left
+	t.node[k].right = right
+	return k
+}
+
+// minimum returns the minimum of f(i) over the items, skipping the items
+// whose boxes don't contain p and are further away than the current minimum.
+// The distance to an item is assumed to be no less than the distance to its box.
+func (t *bvh2) minimum(p v2.Vec, f func(i int) float64) float64 {
+	d := math.Inf(1)
+	// distance squared from p to a box
+	dist2 := func(bb Box2) float64 {
+		return p.Clamp(bb.Min, bb.Max).Sub(p).Length2()
+	}
+	// skip returns true if the box is further away than the current minimum
+	skip := func(bb Box2) bool {
+		d2 := dist2(bb)
+		return d2 > 0 && (d <= 0 || d2 >= d*d)
+	}
+	var stack [bvhStackSize]int
+	n := 1
+	for n > 0 {
+		n--
+		node := &t.node[stack[n]]
+		if skip(node.bb) {
+			continue
+		}
+		if node.left < 0 {
+			for _, i := range t.item[node.start:node.end] {
+				if !skip(t.bb[i]) {
+					d = math.Min(d, f(i))
+				}
+			}
+			continue
+		}
+		// visit the nearer child first
+		l, r := node.left, node.right
+		if dist2(t.node[l].bb) < dist2(t.node[r].bb) {
+			l, r = r, l
+		}
+		stack[n] = l
+		stack[n+1] = r
+		n += 2
+	}
+	return d
+}
+
+// overlap calls f for the items with boxes that overlap a box.
+func (t *bvh2) overlap(bb Box2, f func(i int)) {
+	var stack [bvhStackSize]int
+	n := 1
+	for n > 0 {
+		n--
+		node := &t.node[stack[n]]
+		if !node.bb.Overlap(bb) {
+			continue
+		}
+		if node.left < 0 {
+			for _, i := range t.item[node.start:node.end] {
+				if t.bb[i].Overlap(bb) {
+					f(i)
+				}
+			}
+			continue
+		}
+		stack[n] = node.left
+		stack[n+1] = node.right
+		n += 2
+	}
+}
+
+//-----------------------------------------------------------------------------
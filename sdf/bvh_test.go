@@ -0,0 +1,114 @@
+//-----------------------------------------------------------------------------
+
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// holes3 returns a grid of n x n cylinders and spheres.
+func holes3(n int) []SDF3 {
+	cylinder, _ := Cylinder3D(5, 1, 0)
+	sphere, _ := Sphere3D(1.5)
+	s := make([]SDF3, 0, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			x := sphere
+			if (i+j)&1 == 0 {
+				x = cylinder
+			}
+			m := Translate3d(v3.Vec{float64(4 * i), float64(4 * j), float64(i % 3)})
+			s = append(s, Transform3D(x, m))
+		}
+	}
+	return s
+}
+
+// holes2 returns a grid of n x n circles and boxes.
+func holes2(n int) []SDF2 {
+	circle, _ := Circle2D(1)
+	box := Box2D(v2.Vec{2, 1}, 0.2)
+	s := make([]SDF2, 0, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			x := circle
+			if (i+j)&1 == 0 {
+				x = box
+			}
+			s = append(s, Transform2D(x, Translate2d(v2.Vec{float64(3 * i), float64(3*j + i%2)})))
+		}
+	}
+	return s
+}
+
+func Test_UnionBVH3(t *testing.T) {
+	for _, n := range []int{2, 3, 10} {
+		children := holes3(n)
+		s := Union3D(children...)
+		b := s.BoundingBox().ScaleAboutCenter(1.5)
+		for _, p := range b.RandomSet(2000) {
+			d0 := s.Evaluate(p)
+			d1 := math.Inf(1)
+			for _, x := range children {
+				d1 = math.Min(d1, x.Evaluate(p))
+			}
+			if math.Abs(d0-d1) > tolerance {
+				t.Errorf("n %d %v %f (expected) %f (actual)", n, p, d1, d0)
+			}
+		}
+	}
+}
+
+func Test_UnionBVH2(t *testing.T) {
+	for _, n := range []int{2, 3, 10} {
+		children := holes2(n)
+		s := Union2D(children...)
+		b := s.BoundingBox().ScaleAboutCenter(1.5)
+		for _, p := range b.RandomSet(2000) {
+			d0 := s.Evaluate(p)
+			d1 := s.(*UnionSDF2).EvaluateSlow(p)
+			if math.Abs(d0-d1) > tolerance {
+				t.Errorf("n %d %v %f (expected) %f (actual)", n, p, d1, d0)
+			}
+		}
+	}
+}
+
+func Test_UnionBVH_Interval(t *testing.T) {
+	checkInterval3(t, "bvh union", Union3D(holes3(10)...))
+	checkInterval2(t, "bvh union", Union2D(holes2(10)...))
+}
+
+//-----------------------------------------------------------------------------
+
+func Benchmark_Union3D(b *testing.B) {
+	s := Union3D(holes3(20)...)
+	bb := s.BoundingBox()
+	p := bb.RandomSet(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Evaluate(p[i%len(p)])
+	}
+}
+
+func Benchmark_Union3DBlend(b *testing.B) {
+	s := Union3D(holes3(20)...)
+	// a blended union evaluates every sdf
+	s.(*UnionSDF3).SetMin(math.Min)
+	bb := s.BoundingBox()
+	p := bb.RandomSet(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Evaluate(p[i%len(p)])
+	}
+}
+
+//-----------------------------------------------------------------------------
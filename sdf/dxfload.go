@@ -0,0 +1,552 @@
+//-----------------------------------------------------------------------------
+/*
+
+DXF Import
+
+Read the closed profiles of a DXF file as SDF2s.
+
+Supported entities are LINE, ARC, CIRCLE, LWPOLYLINE (with bulges) and SPLINE.
+Arcs and splines are converted to line segments. Entities with end points that
+meet are chained into closed loops. Loops inside other loops are holes, so each
+outer loop and its holes become an SDF2 (islands within holes are new outer loops).
+
+Only the ENTITIES section of an ASCII DXF file is read (blocks are not expanded).
+Coordinates are in drawing units and the z values are ignored.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// dxfArcStep is the maximum angle of the line segments approximating an arc.
+var dxfArcStep = DtoR(5)
+
+// dxfSplineSteps is the number of line segments per spline control point.
+const dxfSplineSteps = 8
+
+// dxfSnap is the gap allowed between joined end points (relative to the drawing size).
+const dxfSnap = 1e-6
+
+//-----------------------------------------------------------------------------
+// Group Codes
+
+// dxfPair is a group code and its value.
+type dxfPair struct {
+	code  int
+	value string
+}
+
+// dxfReader reads the group code/value pairs of a dxf file.
+type dxfReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// read returns the next group code/value pair.
+func (r *dxfReader) read() (dxfPair, error) {
+	var lines [2]string
+	for i := range lines {
+		if !r.s.Scan() {
+			if err := r.s.Err(); err != nil {
+				return dxfPair{}, err
+			}
+			return dxfPair{}, io.ErrUnexpectedEOF
+		}
+		r.line++
+		lines[i] = strings.TrimSpace(r.s.Text())
+	}
+	code, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return dxfPair{}, fmt.Errorf("line %d: bad group code %q", r.line-1, lines[0])
+	}
+	return dxfPair{code, lines[1]}, nil
+}
+
+//-----------------------------------------------------------------------------
+// Entities
+
+// dxfEntity is an entity type and its group code/value pairs.
+type dxfEntity struct {
+	kind  string
+	pairs []dxfPair
+}
+
+// float returns the value of the first group with a code (or the default).
+func (e *dxfEntity) float(code int, x float64) (float64, error) {
+	for _, p := range e.pairs {
+		if p.code == code {
+			return e.parse(p)
+		}
+	}
+	return x, nil
+}
+
+// floats returns the values of the group codes (the default is 0).
+func (e *dxfEntity) floats(codes ...int) ([]float64, error) {
+	x := make([]float64, len(codes))
+	for i, code := range codes {
+		var err error
+		if x[i], err = e.float(code, 0); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// parse returns the float value of a group.
+func (e *dxfEntity) parse(p dxfPair) (float64, error) {
+	x, err := strconv.ParseFloat(p.value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: bad value %q for group code %d", e.kind, p.value, p.code)
+	}
+	return x, nil
+}
+
+// mirror returns true if the entity has a mirrored object coordinate system.
+// Arcs, circles and polylines use an extrusion direction of (0,0,-1) to flip them.
+func (e *dxfEntity) mirror() (bool, error) {
+	z, err := e.float(230, 1)
+	return z < 0, err
+}
+
+// dxfArc returns the points of an arc (including both ends).
+func dxfArc(c v2.Vec, r, a0, sweep float64) []v2.Vec {
+	n := int(math.Ceil(math.Abs(sweep) / dxfArcStep))
+	if n < 1 {
+		n = 1
+	}
+	pts := make([]v2.Vec, n+1)
+	for i := range pts {
+		a := a0 + sweep*float64(i)/float64(n)
+		pts[i] = c.Add(v2.Vec{math.Cos(a), math.Sin(a)}.MulScalar(r))
+	}
+	return pts
+}
+
+// dxfBulge returns the points of a polyline segment with a bulge (including both ends).
+// The bulge is the tangent of 1/4 of the included angle, positive is counter-clockwise.
+func dxfBulge(p0, p1 v2.Vec, bulge float64) []v2.Vec {
+	if bulge == 0 || p0.Equals(p1, epsilon) {
+		return []v2.Vec{p0, p1}
+	}
+	theta := 4 * math.Atan(bulge)
+	chord := p1.Sub(p0)
+	l := chord.Length()
+	// the center is to the left of the chord for a counter-clockwise arc
+	left := v2.Vec{-chord.Y, chord.X}.DivScalar(l)
+	c := p0.Add(p1).MulScalar(0.5).Add(left.MulScalar(0.5 * l / math.Tan(0.5*theta)))
+	r := p0.Sub(c).Length()
+	a0 := math.Atan2(p0.Y-c.Y, p0.X-c.X)
+	pts := dxfArc(c, r, a0, theta)
+	// exact end points so the segments join
+	pts[0] = p0
+	pts[len(pts)-1] = p1
+	return pts
+}
+
+// dxfSpline returns points on a (rational) b-spline.
+func dxfSpline(degree int, knots []float64, ctrl []v2.Vec, weights []float64) ([]v2.Vec, error) {
+	n := len(ctrl)
+	if degree < 1 || n <= degree {
+		return nil, fmt.Errorf("spline: degree %d with %d control points", degree, n)
+	}
+	if len(knots) != n+degree+1 {
+		return nil, fmt.Errorf("spline: %d knots for degree %d with %d control points", len(knots), degree, n)
+	}
+	if len(weights) != n {
+		weights = make([]float64, n)
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	t0, t1 := knots[degree], knots[n]
+	m := n * dxfSplineSteps
+	pts := make([]v2.Vec, m+1)
+	d := make([][3]float64, degree+1)
+	for k := range pts {
+		t := t0 + (t1-t0)*float64(k)/float64(m)
+		// find the knot span
+		span := degree
+		for span < n-1 && knots[span+1] <= t {
+			span++
+		}
+		// de Boor's algorithm in homogeneous coordinates
+		for j := range d {
+			i := j + span - degree
+			w := weights[i]
+			d[j] = [3]float64{ctrl[i].X * w, ctrl[i].Y * w, w}
+		}
+		for r := 1; r <= degree; r++ {
+			for j := degree; j >= r; j-- {
+				i := j + span - degree
+				alpha := 0.0
+				if den := knots[i+degree-r+1] - knots[i]; den != 0 {
+					alpha = (t - knots[i]) / den
+				}
+				for c := range d[j] {
+					d[j][c] = (1-alpha)*d[j-1][c] + alpha*d[j][c]
+				}
+			}
+		}
+		pts[k] = v2.Vec{d[degree][0], d[degree][1]}.DivScalar(d[degree][2])
+	}
+	return pts, nil
+}
+
+// path returns the points of an entity and if it is a closed loop.
+// Unsupported entities return no points.
+func (e *dxfEntity) path() ([]v2.Vec, bool, error) {
+	var pts []v2.Vec
+	closed := false
+	switch e.kind {
+	case "LINE":
+		x, err := e.floats(10, 20, 11, 21)
+		if err != nil {
+			return nil, false, err
+		}
+		pts = []v2.Vec{{x[0], x[1]}, {x[2], x[3]}}
+	case "CIRCLE", "ARC":
+		x, err := e.floats(10, 20, 40, 50, 51)
+		if err != nil {
+			return nil, false, err
+		}
+		if x[2] <= 0 {
+			return nil, false, fmt.Errorf("%s: radius <= 0", e.kind)
+		}
+		c := v2.Vec{x[0], x[1]}
+		if e.kind == "CIRCLE" {
+			pts = dxfArc(c, x[2], 0, Tau)
+			pts = pts[:len(pts)-1]
+			closed = true
+			break
+		}
+		// arcs are counter-clockwise from the start to the end angle (in degrees)
+		sweep := math.Mod(x[4]-x[3], 360)
+		if sweep <= 0 {
+			sweep += 360
+		}
+		pts = dxfArc(c, x[2], DtoR(x[3]), DtoR(sweep))
+	case "LWPOLYLINE":
+		var vertex []v2.Vec
+		var bulge []float64
+		for _, p := range e.pairs {
+			switch p.code {
+			case 10, 20, 42:
+				if p.code != 10 && len(vertex) == 0 {
+					return nil, false, fmt.Errorf("%s: group code %d before a vertex", e.kind, p.code)
+				}
+				x, err := e.parse(p)
+				if err != nil {
+					return nil, false, err
+				}
+				switch p.code {
+				case 10:
+					vertex = append(vertex, v2.Vec{x, 0})
+					bulge = append(bulge, 0)
+				case 20:
+					vertex[len(vertex)-1].Y = x
+				case 42:
+					bulge[len(bulge)-1] = x
+				}
+			case 70:
+				flags, err := strconv.Atoi(p.value)
+				if err != nil {
+					return nil, false, fmt.Errorf("%s: bad flags %q", e.kind, p.value)
+				}
+				closed = flags&1 != 0
+			}
+		}
+		if len(vertex) == 0 {
+			return nil, false, nil
+		}
+		pts = []v2.Vec{vertex[0]}
+		n := len(vertex)
+		if !closed {
+			n--
+		}
+		for i := 0; i < n; i++ {
+			arc := dxfBulge(vertex[i], vertex[(i+1)%len(vertex)], bulge[i])
+			pts = append(pts, arc[1:]...)
+		}
+		if closed {
+			// the last point is the first point
+			pts = pts[:len(pts)-1]
+		}
+	case "SPLINE":
+		degree := 3
+		var knots, weights []float64
+		var ctrl, fit []v2.Vec
+		for _, p := range e.pairs {
+			switch p.code {
+			case 71:
+				var err error
+				if degree, err = strconv.Atoi(p.value); err != nil {
+					return nil, false, fmt.Errorf("%s: bad degree %q", e.kind, p.value)
+				}
+			case 10, 20, 11, 21, 40, 41:
+				x, err := e.parse(p)
+				if err != nil {
+					return nil, false, err
+				}
+				switch p.code {
+				case 10:
+					ctrl = append(ctrl, v2.Vec{x, 0})
+				case 20:
+					if len(ctrl) == 0 {
+						return nil, false, fmt.Errorf("%s: y before x", e.kind)
+					}
+					ctrl[len(ctrl)-1].Y = x
+				case 11:
+					fit = append(fit, v2.Vec{x, 0})
+				case 21:
+					if len(fit) == 0 {
+						return nil, false, fmt.Errorf("%s: y before x", e.kind)
+					}
+					fit[len(fit)-1].Y = x
+				case 40:
+					knots = append(knots, x)
+				case 41:
+					weights = append(weights, x)
+				}
+			}
+		}
+		if len(ctrl) == 0 {
+			// approximate a fit point spline with its fit points
+			pts = fit
+			break
+		}
+		var err error
+		if pts, err = dxfSpline(degree, knots, ctrl, weights); err != nil {
+			return nil, false, err
+		}
+	default:
+		return nil, false, nil
+	}
+	if e.kind != "LINE" && e.kind != "SPLINE" {
+		mirror, err := e.mirror()
+		if err != nil {
+			return nil, false, err
+		}
+		if mirror {
+			for i := range pts {
+				pts[i].X = -pts[i].X
+			}
+		}
+	}
+	return pts, closed, nil
+}
+
+//-----------------------------------------------------------------------------
+// Loops
+
+// dxfChain joins paths with matching end points into closed loops.
+// The loops do not repeat the first point at the end.
+func dxfChain(paths [][]v2.Vec, tol float64) ([][]v2.Vec, error) {
+	used := make([]bool, len(paths))
+	var loops [][]v2.Vec
+	for i, p := range paths {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		loop := append([]v2.Vec{}, p...)
+		for !loop[0].Equals(loop[len(loop)-1], tol) {
+			end := loop[len(loop)-1]
+			found := false
+			for j, q := range paths {
+				if used[j] {
+					continue
+				}
+				if end.Equals(q[len(q)-1], tol) {
+					// join the path in reverse
+					q = append([]v2.Vec{}, q...)
+					for a, b := 0, len(q)-1; a < b; a, b = a+1, b-1 {
+						q[a], q[b] = q[b], q[a]
+					}
+				} else if !end.Equals(q[0], tol) {
+					continue
+				}
+				loop = append(loop, q[1:]...)
+				used[j] = true
+				found = true
+				break
+			}
+			if !found {
+				return nil, fmt.Errorf("open profile at %v", end)
+			}
+		}
+		loop = loop[:len(loop)-1]
+		if len(loop) >= 3 {
+			loops = append(loops, loop)
+		}
+	}
+	return loops, nil
+}
+
+// dxfProfiles returns the SDF2s for the outer loops and their holes.
+func dxfProfiles(loops [][]v2.Vec) ([]SDF2, error) {
+	lines := make([][]*Line2, len(loops))
+	bb := make([]Box2, len(loops))
+	for i, loop := range loops {
+		lines[i] = VertexToLine(loop, true)
+		bb[i] = Box2{loop[0], loop[0]}
+		for _, v := range loop {
+			bb[i] = bb[i].Include(v)
+		}
+	}
+	// inside returns true if loop i is inside loop j
+	inside := func(i, j int) bool {
+		if !bb[j].Contains(bb[i].Min) || !bb[j].Contains(bb[i].Max) {
+			return false
+		}
+		wn := 0
+		for _, l := range lines[j] {
+			wn += newLineInfo(l).winding(loops[i][0])
+		}
+		return wn != 0
+	}
+	// the nesting depth of each loop, odd depths are holes
+	depth := make([]int, len(loops))
+	parent := make([]int, len(loops))
+	for i := range loops {
+		for j := range loops {
+			if i != j && inside(i, j) {
+				depth[i]++
+			}
+		}
+	}
+	for i := range loops {
+		parent[i] = -1
+		if depth[i]%2 == 0 {
+			continue
+		}
+		for j := range loops {
+			if depth[j] == depth[i]-1 && inside(i, j) {
+				parent[i] = j
+				break
+			}
+		}
+	}
+	var shapes []SDF2
+	for i := range loops {
+		if depth[i]%2 != 0 {
+			continue
+		}
+		mesh := append([]*Line2{}, lines[i]...)
+		for j := range loops {
+			if parent[j] == i {
+				mesh = append(mesh, lines[j]...)
+			}
+		}
+		s, err := Mesh2DFill(mesh, FillEvenOdd)
+		if err != nil {
+			return nil, err
+		}
+		shapes = append(shapes, s)
+	}
+	return shapes, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// dxfEntities returns the entities in the ENTITIES section of a dxf file.
+func dxfEntities(r io.Reader) ([]*dxfEntity, error) {
+	d := &dxfReader{s: bufio.NewScanner(r)}
+	var entities []*dxfEntity
+	var e *dxfEntity
+	section := ""
+	for {
+		p, err := d.read()
+		if err != nil {
+			return nil, err
+		}
+		if p.code != 0 {
+			if e != nil {
+				e.pairs = append(e.pairs, p)
+			} else if p.code == 2 && section == "SECTION" {
+				section = p.value
+			}
+			continue
+		}
+		e = nil
+		switch p.value {
+		case "EOF":
+			return entities, nil
+		case "SECTION":
+			section = "SECTION"
+		case "ENDSEC":
+			section = ""
+		default:
+			if section == "ENTITIES" {
+				e = &dxfEntity{kind: p.value}
+				entities = append(entities, e)
+			}
+		}
+	}
+}
+
+// DecodeDXF returns the closed profiles of a dxf document as SDF2s (one per outer loop).
+// Use Union2D to combine them.
+func DecodeDXF(r io.Reader) ([]SDF2, error) {
+	entities, err := dxfEntities(r)
+	if err != nil {
+		return nil, err
+	}
+	var loops, paths [][]v2.Vec
+	bb := Box2{v2.Vec{math.Inf(1), math.Inf(1)}, v2.Vec{math.Inf(-1), math.Inf(-1)}}
+	for _, e := range entities {
+		pts, closed, err := e.path()
+		if err != nil {
+			return nil, err
+		}
+		if len(pts) < 2 {
+			continue
+		}
+		for _, v := range pts {
+			bb = bb.Include(v)
+		}
+		if closed {
+			loops = append(loops, pts)
+		} else {
+			paths = append(paths, pts)
+		}
+	}
+	if len(loops) == 0 && len(paths) == 0 {
+		return nil, ErrMsg("no profiles")
+	}
+	chained, err := dxfChain(paths, dxfSnap*bb.Size().MaxComponent())
+	if err != nil {
+		return nil, err
+	}
+	loops = append(loops, chained...)
+	if len(loops) == 0 {
+		return nil, ErrMsg("no profiles")
+	}
+	return dxfProfiles(loops)
+}
+
+// LoadDXF returns the closed profiles of a dxf file as SDF2s (one per outer loop). See DecodeDXF.
+func LoadDXF(path string) ([]SDF2, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeDXF(f)
+}
+
+//-----------------------------------------------------------------------------
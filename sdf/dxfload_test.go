@@ -0,0 +1,145 @@
+//-----------------------------------------------------------------------------
+/*
+
+DXF Import Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// dxfDoc returns a dxf document with the entities.
+func dxfDoc(entities ...string) string {
+	s := []string{"0", "SECTION", "2", "HEADER", "9", "$ACADVER", "1", "AC1015", "0", "ENDSEC"}
+	s = append(s, "0", "SECTION", "2", "ENTITIES")
+	s = append(s, entities...)
+	s = append(s, "0", "ENDSEC", "0", "EOF")
+	return strings.Join(s, "\n")
+}
+
+func dxfLine(x0, y0, x1, y1 float64) string {
+	return fmt.Sprintf("0\nLINE\n8\n0\n10\n%g\n20\n%g\n30\n0\n11\n%g\n21\n%g\n31\n0", x0, y0, x1, y1)
+}
+
+func Test_dxfSpline(t *testing.T) {
+	p := []v2.Vec{{0, 0}, {1, 2}, {2, 0}}
+	// a clamped quadratic b-spline is a quadratic bezier
+	pts, err := dxfSpline(2, []float64{0, 0, 0, 1, 1, 1}, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mid := pts[len(pts)/2]
+	if !mid.Equals(v2.Vec{1, 1}, tolerance) {
+		t.Errorf("expected {1 1}, got %v", mid)
+	}
+	if !pts[0].Equals(p[0], tolerance) || !pts[len(pts)-1].Equals(p[2], tolerance) {
+		t.Errorf("bad end points %v %v", pts[0], pts[len(pts)-1])
+	}
+	// a rational quadratic with the right weight is a circular arc
+	w := []float64{1, math.Sqrt2 / 2, 1}
+	pts, err = dxfSpline(2, []float64{0, 0, 0, 1, 1, 1}, []v2.Vec{{1, 0}, {1, 1}, {0, 1}}, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range pts {
+		if !EqualFloat64(v.Length(), 1, tolerance) {
+			t.Errorf("expected radius 1, got %f", v.Length())
+		}
+	}
+	if _, err := dxfSpline(2, []float64{0, 1}, p, nil); err == nil {
+		t.Error("expected error for bad knots")
+	}
+}
+
+func Test_dxfBulge(t *testing.T) {
+	// a bulge of 1 is a semicircle, counter-clockwise for positive bulges
+	pts := dxfBulge(v2.Vec{0, 0}, v2.Vec{2, 0}, 1)
+	c := v2.Vec{1, 0}
+	for _, v := range pts {
+		if !EqualFloat64(v.Sub(c).Length(), 1, tolerance) {
+			t.Errorf("expected radius 1, got %f", v.Sub(c).Length())
+		}
+	}
+	if mid := pts[len(pts)/2]; !mid.Equals(v2.Vec{1, -1}, tolerance) {
+		t.Errorf("expected {1 -1}, got %v", mid)
+	}
+	pts = dxfBulge(v2.Vec{0, 0}, v2.Vec{2, 0}, -1)
+	if mid := pts[len(pts)/2]; !mid.Equals(v2.Vec{1, 1}, tolerance) {
+		t.Errorf("expected {1 1}, got %v", mid)
+	}
+}
+
+func Test_DecodeDXF(t *testing.T) {
+	doc := dxfDoc(
+		// 30x20 plate from lines (in any order and direction)
+		dxfLine(0, 0, 30, 0),
+		dxfLine(30, 20, 0, 20),
+		dxfLine(0, 0, 0, 20),
+		dxfLine(30, 0, 30, 20),
+		// round hole
+		"0\nCIRCLE\n8\n0\n10\n5\n20\n10\n30\n0\n40\n2",
+		// slot with round ends
+		"0\nLWPOLYLINE\n8\n0\n90\n4\n70\n1\n10\n15\n20\n8\n42\n0\n10\n20\n20\n8\n42\n1\n10\n20\n20\n12\n42\n0\n10\n15\n20\n12\n42\n1",
+		// island in the slot
+		"0\nCIRCLE\n8\n0\n10\n17.5\n20\n10\n30\n0\n40\n0.5",
+		// separate disk made from two arcs
+		"0\nARC\n8\n0\n10\n50\n20\n10\n30\n0\n40\n5\n50\n0\n51\n180",
+		"0\nARC\n8\n0\n10\n50\n20\n10\n30\n0\n40\n5\n50\n180\n51\n0",
+		// ignored entities
+		"0\nTEXT\n8\n0\n10\n0\n20\n0\n40\n1\n1\nhello",
+	)
+	shapes, err := DecodeDXF(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shapes) != 3 {
+		t.Fatalf("expected 3 shapes, got %d", len(shapes))
+	}
+	s := Union2D(shapes...)
+	tests := []struct {
+		p v2.Vec
+		d float64
+	}{
+		{v2.Vec{1, 1}, -1},
+		{v2.Vec{-1, 10}, 1},
+		{v2.Vec{5, 10}, 2},
+		{v2.Vec{10, 10}, -3},
+		{v2.Vec{17.5, 11}, 0.5},
+		{v2.Vec{12, 10}, -1},
+		{v2.Vec{25, 10}, -3},
+		{v2.Vec{17.5, 10}, -0.5},
+		{v2.Vec{50, 10}, -5},
+		{v2.Vec{50, 17}, 2},
+	}
+	for _, test := range tests {
+		if d := s.Evaluate(test.p); !EqualFloat64(d, test.d, 0.01) {
+			t.Errorf("at %v expected %f, got %f", test.p, test.d, d)
+		}
+	}
+
+	bad := []string{
+		dxfDoc(dxfLine(0, 0, 1, 0), dxfLine(1, 0, 1, 1)),
+		dxfDoc("0\nCIRCLE\n10\n0\n20\n0\n40\n-1"),
+		dxfDoc("0\nLINE\n10\nx\n20\n0\n11\n1\n21\n1"),
+		dxfDoc("0\nTEXT\n1\nhello"),
+		"0\nSECTION\n2\nENTITIES\n0\nLINE\n10",
+	}
+	for i, doc := range bad {
+		if _, err := DecodeDXF(strings.NewReader(doc)); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,341 @@
+//-----------------------------------------------------------------------------
+/*
+
+Raster Images
+
+Convert images into SDFs.
+
+Image2D: The dark areas of an image (E.g. a logo or stencil) become an SDF2.
+The distance field is a signed Euclidean distance transform of the pixels.
+
+Heightmap3D: The brightness of the pixels is the height of a solid.
+(E.g. terrain, or with inverted brightness, a lithophane).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	_ "image/jpeg" // register the jpeg decoder
+	_ "image/png"  // register the png decoder
+	"math"
+	"os"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// LoadImage reads an image (png or jpeg) from a file.
+func LoadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// luminance returns the brightness (0..1) of a color.
+// Transparent colors are composited over white.
+func luminance(c color.Color) float64 {
+	r, g, b, a := c.RGBA()
+	// Rec. 601 luma of the alpha premultiplied color
+	y := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+	return y + 1 - float64(a)/0xffff
+}
+
+// imageLuminance returns the brightness of the image pixels.
+// Rows are flipped so y increases upwards.
+func imageLuminance(img image.Image) ([]float64, v2i.Vec) {
+	r := img.Bounds()
+	n := v2i.Vec{r.Dx(), r.Dy()}
+	lum := make([]float64, n.X*n.Y)
+	for y := 0; y < n.Y; y++ {
+		for x := 0; x < n.X; x++ {
+			lum[(n.Y-1-y)*n.X+x] = luminance(img.At(r.Min.X+x, r.Min.Y+y))
+		}
+	}
+	return lum, n
+}
+
+//-----------------------------------------------------------------------------
+// Euclidean Distance Transform
+// See: "Distance Transforms of Sampled Functions", Felzenszwalb and Huttenlocher, 2012.
+
+// edt1 does the 1d squared distance transform of f (in place).
+// v, z and d are scratch space of length n, n+1 and n.
+func edt1(f []float64, v []int, z, d []float64) {
+	n := len(f)
+	k := 0
+	v[0] = 0
+	z[0] = math.Inf(-1)
+	z[1] = math.Inf(1)
+	for q := 1; q < n; q++ {
+		if math.IsInf(f[q], 1) {
+			continue
+		}
+		for {
+			p := v[k]
+			if math.IsInf(f[p], 1) {
+				// replace an infinite parabola
+				v[k] = q
+				break
+			}
+			s := ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+			if s > z[k] {
+				k++
+				v[k] = q
+				z[k] = s
+				break
+			}
+			if k == 0 {
+				v[k] = q
+				break
+			}
+			k--
+		}
+		z[k+1] = math.Inf(1)
+	}
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		p := v[k]
+		d[q] = float64((q-p)*(q-p)) + f[p]
+	}
+	copy(f, d)
+}
+
+// edt returns the squared distance (in pixels) from each pixel to the nearest set pixel.
+func edt(set []bool, n v2i.Vec) []float64 {
+	f := make([]float64, len(set))
+	for i, s := range set {
+		if s {
+			f[i] = 0
+		} else {
+			f[i] = math.Inf(1)
+		}
+	}
+	m := maxInt(n.X, n.Y)
+	v := make([]int, m)
+	z := make([]float64, m+1)
+	d := make([]float64, m)
+	col := make([]float64, n.Y)
+	// columns
+	for x := 0; x < n.X; x++ {
+		for y := 0; y < n.Y; y++ {
+			col[y] = f[y*n.X+x]
+		}
+		edt1(col, v, z, d)
+		for y := 0; y < n.Y; y++ {
+			f[y*n.X+x] = col[y]
+		}
+	}
+	// rows
+	for y := 0; y < n.Y; y++ {
+		edt1(f[y*n.X:(y+1)*n.X], v, z, d)
+	}
+	return f
+}
+
+//-----------------------------------------------------------------------------
+
+// imagePad is the number of background pixels added about an image.
+const imagePad = 2
+
+// ImageSDF2 is an SDF2 made from the dark areas of an image.
+type ImageSDF2 struct {
+	grid *VoxelSDF2 // distances at the pixel centers
+	bb   Box2       // bounding box of the dark pixels
+}
+
+// Image2D returns an SDF2 for the pixels of an image with brightness (0..1) below threshold.
+// The image is scaled to width (the height keeps the aspect ratio) and centered on the origin.
+// Distances are accurate to about a pixel within the image and approximate beyond it.
+func Image2D(img image.Image, width, threshold float64) (SDF2, error) {
+	if width <= 0 {
+		return nil, ErrMsg("width <= 0")
+	}
+	lum, n := imageLuminance(img)
+	if n.X == 0 || n.Y == 0 {
+		return nil, ErrMsg("empty image")
+	}
+	pixel := width / float64(n.X)
+
+	// add a border of background pixels so the shapes are closed
+	np := n.AddScalar(2 * imagePad)
+	inside := make([]bool, np.X*np.Y)
+	outside := make([]bool, np.X*np.Y)
+	bb := Box2{v2.Vec{math.Inf(1), math.Inf(1)}, v2.Vec{math.Inf(-1), math.Inf(-1)}}
+	for y := 0; y < np.Y; y++ {
+		for x := 0; x < np.X; x++ {
+			i := y*np.X + x
+			px, py := x-imagePad, y-imagePad
+			if px >= 0 && px < n.X && py >= 0 && py < n.Y && lum[py*n.X+px] < threshold {
+				inside[i] = true
+				bb = bb.Include(v2.Vec{float64(px), float64(py)})
+			} else {
+				outside[i] = true
+			}
+		}
+	}
+	if math.IsInf(bb.Min.X, 1) {
+		return nil, ErrMsg("no pixels below the threshold")
+	}
+
+	// The boundary is half way between the inside and outside pixel centers.
+	dOut := edt(inside, np)
+	dIn := edt(outside, np)
+	values := make([]float64, len(inside))
+	for y := 0; y < np.Y; y++ {
+		for x := 0; x < np.X; x++ {
+			i := y*np.X + x
+			d := math.Sqrt(dOut[i]) - 0.5
+			if inside[i] {
+				d = 0.5 - math.Sqrt(dIn[i])
+			}
+			// the voxel grid is indexed x major
+			values[x*np.Y+y] = d * pixel
+		}
+	}
+
+	// pixel centers relative to the image center
+	center := v2.Vec{float64(n.X), float64(n.Y)}.MulScalar(0.5 * pixel)
+	toXY := func(x, y float64) v2.Vec {
+		return v2.Vec{(x + 0.5) * pixel, (y + 0.5) * pixel}.Sub(center)
+	}
+	grid := &VoxelSDF2{
+		values:    values,
+		interp:    VoxelLinear,
+		bb:        Box2{toXY(-imagePad, -imagePad), toXY(float64(n.X+imagePad-1), float64(n.Y+imagePad-1))},
+		numVoxels: np.SubScalar(1),
+	}
+	return &ImageSDF2{
+		grid: grid,
+		// the dark pixels extend half a pixel beyond their centers
+		bb: Box2{toXY(bb.Min.X, bb.Min.Y), toXY(bb.Max.X, bb.Max.Y)}.Enlarge(v2.Vec{pixel, pixel}),
+	}, nil
+}
+
+// LoadImage2D returns an SDF2 for the dark areas of an image file. See Image2D.
+func LoadImage2D(path string, width, threshold float64) (SDF2, error) {
+	img, err := LoadImage(path)
+	if err != nil {
+		return nil, err
+	}
+	return Image2D(img, width, threshold)
+}
+
+// Evaluate returns the minimum distance to an image SDF2.
+func (s *ImageSDF2) Evaluate(p v2.Vec) float64 {
+	return s.grid.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of an image SDF2.
+func (s *ImageSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// HeightmapSDF3 is an SDF3 with the height of its top surface set by the brightness of an image.
+type HeightmapSDF3 struct {
+	height *VoxelSDF2 // heights at the pixel centers
+	slope  float64    // distance correction for the maximum slope
+	base   Box2       // footprint of the heightmap
+	bb     Box3       // bounding box
+}
+
+// Heightmap3D returns an SDF3 with a footprint of size.X by size.Y centered on the origin.
+// The top surface is base at black and size.Z at white (or the reverse if invert is set,
+// E.g. for a lithophane). The bottom surface is z = 0.
+func Heightmap3D(img image.Image, size v3.Vec, base float64, invert bool) (SDF3, error) {
+	if size.X <= 0 || size.Y <= 0 || size.Z <= 0 {
+		return nil, ErrMsg("size <= 0")
+	}
+	if base < 0 || base > size.Z {
+		return nil, ErrMsg("base must be 0..size.Z")
+	}
+	lum, n := imageLuminance(img)
+	if n.X < 2 || n.Y < 2 {
+		return nil, ErrMsg("image is too small")
+	}
+	if invert {
+		for i := range lum {
+			lum[i] = 1 - lum[i]
+		}
+	}
+
+	pixel := v2.Vec{size.X / float64(n.X), size.Y / float64(n.Y)}
+	values := make([]float64, len(lum))
+	for y := 0; y < n.Y; y++ {
+		for x := 0; x < n.X; x++ {
+			// the voxel grid is indexed x major
+			values[x*n.Y+y] = base + lum[y*n.X+x]*(size.Z-base)
+		}
+	}
+
+	// The maximum slope between pixels bounds the gradient of the height.
+	slope := 0.0
+	for x := 0; x < n.X; x++ {
+		for y := 0; y < n.Y; y++ {
+			h := values[x*n.Y+y]
+			if x+1 < n.X {
+				slope = math.Max(slope, math.Abs(values[(x+1)*n.Y+y]-h)/pixel.X)
+			}
+			if y+1 < n.Y {
+				slope = math.Max(slope, math.Abs(values[x*n.Y+y+1]-h)/pixel.Y)
+			}
+		}
+	}
+
+	footprint := Box2{v2.Vec{-size.X, -size.Y}.MulScalar(0.5), v2.Vec{size.X, size.Y}.MulScalar(0.5)}
+	return &HeightmapSDF3{
+		height: &VoxelSDF2{
+			values:    values,
+			interp:    VoxelLinear,
+			bb:        Box2{footprint.Min.Add(pixel.MulScalar(0.5)), footprint.Max.Sub(pixel.MulScalar(0.5))},
+			numVoxels: n.SubScalar(1),
+		},
+		// the bilinear height has a gradient of up to sqrt(2) * slope
+		slope: math.Sqrt(1 + 2*slope*slope),
+		base:  footprint,
+		bb:    Box3{v3.Vec{footprint.Min.X, footprint.Min.Y, 0}, v3.Vec{footprint.Max.X, footprint.Max.Y, size.Z}},
+	}, nil
+}
+
+// LoadHeightmap3D returns a heightmap SDF3 for an image file. See Heightmap3D.
+func LoadHeightmap3D(path string, size v3.Vec, base float64, invert bool) (SDF3, error) {
+	img, err := LoadImage(path)
+	if err != nil {
+		return nil, err
+	}
+	return Heightmap3D(img, size, base, invert)
+}
+
+// Evaluate returns the minimum distance to a heightmap SDF3.
+func (s *HeightmapSDF3) Evaluate(p v3.Vec) float64 {
+	xy := v2.Vec{p.X, p.Y}
+	// distance to the sides of the footprint
+	q := xy.Abs().Sub(s.base.Max)
+	dSide := q.Max(v2.Vec{0, 0}).Length() + math.Min(q.MaxComponent(), 0)
+	// the height clamped to the footprint (the voxel grid is smaller)
+	h := s.height.Evaluate(xy.Clamp(s.height.bb.Min, s.height.bb.Max))
+	dTop := (p.Z - h) / s.slope
+	return math.Max(dSide, math.Max(-p.Z, dTop))
+}
+
+// BoundingBox returns the bounding box of a heightmap SDF3.
+func (s *HeightmapSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,126 @@
+//-----------------------------------------------------------------------------
+/*
+
+Raster Image Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_EDT(t *testing.T) {
+	n := v2i.Vec{23, 17}
+	r := rand.New(rand.NewSource(1))
+	set := make([]bool, n.X*n.Y)
+	for i := range set {
+		set[i] = r.Intn(20) == 0
+	}
+	d := edt(set, n)
+	for i := range set {
+		x0, y0 := i%n.X, i/n.X
+		d2 := math.Inf(1)
+		for j, s := range set {
+			if s {
+				dx, dy := float64(j%n.X-x0), float64(j/n.X-y0)
+				d2 = math.Min(d2, dx*dx+dy*dy)
+			}
+		}
+		if d[i] != d2 {
+			t.Errorf("pixel %d,%d: expected %f, got %f", x0, y0, d2, d[i])
+		}
+	}
+}
+
+// diskImage returns a white image with a black disk.
+func diskImage(n int, center v2.Vec, radius float64) image.Image {
+	img := image.NewGray(image.Rect(0, 0, n, n))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			p := v2.Vec{float64(x) + 0.5, float64(y) + 0.5}
+			if p.Sub(center).Length() > radius {
+				img.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	return img
+}
+
+func Test_Image2D(t *testing.T) {
+	// disk at the top left of the image
+	img := diskImage(100, v2.Vec{30, 30}, 20)
+	s, err := Image2D(img, 50, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 pixels per unit, y flipped
+	center := v2.Vec{-10, 10}
+	if !s.BoundingBox().Equals(NewBox2(center, v2.Vec{20, 20}), 0.5) {
+		t.Errorf("bad bounding box %v", s.BoundingBox())
+	}
+	b := NewBox2(v2.Vec{0, 0}, v2.Vec{50, 50})
+	for i := 0; i < 1000; i++ {
+		p := b.Random()
+		want := p.Sub(center).Length() - 10
+		if got := s.Evaluate(p); math.Abs(got-want) > 0.5 {
+			t.Errorf("%v: expected %f, got %f", p, want, got)
+		}
+	}
+	if _, err := Image2D(img, 50, 0); err == nil {
+		t.Error("expected error for no pixels below the threshold")
+	}
+}
+
+func Test_Heightmap3D(t *testing.T) {
+	// brightness increases with x
+	img := image.NewGray(image.Rect(0, 0, 11, 5))
+	for x := 0; x < 11; x++ {
+		for y := 0; y < 5; y++ {
+			img.SetGray(x, y, color.Gray{uint8(x * 255 / 10)})
+		}
+	}
+	s, err := Heightmap3D(img, v3.Vec{22, 10, 5}, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.BoundingBox().Equals(Box3{v3.Vec{-11, -5, 0}, v3.Vec{11, 5, 5}}, tolerance) {
+		t.Errorf("bad bounding box %v", s.BoundingBox())
+	}
+	tests := []struct {
+		p      v3.Vec
+		inside bool
+	}{
+		{v3.Vec{-10, 0, 0.5}, true},
+		{v3.Vec{-10, 0, 1.5}, false},
+		{v3.Vec{10, 0, 4.5}, true},
+		{v3.Vec{0, 0, 2.8}, true},
+		{v3.Vec{0, 0, 3.2}, false},
+		{v3.Vec{0, 0, -0.5}, false},
+		{v3.Vec{0, 6, 1}, false},
+	}
+	for _, test := range tests {
+		if d := s.Evaluate(test.p); (d < 0) != test.inside {
+			t.Errorf("%v: expected inside %v, got %f", test.p, test.inside, d)
+		}
+	}
+	// lithophane: dark is thick
+	s, _ = Heightmap3D(img, v3.Vec{22, 10, 5}, 1, true)
+	if d := s.Evaluate(v3.Vec{-10, 0, 4.5}); d >= 0 {
+		t.Errorf("expected inside, got %f", d)
+	}
+}
+
+//-----------------------------------------------------------------------------
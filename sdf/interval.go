@@ -0,0 +1,122 @@
+//-----------------------------------------------------------------------------
+/*
+
+Interval Evaluation
+
+Some SDFs can bound the values of their distance field over a box.
+A renderer can use these bounds to skip regions that are entirely inside
+or outside of the object without sampling them.
+
+The bounds are conservative: every distance evaluated within the box lies
+within the returned interval, but the interval may be wider than the true
+range of the field.
+
+SDFs that don't implement interval evaluation fall back to a single
+evaluation at the box center. This relies on the distance field having
+a gradient magnitude <= 1, which is the case for the SDFs in this package.
+
+Blending functions (MinFunc/MaxFunc) are assumed to be monotonic in both
+arguments. This is true of the blending functions in this package.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// IntervalSDF3 is an SDF3 that can bound its distance field over a box.
+type IntervalSDF3 interface {
+	SDF3
+	EvaluateInterval(b Box3) Interval
+}
+
+// IntervalSDF2 is an SDF2 that can bound its distance field over a box.
+type IntervalSDF2 interface {
+	SDF2
+	EvaluateInterval(b Box2) Interval
+}
+
+// EvaluateInterval3 returns bounds on the distance field of an SDF3 over a box.
+func EvaluateInterval3(s SDF3, b Box3) Interval {
+	if x, ok := s.(IntervalSDF3); ok {
+		return x.EvaluateInterval(b)
+	}
+	d := s.Evaluate(b.Center())
+	h := 0.5 * b.Size().Length()
+	return Interval{d - h, d + h}
+}
+
+// EvaluateInterval2 returns bounds on the distance field of an SDF2 over a box.
+func EvaluateInterval2(s SDF2, b Box2) Interval {
+	if x, ok := s.(IntervalSDF2); ok {
+		return x.EvaluateInterval(b)
+	}
+	d := s.Evaluate(b.Center())
+	h := 0.5 * b.Size().Length()
+	return Interval{d - h, d + h}
+}
+
+// HasSignChange returns true if the distances bounded by an interval may change sign.
+// Renderers take distances < 0 as inside, so there is no sign change (and no surface)
+// if all distances are >= 0 or all distances are < 0.
+func (a Interval) HasSignChange() bool {
+	return a[0] < 0 && a[1] >= 0
+}
+
+//-----------------------------------------------------------------------------
+// Interval Arithmetic
+
+// absInterval returns the range of |x| for x within the interval.
+func absInterval(a Interval) Interval {
+	if a[0] >= 0 {
+		return a
+	}
+	if a[1] <= 0 {
+		return Interval{-a[1], -a[0]}
+	}
+	return Interval{0, math.Max(-a[0], a[1])}
+}
+
+// boxInterval returns the range of length(max(q, 0)) + min(max(q), 0),
+// the distance to a box in terms of the (per axis) distances to the box faces.
+// Both terms are monotonic in each component of q.
+func boxInterval(q ...Interval) Interval {
+	var out0, out1 float64
+	in0, in1 := math.Inf(-1), math.Inf(-1)
+	for _, x := range q {
+		x0 := math.Max(x[0], 0)
+		x1 := math.Max(x[1], 0)
+		out0 += x0 * x0
+		out1 += x1 * x1
+		in0 = math.Max(in0, x[0])
+		in1 = math.Max(in1, x[1])
+	}
+	return Interval{
+		math.Sqrt(out0) + math.Min(in0, 0),
+		math.Sqrt(out1) + math.Min(in1, 0),
+	}
+}
+
+// originDistance3 returns the range of distances from the origin to the points of a box.
+func originDistance3(b Box3) Interval {
+	near := v3.Vec{}.Clamp(b.Min, b.Max)
+	far := b.Min.Abs().Max(b.Max.Abs())
+	return Interval{near.Length(), far.Length()}
+}
+
+// originDistance2 returns the range of distances from the origin to the points of a box.
+func originDistance2(b Box2) Interval {
+	near := v2.Vec{}.Clamp(b.Min, b.Max)
+	far := b.Min.Abs().Max(b.Max.Abs())
+	return Interval{near.Length(), far.Length()}
+}
+
+//-----------------------------------------------------------------------------
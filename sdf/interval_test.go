@@ -0,0 +1,149 @@
+//-----------------------------------------------------------------------------
+
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// checkInterval3 checks that sampled distances lie within the interval bounds.
+func checkInterval3(t *testing.T, name string, s SDF3) {
+	if _, ok := s.(IntervalSDF3); !ok {
+		t.Errorf("%s: no interval evaluation", name)
+		return
+	}
+	bb := s.BoundingBox().ScaleAboutCenter(1.5)
+	for i := 0; i < 200; i++ {
+		size := bb.Size().MulScalar(randomRange(0, 0.5))
+		b := NewBox3(bb.Random(), size)
+		d := EvaluateInterval3(s, b)
+		for _, p := range append(b.Vertices(), b.RandomSet(50)...) {
+			x := s.Evaluate(p)
+			if x < d[0]-tolerance || x > d[1]+tolerance {
+				t.Errorf("%s: %v %f not within %v", name, p, x, d)
+				return
+			}
+		}
+	}
+}
+
+// checkInterval2 checks that sampled distances lie within the interval bounds.
+func checkInterval2(t *testing.T, name string, s SDF2) {
+	if _, ok := s.(IntervalSDF2); !ok {
+		t.Errorf("%s: no interval evaluation", name)
+		return
+	}
+	bb := s.BoundingBox().ScaleAboutCenter(1.5)
+	for i := 0; i < 200; i++ {
+		size := bb.Size().MulScalar(randomRange(0, 0.5))
+		b := NewBox2(bb.Random(), size)
+		d := EvaluateInterval2(s, b)
+		for _, p := range append(b.Vertices(), b.RandomSet(50)...) {
+			x := s.Evaluate(p)
+			if x < d[0]-tolerance || x > d[1]+tolerance {
+				t.Errorf("%s: %v %f not within %v", name, p, x, d)
+				return
+			}
+		}
+	}
+}
+
+func Test_EvaluateInterval3(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 6, 4}, 0.5)
+	sphere, _ := Sphere3D(2)
+	cylinder, _ := Cylinder3D(6, 1, 0.2)
+	var holes []SDF3
+	for i := 0; i < 4; i++ {
+		m := Translate3d(v3.Vec{float64(2*i) - 3, 0, 0}).Mul(RotateX(0.3 * float64(i)))
+		holes = append(holes, Transform3D(cylinder, m))
+	}
+	union := Union3D(holes...)
+	smooth := Union3D(holes...)
+	smooth.(*UnionSDF3).SetMin(PolyMin(0.5))
+	tests := []struct {
+		name string
+		s    SDF3
+	}{
+		{"box", box},
+		{"sphere", sphere},
+		{"cylinder", cylinder},
+		{"transform", Transform3D(box, Translate3d(v3.Vec{1, 2, 3}).Mul(RotateY(0.7)))},
+		{"scale", ScaleUniform3D(sphere, 2.5)},
+		{"union", union},
+		{"smooth union", smooth},
+		{"difference", Difference3D(box, union)},
+		{"intersection", Intersect3D(box, Transform3D(sphere, Translate3d(v3.Vec{4, 2, 1})))},
+	}
+	for _, x := range tests {
+		checkInterval3(t, x.name, x.s)
+	}
+}
+
+func Test_EvaluateInterval2(t *testing.T) {
+	box := Box2D(v2.Vec{8, 4}, 0.5)
+	circle, _ := Circle2D(1)
+	var holes []SDF2
+	for i := 0; i < 4; i++ {
+		holes = append(holes, Transform2D(circle, Translate2d(v2.Vec{float64(2*i) - 3, 0})))
+	}
+	union := Union2D(holes...)
+	tests := []struct {
+		name string
+		s    SDF2
+	}{
+		{"box", box},
+		{"circle", circle},
+		{"transform", Transform2D(box, Rotate2d(0.7))},
+		{"union", union},
+		{"difference", Difference2D(box, union)},
+		{"intersection", Intersect2D(box, Transform2D(circle, Translate2d(v2.Vec{4, 2})))},
+	}
+	for _, x := range tests {
+		checkInterval2(t, x.name, x.s)
+	}
+}
+
+func Test_EvaluateInterval_Fallback(t *testing.T) {
+	// sdfs without interval evaluation use the distance at the box center
+	s, _ := Cone3D(4, 2, 1, 0)
+	b := NewBox3(v3.Vec{5, 0, 0}, v3.Vec{2, 2, 2})
+	d := EvaluateInterval3(s, b)
+	x := s.Evaluate(b.Center())
+	h := b.Size().Length() / 2
+	if !d.Equals(Interval{x - h, x + h}, tolerance) {
+		t.Errorf("%v (expected) %v (actual)", Interval{x - h, x + h}, d)
+	}
+	// a union with a region outside every bounding box is positive
+	u := Union3D(s, Transform3D(s, Translate3d(v3.Vec{0, 0, 10})))
+	d = EvaluateInterval3(u, NewBox3(v3.Vec{20, 0, 0}, v3.Vec{1, 1, 1}))
+	if d[0] < 0 {
+		t.Errorf("expected a positive interval, got %v", d)
+	}
+}
+
+func Test_Interval_HasSignChange(t *testing.T) {
+	tests := []struct {
+		a      Interval
+		result bool
+	}{
+		{Interval{1, 2}, false},
+		{Interval{0, 2}, false},
+		{Interval{-2, -1}, false},
+		{Interval{-2, 0}, true}, // 0 is outside
+		{Interval{-1, 1}, true},
+	}
+	for _, x := range tests {
+		if x.a.HasSignChange() != x.result {
+			t.Errorf("%v: expected %v", x.a, x.result)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
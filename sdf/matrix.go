@@ -256,7 +256,8 @@ func RotateToVector(a, b v3.Vec) M44 {
 	// general case
 	// See:	https://math.stackexchange.com/questions/180418/calculate-rotation-matrix-to-align-vector-a-to-vector-b-in-3d
 	v := a.Cross(b)
-	k := 1 / (1 + a.Dot(b))
+	// k = 1 / (1 + a.b), written to avoid cancellation for nearly opposite vectors
+	k := (1 - a.Dot(b)) / v.Length2()
 	vx := M33{0, -v.Z, v.Y, v.Z, 0, -v.X, -v.Y, v.X, 0}
 	r := Identity2d().Add(vx).Add(vx.Mul(vx).MulScalar(k))
 	return M44{
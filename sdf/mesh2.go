@@ -259,17 +259,46 @@ func (node *qtNode) winding(p v2.Vec, wn int) int {
 	return wn
 }
 
+//-----------------------------------------------------------------------------
+
+// FillRule decides which areas enclosed by a set of line segments are inside.
+type FillRule int
+
+const (
+	// FillNonZero is inside for a non-zero winding number.
+	FillNonZero FillRule = iota
+	// FillEvenOdd is inside for an odd winding number (an odd number of crossings).
+	FillEvenOdd
+)
+
+// inside returns true if a winding number is inside for the fill rule.
+func (f FillRule) inside(wn int) bool {
+	if f == FillEvenOdd {
+		return wn%2 != 0
+	}
+	return wn != 0
+}
+
 //-----------------------------------------------------------------------------
 // Mesh2D. 2D mesh evaluation with quadtree speedup.
 
 // MeshSDF2 is SDF2 made from a set of line segments.
 type MeshSDF2 struct {
-	qt *qtNode // quadtree root
-	bb Box2    // bounding box
+	qt   *qtNode  // quadtree root
+	fill FillRule // fill rule for the winding number
+	bb   Box2     // bounding box
 }
 
 // Mesh2D returns an SDF2 made from a set of line segments.
 func Mesh2D(mesh []*Line2) (SDF2, error) {
+	return Mesh2DFill(mesh, FillNonZero)
+}
+
+// Mesh2DFill returns an SDF2 made from a set of line segments with a fill rule.
+func Mesh2DFill(mesh []*Line2, fill FillRule) (SDF2, error) {
+	if fill != FillNonZero && fill != FillEvenOdd {
+		return nil, ErrMsg("unknown fill rule")
+	}
 	n := len(mesh)
 	if n == 0 {
 		return nil, ErrMsg("no 2d line segments")
@@ -290,8 +319,9 @@ func Mesh2D(mesh []*Line2) (SDF2, error) {
 	qt := qtBuild(0, qtBox, mesh)
 
 	return &MeshSDF2{
-		qt: qt,
-		bb: bb,
+		qt:   qt,
+		fill: fill,
+		bb:   bb,
 	}, nil
 }
 
@@ -301,7 +331,7 @@ func (s *MeshSDF2) Evaluate(p v2.Vec) float64 {
 	wn := s.qt.winding(p, 0)
 	// normalise d*d to d
 	d := math.Sqrt(d2)
-	if wn != 0 {
+	if s.fill.inside(wn) {
 		// p is inside the polygon
 		return -d
 	}
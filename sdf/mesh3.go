@@ -9,6 +9,8 @@
 package sdf
 
 import (
+	"math"
+
 	v2 "github.com/deadsy/sdfx/vec/v2"
 	v3 "github.com/deadsy/sdfx/vec/v3"
 )
@@ -135,37 +137,147 @@ func (a *triangleInfo) minDistance2(p v3.Vec) float64 {
 }
 
 //-----------------------------------------------------------------------------
-// Mesh3D. 3D mesh evaluation with octree speedup.
 
-// MeshSDF3 is an SDF3 made from a set of 3d triangles.
-type MeshSDF3 struct {
-	mesh []*Triangle3
-	bb   Box3 // bounding box
+// MeshSign selects how a mesh SDF decides if a point is inside or outside.
+type MeshSign int
+
+const (
+	// MeshSignNormal uses the normal of the closest triangle.
+	// It needs a closed, non-intersecting, consistently oriented mesh.
+	MeshSignNormal MeshSign = iota
+	// MeshSignWinding uses the generalized winding number of the mesh.
+	// It tolerates holes, self-intersections and flipped triangles.
+	MeshSignWinding
+)
+
+// meshTie is the relative tolerance for distances to be considered equal.
+const meshTie = 1e-9
+
+// meshClosest tracks the closest triangle to a point.
+type meshClosest struct {
+	p     v3.Vec
+	d2    float64 // minimum distance squared
+	plane float64 // signed distance to the plane of the closest triangle
 }
 
-// Mesh3D returns an SDF3 made from a set of triangles.
-func Mesh3D(mesh []*Triangle3) (SDF3, error) {
-	n := len(mesh)
-	if n == 0 {
+func newMeshClosest(p v3.Vec) *meshClosest {
+	return &meshClosest{p: p, d2: math.Inf(1)}
+}
+
+// add considers a triangle and returns its distance to the point.
+// Points closest to an edge or vertex are equally close to several triangles.
+// The sign is taken from the triangle with the largest distance to its plane,
+// which is the triangle facing the point most directly.
+func (c *meshClosest) add(a *triangleInfo) float64 {
+	d2 := a.minDistance2(c.p)
+	if d2 > c.d2*(1+meshTie) {
+		return math.Sqrt(d2)
+	}
+	// z is away from the triangle normal in the xy plane mapping
+	plane := -a.m.MulPosition(c.p).Z
+	if d2 < c.d2*(1-meshTie) || math.Abs(plane) > math.Abs(c.plane) {
+		c.plane = plane
+	}
+	c.d2 = math.Min(c.d2, d2)
+	return math.Sqrt(d2)
+}
+
+// distance returns the signed distance to the closest triangle.
+func (c *meshClosest) distance() float64 {
+	d := math.Sqrt(c.d2)
+	if c.plane < 0 {
+		return -d
+	}
+	return d
+}
+
+// meshTriangles returns the triangles of a mesh without the degenerate (zero area) triangles.
+// They have no distance information, and their triangle information would be NaN.
+func meshTriangles(mesh []*Triangle3) ([]*Triangle3, error) {
+	out := make([]*Triangle3, 0, len(mesh))
+	for _, t := range mesh {
+		if t.Degenerate(0) || t[1].Sub(t[0]).Cross(t[2].Sub(t[0])).Length() == 0 {
+			continue
+		}
+		out = append(out, t)
+	}
+	if len(out) == 0 {
 		return nil, ErrMsg("no triangles")
 	}
+	return out, nil
+}
 
-	// work out the bounding box
+// meshBoundingBox returns the bounding box of a set of triangles.
+func meshBoundingBox(mesh []*Triangle3) Box3 {
 	bb := mesh[0].BoundingBox()
 	for _, t := range mesh {
 		bb = bb.Extend(t.BoundingBox())
 	}
+	return bb
+}
 
-	return &MeshSDF3{
-		mesh: mesh,
-		bb:   bb,
-	}, nil
+//-----------------------------------------------------------------------------
+// Mesh3D. 3D mesh evaluation with bvh speedup.
+
+// MeshSDF3 is an SDF3 made from a set of 3d triangles.
+type MeshSDF3 struct {
+	info    []*triangleInfo
+	t       *bvh3     // bounding volume hierarchy of the triangles
+	winding *Winding3 // winding number for the sign (or nil)
+	bb      Box3      // bounding box
 }
 
-// Evaluate returns the minimum distance for a 2d mesh.
+// Mesh3D returns an SDF3 made from a set of triangles.
+// The sign is taken from the closest triangle, see Mesh3DSign.
+func Mesh3D(mesh []*Triangle3) (SDF3, error) {
+	return Mesh3DSign(mesh, MeshSignNormal)
+}
+
+// Mesh3DSign returns an SDF3 made from a set of triangles.
+// Use MeshSignWinding for meshes that are not cleanly closed.
+func Mesh3DSign(mesh []*Triangle3, sign MeshSign) (SDF3, error) {
+	mesh, err := meshTriangles(mesh)
+	if err != nil {
+		return nil, err
+	}
+	bb := make([]Box3, len(mesh))
+	for i, t := range mesh {
+		bb[i] = t.BoundingBox()
+	}
+	s := &MeshSDF3{
+		info: convertTriangles(mesh),
+		t:    newBVH3(bb),
+		bb:   meshBoundingBox(mesh),
+	}
+	switch sign {
+	case MeshSignNormal:
+	case MeshSignWinding:
+		w, err := NewWinding3(mesh)
+		if err != nil {
+			return nil, err
+		}
+		s.winding = w
+	default:
+		return nil, ErrMsg("unknown mesh sign")
+	}
+	return s, nil
+}
+
+// Evaluate returns the minimum distance for a 3d mesh.
 func (s *MeshSDF3) Evaluate(p v3.Vec) float64 {
-	// TODO
-	return 0
+	c := newMeshClosest(p)
+	s.t.minimum(p, func(i int) float64 {
+		// Don't prune triangles at the same distance, they may give a better sign.
+		return c.add(s.info[i]) * (1 + meshTie)
+	})
+	if s.winding == nil {
+		return c.distance()
+	}
+	d := math.Sqrt(c.d2)
+	if s.winding.Inside(p) {
+		return -d
+	}
+	return d
 }
 
 // BoundingBox returns the bounding box of a 3d mesh.
@@ -178,33 +290,29 @@ func (s *MeshSDF3) BoundingBox() Box3 {
 
 // MeshSDF3Slow is an SDF3 made from a set of 3d triangles.
 type MeshSDF3Slow struct {
-	mesh []*Triangle3
+	info []*triangleInfo
 	bb   Box3 // bounding box
 }
 
 // Mesh3DSlow returns an SDF3 made from a set of triangles.
 func Mesh3DSlow(mesh []*Triangle3) (SDF3, error) {
-	n := len(mesh)
-	if n == 0 {
-		return nil, ErrMsg("no triangles")
-	}
-
-	// work out the bounding box
-	bb := mesh[0].BoundingBox()
-	for _, t := range mesh {
-		bb = bb.Extend(t.BoundingBox())
+	mesh, err := meshTriangles(mesh)
+	if err != nil {
+		return nil, err
 	}
-
 	return &MeshSDF3Slow{
-		mesh: mesh,
-		bb:   bb,
+		info: convertTriangles(mesh),
+		bb:   meshBoundingBox(mesh),
 	}, nil
 }
 
-// Evaluate returns the minimum distance for a 2d mesh.
+// Evaluate returns the minimum distance for a 3d mesh.
 func (s *MeshSDF3Slow) Evaluate(p v3.Vec) float64 {
-	// TODO
-	return 0
+	c := newMeshClosest(p)
+	for _, a := range s.info {
+		c.add(a)
+	}
+	return c.distance()
 }
 
 // BoundingBox returns the bounding box of a 3d mesh.
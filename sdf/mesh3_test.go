@@ -9,6 +9,7 @@ Mesh 3D Testing and Benchmarking
 package sdf
 
 import (
+	"math"
 	"testing"
 
 	v3 "github.com/deadsy/sdfx/vec/v3"
@@ -77,3 +78,111 @@ func Test_Mesh3_minDistance2(t *testing.T) {
 }
 
 //-----------------------------------------------------------------------------
+
+func Test_Mesh3D(t *testing.T) {
+	mesh := torusTriangles(3, 1, 48, 24)
+	s0, err := Mesh3D(mesh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1, err := Mesh3DSlow(mesh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := s0.BoundingBox().ScaleAboutCenter(1.5)
+	for i := 0; i < 5000; i++ {
+		p := b.Random()
+		d0 := s0.Evaluate(p)
+		d1 := s1.Evaluate(p)
+		if !EqualFloat64(d0, d1, tolerance) {
+			t.Errorf("%v: expected %f, got %f", p, d1, d0)
+		}
+		// the mesh approximates the torus
+		d := math.Hypot(math.Hypot(p.X, p.Y)-3, p.Z) - 1
+		if math.Abs(d0-d) > 0.05 {
+			t.Errorf("%v: torus distance %f, mesh distance %f", p, d, d0)
+		}
+	}
+	// points on the edges and vertices of the mesh
+	for _, x := range mesh {
+		for _, p := range []v3.Vec{x[0], x[0].Add(x[1]).MulScalar(0.5)} {
+			if d := s0.Evaluate(p); !EqualFloat64(d, 0, tolerance) {
+				t.Errorf("%v: expected 0, got %f", p, d)
+			}
+		}
+	}
+}
+
+func Test_Mesh3D_Sign(t *testing.T) {
+	// closest to the edges and vertices of a cube
+	s, _ := Mesh3D(cubeTriangles())
+	tests := []struct {
+		p v3.Vec
+		d float64
+	}{
+		{v3.Vec{0.5, -1, -1}, math.Sqrt2},
+		{v3.Vec{0.5, 0.01, 0.02}, -0.01},
+		{v3.Vec{2, 2, 2}, math.Sqrt(3)},
+		{v3.Vec{0.98, 0.99, 0.97}, -0.01},
+		{v3.Vec{0.5, 0.5, 0.5}, -0.5},
+	}
+	for i, test := range tests {
+		if d := s.Evaluate(test.p); !EqualFloat64(d, test.d, tolerance) {
+			t.Errorf("test %d: expected %f, got %f", i, test.d, d)
+		}
+	}
+}
+
+func Test_Mesh3D_Degenerate(t *testing.T) {
+	degenerate := []*Triangle3{
+		{{0.5, 0, 0}, {0.5, 0, 0}, {0.5, 1, 0}}, // repeated vertex
+		{{0, 0, 0}, {0.5, 0, 0}, {1, 0, 0}},     // collinear vertices
+		{{0.2, 0, 0}, {0.2, 0, 0}, {0.2, 0, 0}}, // a point
+	}
+	for i, x := range degenerate {
+		mesh := append(cubeTriangles(), x)
+		s0, _ := Mesh3D(mesh)
+		s1, _ := Mesh3DSign(mesh, MeshSignWinding)
+		s2, _ := Mesh3DSlow(mesh)
+		for _, s := range []SDF3{s0, s1, s2} {
+			if d := s.Evaluate(v3.Vec{0.1, 0.001, 0.001}); !EqualFloat64(d, -0.001, tolerance) {
+				t.Errorf("test %d: expected -0.001, got %f", i, d)
+			}
+		}
+	}
+	if _, err := Mesh3D(degenerate); err == nil {
+		t.Error("expected an error for only degenerate triangles")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Benchmark_Mesh3D(b *testing.B) {
+	mesh := torusTriangles(3, 1, 128, 64)
+	s0, err := Mesh3D(mesh)
+	if err != nil {
+		b.Fatalf("error: %s", err)
+	}
+	bb := s0.BoundingBox()
+	b.Run("Mesh3D", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = s0.Evaluate(bb.Random())
+		}
+	})
+}
+
+func Benchmark_Mesh3DSlow(b *testing.B) {
+	mesh := torusTriangles(3, 1, 128, 64)
+	s0, err := Mesh3DSlow(mesh)
+	if err != nil {
+		b.Fatalf("error: %s", err)
+	}
+	bb := s0.BoundingBox()
+	b.Run("Mesh3DSlow", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = s0.Evaluate(bb.Random())
+		}
+	})
+}
+
+//-----------------------------------------------------------------------------
@@ -341,6 +341,7 @@ type ScrewSDF3 struct {
 	length float64 // total length of screw
 	taper  float64 // thread taper angle
 	starts int     // number of thread starts
+	rMin   float64 // minimum radius for the gradient bound
 	bb     Box3    // bounding box
 }
 
@@ -373,6 +374,10 @@ func Screw3D(
 	s.length = length / 2
 	s.taper = taper
 	s.lead = -pitch * float64(starts)
+	// Near the z-axis the helix is steep and the gradient bound blows up.
+	// Points this close to the axis are deep within the screw core, so we
+	// limit the gradient bound to that at the radius where the helix is at 45 degrees.
+	s.rMin = math.Abs(s.lead) / Tau
 	// Work out the bounding box.
 	// The max-y axis of the sdf2 bounding box is the radius of the thread.
 	bb := s.thread.BoundingBox()
@@ -383,7 +388,25 @@ func Screw3D(
 	return &s, nil
 }
 
+// gradient returns the gradient bound for the helical mapping at radius r.
+func (s *ScrewSDF3) gradient(r, z float64) float64 {
+	// Map to profile coordinates x = z + lead * theta / Tau, y = r + z * t.
+	// The Jacobian rows are (radial, tangential, z) = (0, k, 1) and (1, 0, t)
+	// where k = lead / (Tau * r). The gradient bound is the largest singular value.
+	k := s.lead / (Tau * math.Max(r, s.rMin))
+	t := 0.0
+	if s.taper != 0 {
+		t = math.Atan(s.taper)
+	}
+	k2 := k * k
+	t2 := t * t
+	a := 2 + k2 + t2
+	b := math.Sqrt((k2-t2)*(k2-t2) + 4*t2)
+	return math.Sqrt(0.5 * (a + b))
+}
+
 // Evaluate returns the minimum distance to a 3d screw form.
+// The distance is a lower bound on the distance to the screw surface.
 func (s *ScrewSDF3) Evaluate(p v3.Vec) float64 {
 	// map the 3d point back to the xy space of the profile
 	p0 := v2.Vec{}
@@ -399,6 +422,8 @@ func (s *ScrewSDF3) Evaluate(p v3.Vec) float64 {
 	p0.X = SawTooth(z, s.pitch)
 	// get the thread profile distance
 	d0 := s.thread.Evaluate(p0)
+	// The helical mapping stretches space, so bound the distance.
+	d0 = lipschitzBound(d0, math.Sqrt(p.X*p.X+p.Y*p.Y), p.Z, s.gradient)
 	// create a region for the screw length
 	d1 := math.Abs(p.Z) - s.length
 	// return the intersection
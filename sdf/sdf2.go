@@ -68,6 +68,12 @@ func (s *CircleSDF2) Evaluate(p v2.Vec) float64 {
 	return p.Length() - s.radius
 }
 
+// EvaluateInterval returns bounds on the distance to a 2d circle over a region.
+func (s *CircleSDF2) EvaluateInterval(b Box2) Interval {
+	d := originDistance2(b)
+	return Interval{d[0] - s.radius, d[1] - s.radius}
+}
+
 // BoundingBox returns the bounding box of a 2d circle.
 func (s *CircleSDF2) BoundingBox() Box2 {
 	return s.bb
@@ -98,6 +104,17 @@ func (s *BoxSDF2) Evaluate(p v2.Vec) float64 {
 	return sdfBox2d(p, s.size) - s.round
 }
 
+// EvaluateInterval returns bounds on the distance to a 2d box over a region.
+func (s *BoxSDF2) EvaluateInterval(b Box2) Interval {
+	x := absInterval(Interval{b.Min.X, b.Max.X})
+	y := absInterval(Interval{b.Min.Y, b.Max.Y})
+	d := boxInterval(
+		Interval{x[0] - s.size.X, x[1] - s.size.X},
+		Interval{y[0] - s.size.Y, y[1] - s.size.Y},
+	)
+	return Interval{d[0] - s.round, d[1] - s.round}
+}
+
 // BoundingBox returns the bounding box for a 2d box.
 func (s *BoxSDF2) BoundingBox() Box2 {
 	return s.bb
@@ -195,6 +212,13 @@ func (s *IntersectionSDF2) Evaluate(p v2.Vec) float64 {
 	return s.max(s.s0.Evaluate(p), s.s1.Evaluate(p))
 }
 
+// EvaluateInterval returns bounds on the distance to the intersection of two SDF2s over a region.
+func (s *IntersectionSDF2) EvaluateInterval(b Box2) Interval {
+	d0 := EvaluateInterval2(s.s0, b)
+	d1 := EvaluateInterval2(s.s1, b)
+	return Interval{s.max(d0[0], d1[0]), s.max(d0[1], d1[1])}
+}
+
 // SetMax sets the maximum function to control blending.
 func (s *IntersectionSDF2) SetMax(max MaxFunc) {
 	s.max = max
@@ -266,6 +290,11 @@ func (s *TransformSDF2) Evaluate(p v2.Vec) float64 {
 	return s.sdf.Evaluate(q)
 }
 
+// EvaluateInterval returns bounds on the distance to a transformed SDF2 over a region.
+func (s *TransformSDF2) EvaluateInterval(b Box2) Interval {
+	return EvaluateInterval2(s.sdf, s.mInv.MulBox(b))
+}
+
 // BoundingBox returns the bounding box of a transformed SDF2.
 func (s *TransformSDF2) BoundingBox() Box2 {
 	return s.bb
@@ -542,6 +571,7 @@ type UnionSDF2 struct {
 	sdf []SDF2
 	min MinFunc
 	bb  Box2
+	bvh *bvh2 // bounding volume hierarchy (nil when blending)
 }
 
 // Union2D returns the union of multiple SDF2 objects.
@@ -565,18 +595,27 @@ func Union2D(sdf ...SDF2) SDF2 {
 		return s.sdf[0]
 	}
 	// work out the bounding box
+	bbs := make([]Box2, len(s.sdf))
 	bb := s.sdf[0].BoundingBox()
-	for _, x := range s.sdf {
-		bb = bb.Extend(x.BoundingBox())
+	for i, x := range s.sdf {
+		bbs[i] = x.BoundingBox()
+		bb = bb.Extend(bbs[i])
 	}
 	s.bb = bb
 	s.min = math.Min
+	s.bvh = newBVH2(bbs)
 	return &s
 }
 
 // Evaluate returns the minimum distance to the SDF2 union.
 func (s *UnionSDF2) Evaluate(p v2.Vec) float64 {
 
+	if s.bvh != nil {
+		return s.bvh.minimum(p, func(i int) float64 {
+			return s.sdf[i].Evaluate(p)
+		})
+	}
+
 	// work out the min/max distance for every bounding box
 	vs := make([]Interval, len(s.sdf))
 	minDist2 := -1.0
@@ -608,6 +647,35 @@ func (s *UnionSDF2) Evaluate(p v2.Vec) float64 {
 	return d
 }
 
+// EvaluateInterval returns bounds on the distance to the SDF2 union over a region.
+func (s *UnionSDF2) EvaluateInterval(b Box2) Interval {
+	d := Interval{0, math.Inf(1)}
+	n := 0
+	add := func(i int) {
+		x := EvaluateInterval2(s.sdf[i], b)
+		if n == 0 {
+			d = x
+		} else {
+			d = Interval{s.min(d[0], x[0]), s.min(d[1], x[1])}
+		}
+		n++
+	}
+	if s.bvh != nil {
+		s.bvh.overlap(b, add)
+	} else {
+		for i, x := range s.sdf {
+			if b.Overlap(x.BoundingBox()) {
+				add(i)
+			}
+		}
+	}
+	if n != 0 && n != len(s.sdf) {
+		// the region is outside the other sdfs, so their distances are positive
+		d[0] = s.min(d[0], 0)
+	}
+	return d
+}
+
 // EvaluateSlow returns the minimum distance to the SDF2 union.
 func (s *UnionSDF2) EvaluateSlow(p v2.Vec) float64 {
 	var d float64
@@ -623,8 +691,10 @@ func (s *UnionSDF2) EvaluateSlow(p v2.Vec) float64 {
 }
 
 // SetMin sets the minimum function to control SDF2 blending.
+// Blending needs the distance to nearby SDF2s, so the union no longer uses the BVH.
 func (s *UnionSDF2) SetMin(min MinFunc) {
 	s.min = min
+	s.bvh = nil
 }
 
 // BoundingBox returns the bounding box of an SDF2 union.
@@ -663,6 +733,13 @@ func (s *DifferenceSDF2) Evaluate(p v2.Vec) float64 {
 	return s.max(s.s0.Evaluate(p), -s.s1.Evaluate(p))
 }
 
+// EvaluateInterval returns bounds on the distance to the difference of two SDF2s over a region.
+func (s *DifferenceSDF2) EvaluateInterval(b Box2) Interval {
+	d0 := EvaluateInterval2(s.s0, b)
+	d1 := EvaluateInterval2(s.s1, b)
+	return Interval{s.max(d0[0], -d1[1]), s.max(d0[1], -d1[0])}
+}
+
 // SetMax sets the maximum function to control blending.
 func (s *DifferenceSDF2) SetMax(max MaxFunc) {
 	s.max = max
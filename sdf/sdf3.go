@@ -145,10 +145,11 @@ func (s *SorSDF3) BoundingBox() Box3 {
 
 // ExtrudeSDF3 extrudes an SDF2 to an SDF3.
 type ExtrudeSDF3 struct {
-	sdf     SDF2
-	height  float64
-	extrude ExtrudeFunc
-	bb      Box3
+	sdf      SDF2
+	height   float64
+	extrude  ExtrudeFunc
+	gradient GradientFunc // gradient bound for non-linear extrusions
+	bb       Box3
 }
 
 // Extrude3D does a linear extrude on an SDF3.
@@ -169,6 +170,7 @@ func TwistExtrude3D(sdf SDF2, height, twist float64) SDF3 {
 	s.sdf = sdf
 	s.height = height / 2
 	s.extrude = TwistExtrude(height, twist)
+	s.gradient = twistGradient(height, twist)
 	// work out the bounding box
 	bb := sdf.BoundingBox()
 	l := bb.Max.Length()
@@ -182,6 +184,7 @@ func ScaleExtrude3D(sdf SDF2, height float64, scale v2.Vec) SDF3 {
 	s.sdf = sdf
 	s.height = height / 2
 	s.extrude = ScaleExtrude(height, scale)
+	s.gradient = scaleTwistGradient(height, 0, scale)
 	// work out the bounding box
 	bb := sdf.BoundingBox()
 	bb = bb.Extend(Box2{bb.Min.Mul(scale), bb.Max.Mul(scale)})
@@ -195,6 +198,7 @@ func ScaleTwistExtrude3D(sdf SDF2, height, twist float64, scale v2.Vec) SDF3 {
 	s.sdf = sdf
 	s.height = height / 2
 	s.extrude = ScaleTwistExtrude(height, twist, scale)
+	s.gradient = scaleTwistGradient(height, twist, scale)
 	// work out the bounding box
 	bb := sdf.BoundingBox()
 	bb = bb.Extend(Box2{bb.Min.Mul(scale), bb.Max.Mul(scale)})
@@ -204,9 +208,13 @@ func ScaleTwistExtrude3D(sdf SDF2, height, twist float64, scale v2.Vec) SDF3 {
 }
 
 // Evaluate returns the minimum distance to an extrusion.
+// Non-linear extrusions return a lower bound on the distance.
 func (s *ExtrudeSDF3) Evaluate(p v3.Vec) float64 {
 	// sdf for the projected 2d surface
 	a := s.sdf.Evaluate(s.extrude(p))
+	if s.gradient != nil {
+		a = lipschitzBound(a, math.Sqrt(p.X*p.X+p.Y*p.Y), p.Z, s.gradient)
+	}
 	// sdf for the extrusion region: z = [-height, height]
 	b := math.Abs(p.Z) - s.height
 	// return the intersection
@@ -214,8 +222,15 @@ func (s *ExtrudeSDF3) Evaluate(p v3.Vec) float64 {
 }
 
 // SetExtrude sets the extrusion control function.
+// The extrusion function is assumed to preserve distance, see SetGradient.
 func (s *ExtrudeSDF3) SetExtrude(extrude ExtrudeFunc) {
 	s.extrude = extrude
+	s.gradient = nil
+}
+
+// SetGradient sets the gradient bound for a non-linear extrusion function.
+func (s *ExtrudeSDF3) SetGradient(gradient GradientFunc) {
+	s.gradient = gradient
 }
 
 // BoundingBox returns the bounding box for an extrusion.
@@ -410,6 +425,19 @@ func (s *BoxSDF3) Evaluate(p v3.Vec) float64 {
 	return sdfBox3d(p, s.size) - s.round
 }
 
+// EvaluateInterval returns bounds on the distance to a 3d box over a region.
+func (s *BoxSDF3) EvaluateInterval(b Box3) Interval {
+	x := absInterval(Interval{b.Min.X, b.Max.X})
+	y := absInterval(Interval{b.Min.Y, b.Max.Y})
+	z := absInterval(Interval{b.Min.Z, b.Max.Z})
+	d := boxInterval(
+		Interval{x[0] - s.size.X, x[1] - s.size.X},
+		Interval{y[0] - s.size.Y, y[1] - s.size.Y},
+		Interval{z[0] - s.size.Z, z[1] - s.size.Z},
+	)
+	return Interval{d[0] - s.round, d[1] - s.round}
+}
+
 // BoundingBox returns the bounding box for a 3d box.
 func (s *BoxSDF3) BoundingBox() Box3 {
 	return s.bb
@@ -441,6 +469,12 @@ func (s *SphereSDF3) Evaluate(p v3.Vec) float64 {
 	return p.Length() - s.radius
 }
 
+// EvaluateInterval returns bounds on the distance to a sphere over a region.
+func (s *SphereSDF3) EvaluateInterval(b Box3) Interval {
+	d := originDistance3(b)
+	return Interval{d[0] - s.radius, d[1] - s.radius}
+}
+
 // BoundingBox returns the bounding box for a sphere.
 func (s *SphereSDF3) BoundingBox() Box3 {
 	return s.bb
@@ -491,6 +525,17 @@ func (s *CylinderSDF3) Evaluate(p v3.Vec) float64 {
 	return d - s.round
 }
 
+// EvaluateInterval returns bounds on the distance to a cylinder over a region.
+func (s *CylinderSDF3) EvaluateInterval(b Box3) Interval {
+	r := originDistance2(Box2{v2.Vec{b.Min.X, b.Min.Y}, v2.Vec{b.Max.X, b.Max.Y}})
+	z := absInterval(Interval{b.Min.Z, b.Max.Z})
+	d := boxInterval(
+		Interval{r[0] - s.radius, r[1] - s.radius},
+		Interval{z[0] - s.height, z[1] - s.height},
+	)
+	return Interval{d[0] - s.round, d[1] - s.round}
+}
+
 // BoundingBox returns the bounding box for a cylinder.
 func (s *CylinderSDF3) BoundingBox() Box3 {
 	return s.bb
@@ -604,6 +649,11 @@ func (s *TransformSDF3) Evaluate(p v3.Vec) float64 {
 	return s.sdf.Evaluate(s.inverse.MulPosition(p))
 }
 
+// EvaluateInterval returns bounds on the distance to a transformed SDF3 over a region.
+func (s *TransformSDF3) EvaluateInterval(b Box3) Interval {
+	return EvaluateInterval3(s.sdf, s.inverse.MulBox(b))
+}
+
 // BoundingBox returns the bounding box of a transformed SDF3.
 func (s *TransformSDF3) BoundingBox() Box3 {
 	return s.bb
@@ -637,6 +687,14 @@ func (s *ScaleUniformSDF3) Evaluate(p v3.Vec) float64 {
 	return s.sdf.Evaluate(q) * s.k
 }
 
+// EvaluateInterval returns bounds on the distance to a uniformly scaled SDF3 over a region.
+func (s *ScaleUniformSDF3) EvaluateInterval(b Box3) Interval {
+	q := Box3{b.Min.MulScalar(s.invK), b.Max.MulScalar(s.invK)}
+	q = Box3{q.Min.Min(q.Max), q.Min.Max(q.Max)}
+	d := EvaluateInterval3(s.sdf, q)
+	return Interval{d[0] * s.k, d[1] * s.k}.Sort()
+}
+
 // BoundingBox returns the bounding box of a uniformly scaled SDF3.
 func (s *ScaleUniformSDF3) BoundingBox() Box3 {
 	return s.bb
@@ -649,6 +707,7 @@ type UnionSDF3 struct {
 	sdf []SDF3
 	min MinFunc
 	bb  Box3
+	bvh *bvh3 // bounding volume hierarchy (nil when blending)
 }
 
 // Union3D returns the union of multiple SDF3 objects.
@@ -672,17 +731,25 @@ func Union3D(sdf ...SDF3) SDF3 {
 		return s.sdf[0]
 	}
 	// work out the bounding box
+	bbs := make([]Box3, len(s.sdf))
 	bb := s.sdf[0].BoundingBox()
-	for _, x := range s.sdf {
-		bb = bb.Extend(x.BoundingBox())
+	for i, x := range s.sdf {
+		bbs[i] = x.BoundingBox()
+		bb = bb.Extend(bbs[i])
 	}
 	s.bb = bb
 	s.min = math.Min
+	s.bvh = newBVH3(bbs)
 	return &s
 }
 
 // Evaluate returns the minimum distance to an SDF3 union.
 func (s *UnionSDF3) Evaluate(p v3.Vec) float64 {
+	if s.bvh != nil {
+		return s.bvh.minimum(p, func(i int) float64 {
+			return s.sdf[i].Evaluate(p)
+		})
+	}
 	var d float64
 	for i, x := range s.sdf {
 		if i == 0 {
@@ -694,9 +761,40 @@ func (s *UnionSDF3) Evaluate(p v3.Vec) float64 {
 	return d
 }
 
+// EvaluateInterval returns bounds on the distance to an SDF3 union over a region.
+func (s *UnionSDF3) EvaluateInterval(b Box3) Interval {
+	d := Interval{0, math.Inf(1)}
+	n := 0
+	add := func(i int) {
+		x := EvaluateInterval3(s.sdf[i], b)
+		if n == 0 {
+			d = x
+		} else {
+			d = Interval{s.min(d[0], x[0]), s.min(d[1], x[1])}
+		}
+		n++
+	}
+	if s.bvh != nil {
+		s.bvh.overlap(b, add)
+	} else {
+		for i, x := range s.sdf {
+			if b.Overlap(x.BoundingBox()) {
+				add(i)
+			}
+		}
+	}
+	if n != 0 && n != len(s.sdf) {
+		// the region is outside the other sdfs, so their distances are positive
+		d[0] = s.min(d[0], 0)
+	}
+	return d
+}
+
 // SetMin sets the minimum function to control blending.
+// Blending needs the distance to every SDF3, so the union no longer culls distant SDF3s.
 func (s *UnionSDF3) SetMin(min MinFunc) {
 	s.min = min
+	s.bvh = nil
 }
 
 // BoundingBox returns the bounding box of an SDF3 union.
@@ -735,6 +833,13 @@ func (s *DifferenceSDF3) Evaluate(p v3.Vec) float64 {
 	return s.max(s.s0.Evaluate(p), -s.s1.Evaluate(p))
 }
 
+// EvaluateInterval returns bounds on the distance to the SDF3 difference over a region.
+func (s *DifferenceSDF3) EvaluateInterval(b Box3) Interval {
+	d0 := EvaluateInterval3(s.s0, b)
+	d1 := EvaluateInterval3(s.s1, b)
+	return Interval{s.max(d0[0], -d1[1]), s.max(d0[1], -d1[0])}
+}
+
 // SetMax sets the maximum function to control blending.
 func (s *DifferenceSDF3) SetMax(max MaxFunc) {
 	s.max = max
@@ -810,6 +915,13 @@ func (s *IntersectionSDF3) Evaluate(p v3.Vec) float64 {
 	return s.max(s.s0.Evaluate(p), s.s1.Evaluate(p))
 }
 
+// EvaluateInterval returns bounds on the distance to the SDF3 intersection over a region.
+func (s *IntersectionSDF3) EvaluateInterval(b Box3) Interval {
+	d0 := EvaluateInterval3(s.s0, b)
+	d1 := EvaluateInterval3(s.s1, b)
+	return Interval{s.max(d0[0], d1[0]), s.max(d0[1], d1[1])}
+}
+
 // SetMax sets the maximum function to control blending.
 func (s *IntersectionSDF3) SetMax(max MaxFunc) {
 	s.max = max
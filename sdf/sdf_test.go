@@ -721,6 +721,21 @@ func Test_Rotate_To_Vector(t *testing.T) {
 		}
 	}
 
+	// nearly opposite vectors (not caught by the opposite vector check)
+	for _, delta := range []float64{1e-4, 1e-6, 1e-8} {
+		a := v3.Vec{1, 0, 0}
+		b := v3.Vec{-1, delta, 0}.Normalize()
+		m := RotateToVector(a, b)
+		if ax := m.MulPosition(a); !ax.Equals(b, 1e-12) {
+			t.Errorf("delta %g: expected %v, got %v", delta, b, ax)
+		}
+		// the other axes are rotated to unit vectors
+		for _, x := range []v3.Vec{{0, 1, 0}, {0, 0, 1}} {
+			if l := m.MulPosition(x).Length(); math.Abs(l-1) > 1e-12 {
+				t.Errorf("delta %g: %v has length %f", delta, x, l)
+			}
+		}
+	}
 }
 
 //-----------------------------------------------------------------------------
@@ -816,3 +831,62 @@ func Test_Normal(t *testing.T) {
 }
 
 //-----------------------------------------------------------------------------
+
+// checkConservative checks that the SDF3 distance at a point is a lower
+// bound on the distance to the surface, i.e. there are no points within
+// that distance that have a different inside/outside state.
+func checkConservative(t *testing.T, name string, s SDF3) {
+	t.Helper()
+	bb := s.BoundingBox().ScaleAboutCenter(1.2)
+	fails := 0
+	for _, p := range bb.RandomSet(500) {
+		d := s.Evaluate(p)
+		r := 0.999 * math.Abs(d)
+		ball := NewBox3(p, v3.Vec{2 * r, 2 * r, 2 * r})
+		for _, q := range ball.RandomSet(50) {
+			if q.Sub(p).Length() > r {
+				continue
+			}
+			if (s.Evaluate(q) < 0) != (d < 0) {
+				fails++
+				break
+			}
+		}
+	}
+	if fails != 0 {
+		t.Errorf("%s: %d points overestimate the distance", name, fails)
+	}
+}
+
+func Test_Conservative(t *testing.T) {
+	thread, err := ISOThread(10, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	screw, err := Screw3D(thread, 20, 0, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkConservative(t, "screw", screw)
+
+	multi, err := Screw3D(thread, 20, DtoR(3), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkConservative(t, "multi-start tapered screw", multi)
+
+	box := Box2D(v2.Vec{20, 4}, 0)
+	checkConservative(t, "twist", TwistExtrude3D(box, 10, Tau))
+	checkConservative(t, "scale", ScaleExtrude3D(box, 10, v2.Vec{3, 0.5}))
+	checkConservative(t, "scale twist", ScaleTwistExtrude3D(box, 10, Pi, v2.Vec{2, 0.5}))
+
+	// the profile must be small relative to the path curvature
+	profile := Box2D(v2.Vec{6, 2}, 0)
+	sweep, err := SweepSpline3D(profile, v3.VecSet{{0, 0, 0}, {0, 0, 20}, {20, 10, 30}}, Tau, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkConservative(t, "twisted sweep", sweep)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,390 @@
+//-----------------------------------------------------------------------------
+/*
+
+Sparse Narrow-Band Voxel SDF3
+
+The SDF is sampled on a regular grid of voxel corners. The grid is divided
+into blocks of voxels (similar to OpenVDB leaf nodes). Only the blocks near
+the surface (within a narrow band) store their samples. Blocks further away
+store a single "tile" value, the distance at the center of the block reduced
+by the distance to the block corners (so it is a lower bound for the block).
+
+Evaluation is a trilinear interpolation of the samples, so the SDF is accurate
+near the surface (where the renderers need it) and only approximate elsewhere.
+
+Deciding if a block is near the surface uses a single evaluation at the block
+center, so the underlying SDF must not over estimate distances.
+
+The voxel data can be saved to a file and loaded again, so expensive SDFs
+(E.g. imported meshes) can be cached between program runs.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/deadsy/sdfx/vec/conv"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// sparseBlockSize is the number of voxel corners along each side of a block.
+const sparseBlockSize = 8
+const sparseBlockSize3 = sparseBlockSize * sparseBlockSize * sparseBlockSize
+
+// sparseMaxBlocks is the maximum number of blocks in a voxel grid.
+const sparseMaxBlocks = 1 << 26
+
+// sparseBand is the default narrow band width (in voxels).
+const sparseBand = 3.0
+
+// SparseVoxelSDF3 is a pre-computed voxel-based SDF3 that only stores the voxels near the surface.
+type SparseVoxelSDF3 struct {
+	bb     Box3      // bounding box of the sampled SDF
+	origin v3.Vec    // position of the first voxel corner
+	size   float64   // voxel size
+	band   float64   // narrow band width
+	cells  v3i.Vec   // number of voxels
+	blocks v3i.Vec   // number of blocks
+	block  []int32   // index into data for each block, < 0 for tiles
+	tile   []float32 // tile value for each block
+	data   []float32 // voxel corner values for the stored blocks
+}
+
+// NewSparseVoxelSDF3 returns a sparse voxel SDF3 with meshCells voxels on the longest side of the bounding box.
+// Samples are stored within band of the surface (band = 0 uses a default of a few voxels).
+// The progress listener may be nil.
+func NewSparseVoxelSDF3(s SDF3, meshCells int, band float64, progress chan float64) (*SparseVoxelSDF3, error) {
+	if meshCells <= 0 {
+		return nil, ErrMsg("meshCells <= 0")
+	}
+	if band < 0 {
+		return nil, ErrMsg("band < 0")
+	}
+
+	bb := s.BoundingBox()
+	size := bb.Size().MaxComponent() / float64(meshCells)
+	if size <= 0 {
+		return nil, ErrMsg("empty bounding box")
+	}
+	if band == 0 {
+		band = sparseBand * size
+	}
+	// the grid covers the narrow band outside of the bounding box
+	grid := bb.Enlarge(v3.Vec{2 * band, 2 * band, 2 * band})
+	cells := conv.V3ToV3i(grid.Size().DivScalar(size).Ceil().Max(v3.Vec{1, 1, 1}))
+	blocks := sparseBlocks(cells)
+	if sparseCount(blocks) > sparseMaxBlocks {
+		return nil, ErrMsg("too many voxel blocks")
+	}
+
+	m := &SparseVoxelSDF3{
+		bb:     bb,
+		origin: grid.Min,
+		size:   size,
+		band:   band,
+		cells:  cells,
+		blocks: blocks,
+		block:  make([]int32, blocks.X*blocks.Y*blocks.Z),
+		tile:   make([]float32, blocks.X*blocks.Y*blocks.Z),
+	}
+
+	// the distance from a block center to its corners
+	half := 0.5 * float64(sparseBlockSize-1) * size
+	radius := half * math.Sqrt(3)
+
+	// sample the blocks in parallel, one x-slab of blocks at a time
+	stored := make([][]float32, len(m.block))
+	var wg sync.WaitGroup
+	slabs := make(chan int)
+	done := make(chan bool)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for bx := range slabs {
+				for by := 0; by < blocks.Y; by++ {
+					for bz := 0; bz < blocks.Z; bz++ {
+						k := m.blockIndex(bx, by, bz)
+						c0 := v3i.Vec{bx * sparseBlockSize, by * sparseBlockSize, bz * sparseBlockSize}
+						center := m.corner(c0).AddScalar(half)
+						d := s.Evaluate(center)
+						if math.Abs(d) > band+radius {
+							// The block is away from the surface. The tile is the distance
+							// from the block corners furthest from the surface, so it doesn't
+							// over estimate the distance anywhere in the block.
+							m.tile[k] = float32(math.Copysign(math.Abs(d)-radius, d))
+							continue
+						}
+						values := make([]float32, sparseBlockSize3)
+						i := 0
+						for x := 0; x < sparseBlockSize; x++ {
+							for y := 0; y < sparseBlockSize; y++ {
+								for z := 0; z < sparseBlockSize; z++ {
+									values[i] = float32(s.Evaluate(m.corner(c0.Add(v3i.Vec{x, y, z}))))
+									i++
+								}
+							}
+						}
+						stored[k] = values
+					}
+				}
+				done <- true
+			}
+		}()
+	}
+	go func() {
+		for bx := 0; bx < blocks.X; bx++ {
+			slabs <- bx
+		}
+		close(slabs)
+		wg.Wait()
+		close(done)
+	}()
+	n := 0
+	for range done {
+		n++
+		if progress != nil {
+			progress <- float64(n) / float64(blocks.X)
+		}
+	}
+
+	// pack the stored blocks
+	for k, values := range stored {
+		if values == nil {
+			m.block[k] = -1
+			continue
+		}
+		m.block[k] = int32(len(m.data) / sparseBlockSize3)
+		m.data = append(m.data, values...)
+	}
+
+	return m, nil
+}
+
+// sparseBlocks returns the number of blocks for a voxel grid.
+// A block covers sparseBlockSize corners and there are cells + 1 corners.
+func sparseBlocks(cells v3i.Vec) v3i.Vec {
+	n := cells.AddScalar(sparseBlockSize)
+	return v3i.Vec{n.X / sparseBlockSize, n.Y / sparseBlockSize, n.Z / sparseBlockSize}
+}
+
+// sparseCount returns the number of blocks in a grid (or sparseMaxBlocks + 1 if there are more).
+func sparseCount(blocks v3i.Vec) int {
+	n := 1
+	for _, x := range []int{blocks.X, blocks.Y, blocks.Z} {
+		if x > sparseMaxBlocks {
+			return sparseMaxBlocks + 1
+		}
+		// n and x are at most sparseMaxBlocks, so this doesn't overflow
+		n *= x
+		if n > sparseMaxBlocks {
+			return sparseMaxBlocks + 1
+		}
+	}
+	return n
+}
+
+// corner returns the position of a voxel corner.
+func (m *SparseVoxelSDF3) corner(i v3i.Vec) v3.Vec {
+	return m.origin.Add(conv.V3iToV3(i).MulScalar(m.size))
+}
+
+// blockIndex returns the index of a block.
+func (m *SparseVoxelSDF3) blockIndex(x, y, z int) int {
+	return (x*m.blocks.Y+y)*m.blocks.Z + z
+}
+
+// value returns the sampled value at a voxel corner.
+func (m *SparseVoxelSDF3) value(x, y, z int) float64 {
+	k := m.blockIndex(x/sparseBlockSize, y/sparseBlockSize, z/sparseBlockSize)
+	b := m.block[k]
+	if b < 0 {
+		return float64(m.tile[k])
+	}
+	x %= sparseBlockSize
+	y %= sparseBlockSize
+	z %= sparseBlockSize
+	return float64(m.data[int(b)*sparseBlockSize3+(x*sparseBlockSize+y)*sparseBlockSize+z])
+}
+
+// Evaluate returns the minimum distance to a sparse voxel SDF3.
+// Outside of the voxel grid the distance to the grid is added to the boundary value.
+func (m *SparseVoxelSDF3) Evaluate(p v3.Vec) float64 {
+	max := m.corner(m.cells)
+	q := p.Clamp(m.origin, max)
+	d := p.Sub(q).Length()
+	// voxel coordinates
+	q = q.Sub(m.origin).DivScalar(m.size)
+	i := conv.V3ToV3i(q)
+	// the far faces of the grid are in the last voxel
+	i.X = minInt(i.X, m.cells.X-1)
+	i.Y = minInt(i.Y, m.cells.Y-1)
+	i.Z = minInt(i.Z, m.cells.Z-1)
+	f := q.Sub(conv.V3iToV3(i))
+	// trilinear interpolation over the voxel corners
+	c00 := m.value(i.X, i.Y, i.Z)*(1-f.X) + m.value(i.X+1, i.Y, i.Z)*f.X
+	c01 := m.value(i.X, i.Y, i.Z+1)*(1-f.X) + m.value(i.X+1, i.Y, i.Z+1)*f.X
+	c10 := m.value(i.X, i.Y+1, i.Z)*(1-f.X) + m.value(i.X+1, i.Y+1, i.Z)*f.X
+	c11 := m.value(i.X, i.Y+1, i.Z+1)*(1-f.X) + m.value(i.X+1, i.Y+1, i.Z+1)*f.X
+	c0 := c00*(1-f.Y) + c10*f.Y
+	c1 := c01*(1-f.Y) + c11*f.Y
+	return c0*(1-f.Z) + c1*f.Z + d
+}
+
+// BoundingBox returns the bounding box for a sparse voxel SDF3.
+func (m *SparseVoxelSDF3) BoundingBox() Box3 {
+	return m.bb
+}
+
+// Band returns the width of the narrow band about the surface.
+// Distances further from the surface are approximate.
+func (m *SparseVoxelSDF3) Band() float64 {
+	return m.band
+}
+
+// Blocks returns the number of stored blocks and the total number of blocks.
+func (m *SparseVoxelSDF3) Blocks() (int, int) {
+	return len(m.data) / sparseBlockSize3, len(m.block)
+}
+
+//-----------------------------------------------------------------------------
+// Serialization
+
+// sparseMagic identifies a sparse voxel file.
+var sparseMagic = [8]byte{'S', 'D', 'F', 'X', 'V', 'O', 'X', '3'}
+
+const sparseVersion = 1
+
+// sparseHeader is the file header for a sparse voxel SDF3.
+type sparseHeader struct {
+	Magic     [8]byte
+	Version   uint32
+	BlockSize uint32
+	Cells     [3]int32
+	Blocks    [3]int32
+	Stored    int32 // number of stored blocks
+	Min, Max  [3]float64
+	Origin    [3]float64
+	Size      float64
+	Band      float64
+}
+
+// Encode writes the sparse voxel SDF3 to a writer.
+func (m *SparseVoxelSDF3) Encode(w io.Writer) error {
+	h := sparseHeader{
+		Magic:     sparseMagic,
+		Version:   sparseVersion,
+		BlockSize: sparseBlockSize,
+		Cells:     [3]int32{int32(m.cells.X), int32(m.cells.Y), int32(m.cells.Z)},
+		Blocks:    [3]int32{int32(m.blocks.X), int32(m.blocks.Y), int32(m.blocks.Z)},
+		Stored:    int32(len(m.data) / sparseBlockSize3),
+		Min:       [3]float64{m.bb.Min.X, m.bb.Min.Y, m.bb.Min.Z},
+		Max:       [3]float64{m.bb.Max.X, m.bb.Max.Y, m.bb.Max.Z},
+		Origin:    [3]float64{m.origin.X, m.origin.Y, m.origin.Z},
+		Size:      m.size,
+		Band:      m.band,
+	}
+	for _, x := range []interface{}{&h, m.block, m.tile, m.data} {
+		if err := binary.Write(w, binary.LittleEndian, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeSparseVoxelSDF3 reads a sparse voxel SDF3 from a reader.
+func DecodeSparseVoxelSDF3(r io.Reader) (*SparseVoxelSDF3, error) {
+	var h sparseHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	if h.Magic != sparseMagic {
+		return nil, ErrMsg("not a sparse voxel file")
+	}
+	if h.Version != sparseVersion || h.BlockSize != sparseBlockSize {
+		return nil, ErrMsg("unsupported sparse voxel file version")
+	}
+	cells := v3i.Vec{int(h.Cells[0]), int(h.Cells[1]), int(h.Cells[2])}
+	blocks := v3i.Vec{int(h.Blocks[0]), int(h.Blocks[1]), int(h.Blocks[2])}
+	if cells.X <= 0 || cells.Y <= 0 || cells.Z <= 0 || blocks != sparseBlocks(cells) {
+		return nil, ErrMsg("bad sparse voxel grid size")
+	}
+	n := sparseCount(blocks)
+	if n > sparseMaxBlocks {
+		return nil, ErrMsg("too many sparse voxel blocks")
+	}
+	if h.Stored < 0 || int(h.Stored) > n || !(h.Size > 0) {
+		return nil, ErrMsg("bad sparse voxel header")
+	}
+	m := &SparseVoxelSDF3{
+		bb:     Box3{v3.Vec{h.Min[0], h.Min[1], h.Min[2]}, v3.Vec{h.Max[0], h.Max[1], h.Max[2]}},
+		origin: v3.Vec{h.Origin[0], h.Origin[1], h.Origin[2]},
+		size:   h.Size,
+		band:   h.Band,
+		cells:  cells,
+		blocks: blocks,
+		block:  make([]int32, n),
+		tile:   make([]float32, n),
+	}
+	for _, x := range []interface{}{m.block, m.tile} {
+		if err := binary.Read(r, binary.LittleEndian, x); err != nil {
+			return nil, err
+		}
+	}
+	// the stored blocks are read one at a time, so a bad count can't allocate more than the input
+	values := make([]float32, sparseBlockSize3)
+	for i := 0; i < int(h.Stored); i++ {
+		if err := binary.Read(r, binary.LittleEndian, values); err != nil {
+			return nil, err
+		}
+		m.data = append(m.data, values...)
+	}
+	for _, b := range m.block {
+		if b >= h.Stored {
+			return nil, ErrMsg("bad sparse voxel block index")
+		}
+	}
+	return m, nil
+}
+
+// Save writes the sparse voxel SDF3 to a file.
+func (m *SparseVoxelSDF3) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := m.Encode(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadSparseVoxelSDF3 reads a sparse voxel SDF3 from a file.
+func LoadSparseVoxelSDF3(path string) (*SparseVoxelSDF3, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeSparseVoxelSDF3(bufio.NewReader(f))
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,158 @@
+//-----------------------------------------------------------------------------
+/*
+
+Sparse Voxel SDF3 Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deadsy/sdfx/vec/conv"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_SparseVoxelSDF3(t *testing.T) {
+	sphere, _ := Sphere3D(10)
+	s, err := NewSparseVoxelSDF3(sphere, 64, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, total := s.Blocks()
+	if stored == 0 || stored >= total {
+		t.Errorf("expected a sparse grid, got %d of %d blocks", stored, total)
+	}
+	b := sphere.BoundingBox().ScaleAboutCenter(1.5)
+	for i := 0; i < 10000; i++ {
+		p := b.Random()
+		d0 := sphere.Evaluate(p)
+		d1 := s.Evaluate(p)
+		if math.Abs(d0) < s.Band() {
+			if math.Abs(d0-d1) > 0.01 {
+				t.Errorf("%v: expected %f, got %f", p, d0, d1)
+			}
+		} else if (d0 < 0) != (d1 < 0) {
+			t.Errorf("%v: expected %f, got %f (sign)", p, d0, d1)
+		}
+	}
+	if _, err := NewSparseVoxelSDF3(sphere, 0, 0, nil); err == nil {
+		t.Error("expected error for meshCells == 0")
+	}
+}
+
+func Test_SparseVoxelSDF3_Tiles(t *testing.T) {
+	sphere, _ := Sphere3D(10)
+	s, err := NewSparseVoxelSDF3(sphere, 64, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tile blocks don't over estimate the distance
+	tile := func(i v3i.Vec) bool {
+		k := s.blockIndex(i.X/sparseBlockSize, i.Y/sparseBlockSize, i.Z/sparseBlockSize)
+		return s.block[k] < 0
+	}
+	b := Box3{s.origin, s.corner(s.cells)}
+	inside, outside := 0, 0
+	for n := 0; n < 20000; n++ {
+		p := b.Random()
+		i := conv.V3ToV3i(p.Sub(s.origin).DivScalar(s.size))
+		if !tile(i) || !tile(i.AddScalar(1)) {
+			continue
+		}
+		d0 := sphere.Evaluate(p)
+		d1 := s.Evaluate(p)
+		// the inside (negative) distance is also a lower bound on the magnitude
+		if math.Abs(d1) > math.Abs(d0)+1e-4 || (d0 < 0) != (d1 < 0) {
+			t.Errorf("%v: %f over estimates %f", p, d1, d0)
+		}
+		if d0 < 0 {
+			inside++
+		} else {
+			outside++
+		}
+	}
+	if inside == 0 || outside == 0 {
+		t.Errorf("expected tile blocks inside and outside, got %d %d", inside, outside)
+	}
+}
+
+func Test_SparseVoxelSDF3_Save(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 20, 30}, 1)
+	s0, err := NewSparseVoxelSDF3(box, 50, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "sdfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "box.vox")
+	if err := s0.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	s1, err := LoadSparseVoxelSDF3(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s0.BoundingBox() != s1.BoundingBox() || s0.Band() != s1.Band() {
+		t.Errorf("expected %v band %f, got %v band %f", s0.BoundingBox(), s0.Band(), s1.BoundingBox(), s1.Band())
+	}
+	b := box.BoundingBox().ScaleAboutCenter(1.2)
+	for i := 0; i < 1000; i++ {
+		p := b.Random()
+		if d0, d1 := s0.Evaluate(p), s1.Evaluate(p); d0 != d1 {
+			t.Errorf("%v: expected %f, got %f", p, d0, d1)
+		}
+	}
+	// truncated and corrupted files
+	var buf bytes.Buffer
+	if err := s0.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err := DecodeSparseVoxelSDF3(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("expected error for truncated data")
+	}
+	// bad header counts
+	header := func(f func(h *sparseHeader)) []byte {
+		var h sparseHeader
+		binary.Read(bytes.NewReader(data), binary.LittleEndian, &h)
+		f(&h)
+		var b bytes.Buffer
+		binary.Write(&b, binary.LittleEndian, &h)
+		return append(b.Bytes(), data[b.Len():]...)
+	}
+	huge := header(func(h *sparseHeader) {
+		h.Cells = [3]int32{1 << 30, 1 << 30, 1 << 30}
+		b := sparseBlocks(v3i.Vec{1 << 30, 1 << 30, 1 << 30})
+		h.Blocks = [3]int32{int32(b.X), int32(b.Y), int32(b.Z)}
+	})
+	if _, err := DecodeSparseVoxelSDF3(bytes.NewReader(huge)); err == nil {
+		t.Error("expected error for too many blocks")
+	}
+	stored := header(func(h *sparseHeader) {
+		h.Stored = h.Blocks[0] * h.Blocks[1] * h.Blocks[2]
+	})
+	if _, err := DecodeSparseVoxelSDF3(bytes.NewReader(stored)); err == nil {
+		t.Error("expected error for too many stored blocks")
+	}
+	data[0] = 'X'
+	if _, err := DecodeSparseVoxelSDF3(bytes.NewReader(data)); err == nil {
+		t.Error("expected error for bad magic")
+	}
+}
+
+//-----------------------------------------------------------------------------
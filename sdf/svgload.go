@@ -0,0 +1,749 @@
+//-----------------------------------------------------------------------------
+/*
+
+SVG Import
+
+Read the filled shapes of an SVG file as SDF2s.
+
+Supported elements are path, rect, circle, ellipse, polygon and polyline.
+Path data commands are M/L/H/V/C/S/Q/T/A/Z (absolute and relative).
+Curves are converted to polygons using the Bezier code.
+The transform attributes of elements and groups are applied.
+
+The fill-rule (nonzero/evenodd) of each shape is used to work out the holes.
+Elements with fill="none" are not shapes and are skipped, as are the contents
+of defs, clipPath, mask, symbol, pattern and marker elements.
+
+Coordinates are in SVG user units (the viewBox is ignored) and the y-axis is
+flipped so the drawing is upright. Shapes with percentage lengths (E.g. a
+background rect with width="100%") are relative to the viewport, and are skipped.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+// Tokenizing
+
+// svgScanner reads the numbers, flags and commands of svg attribute values.
+type svgScanner struct {
+	s string
+	i int
+}
+
+// skip skips white space and commas.
+func (p *svgScanner) skip() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n,", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// done returns true at the end of the string.
+func (p *svgScanner) done() bool {
+	p.skip()
+	return p.i == len(p.s)
+}
+
+// isNumber returns true if a number is next.
+func (p *svgScanner) isNumber() bool {
+	p.skip()
+	return p.i < len(p.s) && strings.IndexByte("+-.0123456789", p.s[p.i]) >= 0
+}
+
+// command returns the next path command letter.
+func (p *svgScanner) command() (byte, error) {
+	p.skip()
+	c := p.s[p.i]
+	if strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) < 0 {
+		return 0, fmt.Errorf("bad path command %q", c)
+	}
+	p.i++
+	return c, nil
+}
+
+// number returns the next number.
+func (p *svgScanner) number() (float64, error) {
+	p.skip()
+	j := p.i
+	if j < len(p.s) && (p.s[j] == '+' || p.s[j] == '-') {
+		j++
+	}
+	digits := func() {
+		for j < len(p.s) && p.s[j] >= '0' && p.s[j] <= '9' {
+			j++
+		}
+	}
+	digits()
+	if j < len(p.s) && p.s[j] == '.' {
+		j++
+		digits()
+	}
+	if j < len(p.s) && (p.s[j] == 'e' || p.s[j] == 'E') {
+		// don't mistake a following "em" or "ex" unit for an exponent
+		k := j + 1
+		if k < len(p.s) && (p.s[k] == '+' || p.s[k] == '-') {
+			k++
+		}
+		if k < len(p.s) && p.s[k] >= '0' && p.s[k] <= '9' {
+			j = k
+			digits()
+		}
+	}
+	x, err := strconv.ParseFloat(p.s[p.i:j], 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad number at %q", p.s[p.i:])
+	}
+	p.i = j
+	return x, nil
+}
+
+// numbers returns the next n numbers.
+func (p *svgScanner) numbers(n int) ([]float64, error) {
+	x := make([]float64, n)
+	for i := range x {
+		var err error
+		if x[i], err = p.number(); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// flag returns the next arc flag (a single 0 or 1, maybe without a separator).
+func (p *svgScanner) flag() (bool, error) {
+	p.skip()
+	if p.i < len(p.s) {
+		switch p.s[p.i] {
+		case '0':
+			p.i++
+			return false, nil
+		case '1':
+			p.i++
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("bad arc flag at %q", p.s[p.i:])
+}
+
+// svgNumbers returns all the numbers in a string.
+func svgNumbers(s string) ([]float64, error) {
+	p := &svgScanner{s: s}
+	var x []float64
+	for !p.done() {
+		f, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		x = append(x, f)
+	}
+	return x, nil
+}
+
+// svgUnits are the sizes of the length units in user units (px at 96 dpi).
+var svgUnits = map[string]float64{
+	"":   1,
+	"px": 1,
+	"in": 96,
+	"cm": 96 / 2.54,
+	"mm": 96 / 25.4,
+	"pt": 96.0 / 72,
+	"pc": 96.0 / 6,
+}
+
+// errSVGPercent is returned for a length relative to the viewport.
+var errSVGPercent = errors.New("percentage length")
+
+// svgLength returns the value of a length attribute.
+func svgLength(s string) (float64, error) {
+	p := &svgScanner{s: strings.TrimSpace(s)}
+	if p.done() {
+		return 0, nil
+	}
+	x, err := p.number()
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(p.s[p.i:]) == "%" {
+		return 0, errSVGPercent
+	}
+	k, ok := svgUnits[strings.TrimSpace(p.s[p.i:])]
+	if !ok {
+		return 0, fmt.Errorf("unsupported length %q", s)
+	}
+	return x * k, nil
+}
+
+//-----------------------------------------------------------------------------
+// Transforms
+
+var svgTransformRegexp = regexp.MustCompile(`([a-zA-Z]+)\s*\(([^)]*)\)`)
+
+// svgTransform returns the matrix for a transform attribute.
+func svgTransform(s string) (M33, error) {
+	m := Identity2d()
+	for _, t := range svgTransformRegexp.FindAllStringSubmatch(s, -1) {
+		x, err := svgNumbers(t[2])
+		if err != nil {
+			return m, err
+		}
+		n := len(x)
+		var tm M33
+		switch {
+		case t[1] == "matrix" && n == 6:
+			tm = M33{x[0], x[2], x[4], x[1], x[3], x[5], 0, 0, 1}
+		case t[1] == "translate" && n == 1:
+			tm = Translate2d(v2.Vec{x[0], 0})
+		case t[1] == "translate" && n == 2:
+			tm = Translate2d(v2.Vec{x[0], x[1]})
+		case t[1] == "scale" && n == 1:
+			tm = Scale2d(v2.Vec{x[0], x[0]})
+		case t[1] == "scale" && n == 2:
+			tm = Scale2d(v2.Vec{x[0], x[1]})
+		case t[1] == "rotate" && n == 1:
+			tm = Rotate2d(DtoR(x[0]))
+		case t[1] == "rotate" && n == 3:
+			c := v2.Vec{x[1], x[2]}
+			tm = Translate2d(c).Mul(Rotate2d(DtoR(x[0]))).Mul(Translate2d(c.Neg()))
+		case t[1] == "skewX" && n == 1:
+			tm = M33{1, math.Tan(DtoR(x[0])), 0, 0, 1, 0, 0, 0, 1}
+		case t[1] == "skewY" && n == 1:
+			tm = M33{1, 0, 0, math.Tan(DtoR(x[0])), 1, 0, 0, 0, 1}
+		default:
+			return m, fmt.Errorf("bad transform %q", t[0])
+		}
+		m = m.Mul(tm)
+	}
+	return m, nil
+}
+
+//-----------------------------------------------------------------------------
+// Paths
+
+// svgPath builds the line segments for the subpaths of a shape.
+type svgPath struct {
+	m     M33      // user to output coordinates
+	lines []*Line2 // line segments of the finished subpaths
+	b     *Bezier  // current subpath
+	start v2.Vec   // start of the current subpath
+	cur   v2.Vec   // current point
+	ctrl  v2.Vec   // last control point (for smooth curves)
+	prev  byte     // previous command
+}
+
+// add adds a point to the current subpath.
+func (p *svgPath) add(x v2.Vec) *BezierVertex {
+	if p.b == nil {
+		// drawing after a close starts a new subpath
+		p.b = NewBezier()
+		p.b.AddV2(p.m.MulPosition(p.cur))
+		p.start = p.cur
+	}
+	return p.b.AddV2(p.m.MulPosition(x))
+}
+
+// finish adds the current subpath (closed for filling) to the line segments.
+func (p *svgPath) finish() error {
+	b := p.b
+	p.b = nil
+	if b == nil || len(b.vlist) < 3 {
+		// nothing to fill
+		return nil
+	}
+	b.Close()
+	poly, err := b.Polygon()
+	if err != nil {
+		return err
+	}
+	for _, l := range VertexToLine(poly.Vertices(), true) {
+		if !l.Degenerate(tolerance) {
+			p.lines = append(p.lines, l)
+		}
+	}
+	return nil
+}
+
+func (p *svgPath) moveTo(x v2.Vec) error {
+	if err := p.finish(); err != nil {
+		return err
+	}
+	p.b = NewBezier()
+	p.b.AddV2(p.m.MulPosition(x))
+	p.start = x
+	p.cur = x
+	return nil
+}
+
+func (p *svgPath) lineTo(x v2.Vec) {
+	p.add(x)
+	p.cur = x
+}
+
+func (p *svgPath) cubicTo(c1, c2, x v2.Vec) {
+	p.add(c1).Mid()
+	p.add(c2).Mid()
+	p.add(x)
+	p.ctrl = c2
+	p.cur = x
+}
+
+func (p *svgPath) quadTo(c, x v2.Vec) {
+	p.add(c).Mid()
+	p.add(x)
+	p.ctrl = c
+	p.cur = x
+}
+
+func (p *svgPath) close() error {
+	err := p.finish()
+	p.cur = p.start
+	return err
+}
+
+// svgAngle returns the signed angle between two vectors.
+func svgAngle(u, v v2.Vec) float64 {
+	return math.Atan2(u.X*v.Y-u.Y*v.X, u.Dot(v))
+}
+
+// arcTo adds an elliptical arc as cubic bezier curves.
+// See: https://www.w3.org/TR/SVG11/implnote.html#ArcImplementationNotes
+func (p *svgPath) arcTo(r v2.Vec, phi float64, large, sweep bool, x v2.Vec) {
+	x0 := p.cur
+	if x0.Equals(x, 0) {
+		return
+	}
+	rx, ry := math.Abs(r.X), math.Abs(r.Y)
+	if rx == 0 || ry == 0 {
+		p.lineTo(x)
+		return
+	}
+	sin, cos := math.Sincos(phi)
+	// the midpoint in the ellipse frame
+	d := x0.Sub(x).MulScalar(0.5)
+	x1 := cos*d.X + sin*d.Y
+	y1 := -sin*d.X + cos*d.Y
+	// scale up radii that are too small
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx *= math.Sqrt(l)
+		ry *= math.Sqrt(l)
+	}
+	// the center
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	k := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		k = -k
+	}
+	cx1 := k * rx * y1 / ry
+	cy1 := -k * ry * x1 / rx
+	mid := x0.Add(x).MulScalar(0.5)
+	c := v2.Vec{cos*cx1 - sin*cy1 + mid.X, sin*cx1 + cos*cy1 + mid.Y}
+	// the start and sweep angles
+	u := v2.Vec{(x1 - cx1) / rx, (y1 - cy1) / ry}
+	v := v2.Vec{(-x1 - cx1) / rx, (-y1 - cy1) / ry}
+	theta := svgAngle(v2.Vec{1, 0}, u)
+	dtheta := svgAngle(u, v)
+	if !sweep && dtheta > 0 {
+		dtheta -= Tau
+	} else if sweep && dtheta < 0 {
+		dtheta += Tau
+	}
+	// a cubic bezier for each quarter (or less) of the ellipse
+	n := int(math.Ceil(math.Abs(dtheta)/(0.5*Pi) - epsilon))
+	if n < 1 {
+		n = 1
+	}
+	delta := dtheta / float64(n)
+	h := 4.0 / 3.0 * math.Tan(delta/4)
+	point := func(t float64) v2.Vec {
+		st, ct := math.Sincos(t)
+		return v2.Vec{c.X + rx*ct*cos - ry*st*sin, c.Y + rx*ct*sin + ry*st*cos}
+	}
+	tangent := func(t float64) v2.Vec {
+		st, ct := math.Sincos(t)
+		return v2.Vec{-rx*st*cos - ry*ct*sin, -rx*st*sin + ry*ct*cos}
+	}
+	for i := 0; i < n; i++ {
+		t0 := theta + float64(i)*delta
+		t1 := t0 + delta
+		x3 := point(t1)
+		if i == n-1 {
+			x3 = x
+		}
+		p.cubicTo(point(t0).Add(tangent(t0).MulScalar(h)), point(t1).Sub(tangent(t1).MulScalar(h)), x3)
+	}
+}
+
+// pathData adds the subpaths of svg path data.
+func (p *svgPath) pathData(d string) error {
+	s := &svgScanner{s: d}
+	var cmd byte
+	for !s.done() {
+		if !s.isNumber() {
+			c, err := s.command()
+			if err != nil {
+				return err
+			}
+			cmd = c
+		} else if cmd == 0 {
+			return fmt.Errorf("path data must start with a command")
+		}
+		// relative commands are lower case
+		rel := v2.Vec{}
+		if cmd >= 'a' {
+			rel = p.cur
+		}
+		point := func() (v2.Vec, error) {
+			x, err := s.numbers(2)
+			if err != nil {
+				return v2.Vec{}, err
+			}
+			return v2.Vec{x[0], x[1]}.Add(rel), nil
+		}
+		upper := cmd &^ 0x20
+		switch upper {
+		case 'M':
+			x, err := point()
+			if err != nil {
+				return err
+			}
+			if err := p.moveTo(x); err != nil {
+				return err
+			}
+			// following coordinate pairs are line-to commands
+			cmd = 'L' | (cmd & 0x20)
+		case 'L':
+			x, err := point()
+			if err != nil {
+				return err
+			}
+			p.lineTo(x)
+		case 'H', 'V':
+			x, err := s.number()
+			if err != nil {
+				return err
+			}
+			y := p.cur
+			if upper == 'H' {
+				y.X = x + rel.X
+			} else {
+				y.Y = x + rel.Y
+			}
+			p.lineTo(y)
+		case 'C', 'S':
+			c1 := p.cur
+			if upper == 'C' {
+				var err error
+				if c1, err = point(); err != nil {
+					return err
+				}
+			} else if p.prev == 'C' || p.prev == 'S' {
+				// reflect the previous control point
+				c1 = p.cur.MulScalar(2).Sub(p.ctrl)
+			}
+			c2, err := point()
+			if err != nil {
+				return err
+			}
+			x, err := point()
+			if err != nil {
+				return err
+			}
+			p.cubicTo(c1, c2, x)
+		case 'Q', 'T':
+			c := p.cur
+			if upper == 'Q' {
+				var err error
+				if c, err = point(); err != nil {
+					return err
+				}
+			} else if p.prev == 'Q' || p.prev == 'T' {
+				// reflect the previous control point
+				c = p.cur.MulScalar(2).Sub(p.ctrl)
+			}
+			x, err := point()
+			if err != nil {
+				return err
+			}
+			p.quadTo(c, x)
+		case 'A':
+			r, err := s.numbers(3)
+			if err != nil {
+				return err
+			}
+			large, err := s.flag()
+			if err != nil {
+				return err
+			}
+			sweep, err := s.flag()
+			if err != nil {
+				return err
+			}
+			x, err := point()
+			if err != nil {
+				return err
+			}
+			p.arcTo(v2.Vec{r[0], r[1]}, DtoR(r[2]), large, sweep, x)
+		case 'Z':
+			if err := p.close(); err != nil {
+				return err
+			}
+		}
+		p.prev = upper
+	}
+	return p.finish()
+}
+
+// polygon adds a polygon from a list of coordinates.
+func (p *svgPath) polygon(points string) error {
+	x, err := svgNumbers(points)
+	if err != nil {
+		return err
+	}
+	if len(x) < 6 {
+		// nothing to fill
+		return nil
+	}
+	poly := NewPolygon()
+	for i := 0; i+1 < len(x); i += 2 {
+		poly.AddV2(p.m.MulPosition(v2.Vec{x[i], x[i+1]}))
+	}
+	for _, l := range VertexToLine(poly.Vertices(), true) {
+		if !l.Degenerate(tolerance) {
+			p.lines = append(p.lines, l)
+		}
+	}
+	return nil
+}
+
+// ellipse adds an ellipse.
+func (p *svgPath) ellipse(c, r v2.Vec) error {
+	if r.X <= 0 || r.Y <= 0 {
+		return nil
+	}
+	if err := p.moveTo(v2.Vec{c.X + r.X, c.Y}); err != nil {
+		return err
+	}
+	p.arcTo(r, 0, false, true, v2.Vec{c.X - r.X, c.Y})
+	p.arcTo(r, 0, false, true, v2.Vec{c.X + r.X, c.Y})
+	return p.close()
+}
+
+// rect adds a rectangle, with rounded corners for non-zero r.
+func (p *svgPath) rect(x, size, r v2.Vec) error {
+	if size.X <= 0 || size.Y <= 0 {
+		return nil
+	}
+	r = r.Min(size.MulScalar(0.5))
+	x1 := x.Add(size)
+	if err := p.moveTo(v2.Vec{x.X + r.X, x.Y}); err != nil {
+		return err
+	}
+	corner := func(y v2.Vec) {
+		if r.X > 0 && r.Y > 0 {
+			p.arcTo(r, 0, false, true, y)
+		}
+	}
+	p.lineTo(v2.Vec{x1.X - r.X, x.Y})
+	corner(v2.Vec{x1.X, x.Y + r.Y})
+	p.lineTo(v2.Vec{x1.X, x1.Y - r.Y})
+	corner(v2.Vec{x1.X - r.X, x1.Y})
+	p.lineTo(v2.Vec{x.X + r.X, x1.Y})
+	corner(v2.Vec{x.X, x1.Y - r.Y})
+	p.lineTo(v2.Vec{x.X, x.Y + r.Y})
+	corner(v2.Vec{x.X + r.X, x.Y})
+	return p.close()
+}
+
+//-----------------------------------------------------------------------------
+// Elements
+
+// svgState is the state inherited by svg elements.
+type svgState struct {
+	m    M33      // user to output coordinates
+	fill FillRule // fill rule
+	none bool     // fill="none"
+}
+
+// svgSkip are elements with contents that are not drawn directly.
+var svgSkip = map[string]bool{
+	"defs":     true,
+	"clipPath": true,
+	"mask":     true,
+	"symbol":   true,
+	"pattern":  true,
+	"marker":   true,
+}
+
+// svgAttributes returns the attributes of an element, with style properties taking precedence.
+func svgAttributes(e xml.StartElement) map[string]string {
+	attr := make(map[string]string)
+	for _, a := range e.Attr {
+		attr[a.Name.Local] = strings.TrimSpace(a.Value)
+	}
+	for _, prop := range strings.Split(attr["style"], ";") {
+		kv := strings.SplitN(prop, ":", 2)
+		if len(kv) == 2 {
+			attr[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return attr
+}
+
+// apply returns the state for an element.
+func (s svgState) apply(attr map[string]string) (svgState, error) {
+	if t, ok := attr["transform"]; ok {
+		m, err := svgTransform(t)
+		if err != nil {
+			return s, err
+		}
+		s.m = s.m.Mul(m)
+	}
+	switch attr["fill-rule"] {
+	case "nonzero":
+		s.fill = FillNonZero
+	case "evenodd":
+		s.fill = FillEvenOdd
+	}
+	if f, ok := attr["fill"]; ok {
+		s.none = f == "none"
+	}
+	return s, nil
+}
+
+// svgShape returns the line segments for a shape element.
+func svgShape(name string, attr map[string]string, m M33) ([]*Line2, error) {
+	// lengths returns the values of length attributes
+	lengths := func(names ...string) ([]float64, error) {
+		x := make([]float64, len(names))
+		for i, n := range names {
+			var err error
+			if x[i], err = svgLength(attr[n]); err != nil {
+				return nil, err
+			}
+		}
+		return x, nil
+	}
+	p := &svgPath{m: m}
+	var err error
+	switch name {
+	case "path":
+		err = p.pathData(attr["d"])
+	case "polygon", "polyline":
+		err = p.polygon(attr["points"])
+	case "circle":
+		var x []float64
+		if x, err = lengths("cx", "cy", "r"); err == nil {
+			err = p.ellipse(v2.Vec{x[0], x[1]}, v2.Vec{x[2], x[2]})
+		}
+	case "ellipse":
+		var x []float64
+		if x, err = lengths("cx", "cy", "rx", "ry"); err == nil {
+			err = p.ellipse(v2.Vec{x[0], x[1]}, v2.Vec{x[2], x[3]})
+		}
+	case "rect":
+		var x []float64
+		if x, err = lengths("x", "y", "width", "height", "rx", "ry"); err == nil {
+			r := v2.Vec{x[4], x[5]}
+			// a missing radius is the same as the other one
+			if _, ok := attr["rx"]; !ok {
+				r.X = r.Y
+			}
+			if _, ok := attr["ry"]; !ok {
+				r.Y = r.X
+			}
+			err = p.rect(v2.Vec{x[0], x[1]}, v2.Vec{x[2], x[3]}, r)
+		}
+	}
+	if err == errSVGPercent {
+		// skip the shape
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return p.lines, nil
+}
+
+// DecodeSVG returns the filled shapes of an svg document as SDF2s (one per element).
+// Use Union2D to combine them.
+func DecodeSVG(r io.Reader) ([]SDF2, error) {
+	d := xml.NewDecoder(r)
+	// flip the y-axis so the drawing is upright
+	stack := []svgState{{m: MirrorX()}}
+	skip := 0
+	var shapes []SDF2
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			attr := svgAttributes(t)
+			if skip > 0 || svgSkip[t.Name.Local] || attr["display"] == "none" {
+				skip++
+				continue
+			}
+			s, err := stack[len(stack)-1].apply(attr)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, s)
+			if s.none {
+				continue
+			}
+			lines, err := svgShape(t.Name.Local, attr, s.m)
+			if err != nil {
+				return nil, err
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			shape, err := Mesh2DFill(lines, s.fill)
+			if err != nil {
+				return nil, err
+			}
+			shapes = append(shapes, shape)
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(shapes) == 0 {
+		return nil, ErrMsg("no filled shapes")
+	}
+	return shapes, nil
+}
+
+// LoadSVG returns the filled shapes of an svg file as SDF2s (one per element). See DecodeSVG.
+func LoadSVG(path string) ([]SDF2, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeSVG(f)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,158 @@
+//-----------------------------------------------------------------------------
+/*
+
+SVG Import Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_svgScanner(t *testing.T) {
+	x, err := svgNumbers("1.5.5-2e1,3e-1 -.5E+1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{1.5, 0.5, -20, 0.3, -5}
+	if len(x) != len(want) {
+		t.Fatalf("expected %v, got %v", want, x)
+	}
+	for i := range x {
+		if !EqualFloat64(x[i], want[i], tolerance) {
+			t.Errorf("expected %v, got %v", want, x)
+		}
+	}
+	for s, want := range map[string]float64{"10": 10, "2.5px": 2.5, "1in": 96, "25.4mm": 96} {
+		if x, err := svgLength(s); err != nil || !EqualFloat64(x, want, tolerance) {
+			t.Errorf("%s: expected %f, got %f (%v)", s, want, x, err)
+		}
+	}
+	if _, err := svgLength("50%"); err == nil {
+		t.Error("expected error for percentage length")
+	}
+}
+
+func Test_svgTransform(t *testing.T) {
+	tests := []struct {
+		s    string
+		p, q v2.Vec
+	}{
+		{"translate(10,20)", v2.Vec{1, 2}, v2.Vec{11, 22}},
+		{"translate(10) scale(2 3)", v2.Vec{1, 2}, v2.Vec{12, 6}},
+		{"rotate(90)", v2.Vec{1, 0}, v2.Vec{0, 1}},
+		{"rotate(180, 5, 5)", v2.Vec{0, 0}, v2.Vec{10, 10}},
+		{"matrix(1 2 3 4 5 6)", v2.Vec{1, 1}, v2.Vec{9, 12}},
+		{"skewX(45)", v2.Vec{0, 1}, v2.Vec{1, 1}},
+	}
+	for _, test := range tests {
+		m, err := svgTransform(test.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q := m.MulPosition(test.p); !q.Equals(test.q, tolerance) {
+			t.Errorf("%s: expected %v, got %v", test.s, test.q, q)
+		}
+	}
+	if _, err := svgTransform("rotate(1,2)"); err == nil {
+		t.Error("expected error for bad transform")
+	}
+}
+
+const testSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="200mm" height="100mm" viewBox="0 0 200 100">
+  <defs>
+    <rect id="hidden" x="0" y="0" width="1000" height="1000"/>
+  </defs>
+  <path d="M0 0 H10 V10 H0 Z M3 3 H7 V7 H3 Z" fill-rule="evenodd"/>
+  <path style="fill:#000;fill-rule:nonzero" d="m20 0 h10 v10 h-10 z m3 3 h4 v4 h-4 z"/>
+  <circle cx="50" cy="5" r="5"/>
+  <path d="M60 5 A5 5 0 0 1 70 5 A5 5 0 1 1 60 5 Z"/>
+  <g transform="translate(80,0)">
+    <rect x="0" y="0" width="10" height="10" rx="2"/>
+    <ellipse cx="20" cy="5" rx="5" ry="2.5" transform="rotate(90 20 5)"/>
+  </g>
+  <polygon points="110,0 120,0 120,10"/>
+  <path d="M130 0 Q135 10 140 0 T150 0 C150 10 160 10 160 0 S170 -10 170 0 Z"/>
+  <path d="M0 50 H200" fill="none" stroke="black"/>
+</svg>`
+
+func Test_DecodeSVG(t *testing.T) {
+	shapes, err := DecodeSVG(strings.NewReader(testSVG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shapes) != 8 {
+		t.Fatalf("expected 8 shapes, got %d", len(shapes))
+	}
+	// svg y is down, sdf y is up
+	tests := []struct {
+		shape int
+		p     v2.Vec
+		d     float64
+	}{
+		{0, v2.Vec{1, -5}, -1},
+		{0, v2.Vec{5, -5}, 2},
+		{1, v2.Vec{25, -5}, -2},
+		{2, v2.Vec{50, -5}, -5},
+		{2, v2.Vec{50, -15}, 5},
+		{3, v2.Vec{65, -5}, -5},
+		{3, v2.Vec{65, 5}, 5},
+		{4, v2.Vec{85, -5}, -5},
+		{4, v2.Vec{80, 0}, 2 * (math.Sqrt2 - 1)},
+		{5, v2.Vec{100, -5}, -2.5},
+		{5, v2.Vec{100, -9}, -1},
+		{5, v2.Vec{104, -5}, 1.5},
+		{6, v2.Vec{119, -5}, -1},
+		{6, v2.Vec{111, -5}, 2 * math.Sqrt2},
+		{7, v2.Vec{135, -2}, -2},
+		{7, v2.Vec{145, 2}, -2},
+	}
+	for i, test := range tests {
+		d := shapes[test.shape].Evaluate(test.p)
+		if !EqualFloat64(d, test.d, 0.05) {
+			t.Errorf("test %d: shape %d at %v, expected %f, got %f", i, test.shape, test.p, test.d, d)
+		}
+	}
+	// combined shapes
+	s := Union2D(shapes...)
+	if d := s.Evaluate(v2.Vec{100, -50}); d <= 0 {
+		t.Errorf("expected outside, got %f", d)
+	}
+
+	// a background rect with percentage lengths is skipped
+	shapes, err = DecodeSVG(strings.NewReader(`<svg><rect width="100%" height="100%" fill="white"/><circle r="5"/></svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shapes) != 1 {
+		t.Errorf("expected 1 shape, got %d", len(shapes))
+	}
+
+	bad := []string{
+		`<svg><path d="M0 0 L10"/></svg>`,
+		`<svg><path d="0 0 L10 10"/></svg>`,
+		`<svg><path d="M0 0 X10 10"/></svg>`,
+		`<svg><path d="M0 0 A 1 1 0 2 0 5 5"/></svg>`,
+		`<svg><rect width="10%" height="10"/></svg>`,
+		`<svg><rect width="10em" height="10"/></svg>`,
+		`<svg><path d="M0 0 H10" fill="none"/></svg>`,
+		`<svg><path d="M0 0 H10 V10 Z"</svg>`,
+	}
+	for _, s := range bad {
+		if _, err := DecodeSVG(strings.NewReader(s)); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,333 @@
+//-----------------------------------------------------------------------------
+/*
+
+Sweep an SDF2 profile along a 3D path.
+
+The path is a polyline. Each segment of the polyline carries the profile
+as a prism. The prisms are cut by miter planes at the polyline vertices so
+that adjacent prisms join without gaps.
+
+The profile plane is carried along the path using rotation minimising frames.
+Along a straight segment the frame does not change. At each vertex the frame is
+rotated by the minimal rotation that maps the incoming tangent onto the
+outgoing tangent (discrete parallel transport). This gives a frame without
+spurious twisting, so any twist is under user control.
+
+The profile X/Y axes map onto the frame normal/binormal. For a path that starts
+in the +Z direction the profile is oriented as it would be for Extrude3D.
+
+Smooth paths are given as cubic spline knots. The spline is sampled to a
+polyline and swept as above.
+
+Note: The profile should be small relative to the radius of curvature of the path.
+If the profile extends beyond the point where miter planes intersect the swept
+surface will self-intersect.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"errors"
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// sweepSegment is a single straight segment of the sweep path.
+type sweepSegment struct {
+	p0, p1 v3.Vec       // segment end points
+	t      v3.Vec       // unit tangent
+	n, b   v3.Vec       // frame normal and binormal (profile x and y axes)
+	m0, m1 v3.Vec       // miter plane normals at p0 and p1
+	cap0   bool         // p0 is the start of the path
+	cap1   bool         // p1 is the end of the path
+	a0, a1 float64      // twist angle at p0 and p1
+	twist  GradientFunc // gradient bound for twisted segments
+	reach  float64      // maximum distance from the segment to the swept profile
+}
+
+// distance2 returns the distance squared from a point to the segment.
+func (s *sweepSegment) distance2(p v3.Vec) float64 {
+	v := s.p1.Sub(s.p0)
+	w := p.Sub(s.p0)
+	k := Clamp(w.Dot(v)/v.Length2(), 0, 1)
+	return w.Sub(v.MulScalar(k)).Length2()
+}
+
+// evaluate returns the distance to the profile swept along this segment.
+func (s *sweepSegment) evaluate(profile SDF2, p v3.Vec) float64 {
+	// distances to the miter planes (positive between the planes)
+	h0 := p.Sub(s.p0).Dot(s.m0)
+	h1 := s.p1.Sub(p).Dot(s.m1)
+	// twist angle, interpolated between the miter planes
+	a := s.a0
+	if s.a0 != s.a1 {
+		k := 0.0
+		if h0+h1 > 0 {
+			k = Clamp(h0/(h0+h1), 0, 1)
+		}
+		a = Mix(s.a0, s.a1, k)
+	}
+	// profile coordinates
+	d := p.Sub(s.p0)
+	q := v2.Vec{d.Dot(s.n), d.Dot(s.b)}
+	if a != 0 {
+		q = Rotate(a).MulPosition(q)
+	}
+	dp := profile.Evaluate(q)
+	if s.twist != nil {
+		dp = lipschitzBound(dp, q.Length(), 0, s.twist)
+	}
+	// End caps are normal to the path, so we can work out the distance
+	// to the cap edge. Miter planes are oblique, so we use the intersection.
+	// Miter planes are not surfaces, so they only apply beyond the segment.
+	dc := math.Inf(-1)
+	dm := math.Inf(-1)
+	if s.cap0 {
+		dc = math.Max(dc, -h0)
+	} else if h0 < 0 {
+		dm = math.Max(dm, -h0)
+	}
+	if s.cap1 {
+		dc = math.Max(dc, -h1)
+	} else if h1 < 0 {
+		dm = math.Max(dm, -h1)
+	}
+	if dp > 0 && dc > 0 {
+		dp = math.Sqrt(dp*dp + dc*dc)
+	} else {
+		dp = math.Max(dp, dc)
+	}
+	return math.Max(dp, dm)
+}
+
+// boundingBox returns the bounding box of the profile swept along this segment.
+func (s *sweepSegment) boundingBox(bb Box2) Box3 {
+	if s.a0 == 0 && s.a1 == 0 {
+		// No twist: the profile box forms a prism cut by the miter planes.
+		// Project the box vertices onto the miter planes.
+		var vs v3.VecSet
+		for _, v := range bb.Vertices() {
+			x := s.n.MulScalar(v.X).Add(s.b.MulScalar(v.Y))
+			vs = append(vs, s.p0.Add(x).Sub(s.t.MulScalar(x.Dot(s.m0)/s.t.Dot(s.m0))))
+			vs = append(vs, s.p1.Add(x).Sub(s.t.MulScalar(x.Dot(s.m1)/s.t.Dot(s.m1))))
+		}
+		return Box3{vs.Min(), vs.Max()}
+	}
+	// The cross section at each end lies on the miter plane, within a disk
+	// of radius "reach". The swept region is within the hull of the two disks.
+	disk := func(m v3.Vec) v3.Vec {
+		return v3.Vec{
+			s.reach * math.Sqrt(math.Max(0, 1-m.X*m.X)),
+			s.reach * math.Sqrt(math.Max(0, 1-m.Y*m.Y)),
+			s.reach * math.Sqrt(math.Max(0, 1-m.Z*m.Z)),
+		}
+	}
+	e0 := disk(s.m0)
+	e1 := disk(s.m1)
+	return Box3{s.p0.Sub(e0), s.p0.Add(e0)}.Extend(Box3{s.p1.Sub(e1), s.p1.Add(e1)})
+}
+
+//-----------------------------------------------------------------------------
+
+// sweepBuffer is the number of segment distances kept on the stack by Evaluate.
+const sweepBuffer = 64
+
+// SweepSDF3 is an SDF2 profile swept along a 3D path.
+type SweepSDF3 struct {
+	profile SDF2           // the swept profile
+	segment []sweepSegment // path segments
+	bb      Box3           // bounding box
+}
+
+// Sweep3D sweeps an SDF2 profile along a polyline path.
+// The profile is rotated by twist radians (linearly with path length) from the start to the end of the path.
+func Sweep3D(profile SDF2, path v3.VecSet, twist float64) (SDF3, error) {
+	if profile == nil {
+		return nil, errors.New("profile == nil")
+	}
+	// remove duplicate points
+	var vs v3.VecSet
+	for _, v := range path {
+		if len(vs) != 0 && v.Equals(vs[len(vs)-1], tolerance) {
+			continue
+		}
+		vs = append(vs, v)
+	}
+	if len(vs) < 2 {
+		return nil, errors.New("path needs at least 2 distinct points")
+	}
+
+	n := len(vs) - 1
+	s := SweepSDF3{
+		profile: profile,
+		segment: make([]sweepSegment, n),
+	}
+
+	// tangents and path length
+	length := 0.0
+	for i := 0; i < n; i++ {
+		x := &s.segment[i]
+		x.p0 = vs[i]
+		x.p1 = vs[i+1]
+		x.t = x.p1.Sub(x.p0).Normalize()
+		length += x.p1.Sub(x.p0).Length()
+		if i > 0 && x.t.Dot(s.segment[i-1].t) < -1+tolerance {
+			return nil, errors.New("path reverses direction")
+		}
+	}
+
+	// radius of a circle (centered on the path) enclosing the profile
+	bb := profile.BoundingBox()
+	r := 0.0
+	for _, v := range bb.Vertices() {
+		r = math.Max(r, v.Length())
+	}
+
+	// the initial frame matches Extrude3D for a path in the +Z direction
+	m := RotateToVector(v3.Vec{0, 0, 1}, s.segment[0].t)
+	nrm := m.MulPosition(v3.Vec{1, 0, 0})
+
+	l := 0.0
+	for i := range s.segment {
+		x := &s.segment[i]
+		// rotation minimising frame
+		if i > 0 {
+			nrm = RotateToVector(s.segment[i-1].t, x.t).MulPosition(nrm)
+		}
+		// keep the frame orthonormal as we go
+		x.n = nrm.Sub(x.t.MulScalar(nrm.Dot(x.t))).Normalize()
+		x.b = x.t.Cross(x.n)
+		nrm = x.n
+		// miter planes
+		x.m0 = x.t
+		x.cap0 = i == 0
+		x.cap1 = i == n-1
+		if i > 0 {
+			x.m0 = s.segment[i-1].t.Add(x.t).Normalize()
+		}
+		x.m1 = x.t
+		if i < n-1 {
+			x.m1 = x.t.Add(s.segment[i+1].t).Normalize()
+		}
+		// the cross section on a miter plane is stretched by 1/cos(bend/2)
+		x.reach = r / math.Min(x.m0.Dot(x.t), x.m1.Dot(x.t))
+		// twist angles
+		x.a0 = twist * l / length
+		l += x.p1.Sub(x.p0).Length()
+		x.a1 = twist * l / length
+		if x.a0 != x.a1 {
+			x.twist = twistGradient(x.p1.Sub(x.p0).Length(), x.a1-x.a0)
+		}
+	}
+
+	// work out the bounding box
+	s.bb = s.segment[0].boundingBox(bb)
+	for i := 1; i < n; i++ {
+		s.bb = s.bb.Extend(s.segment[i].boundingBox(bb))
+	}
+	return &s, nil
+}
+
+// SweepSpline3D sweeps an SDF2 profile along a cubic spline path through a set of knots.
+// Each span of the spline is approximated by n line segments.
+// The profile is rotated by twist radians (linearly with path length) from the start to the end of the path.
+func SweepSpline3D(profile SDF2, knot v3.VecSet, twist float64, n int) (SDF3, error) {
+	path, err := splinePath3(knot, n)
+	if err != nil {
+		return nil, err
+	}
+	return Sweep3D(profile, path, twist)
+}
+
+// Evaluate returns the minimum distance to a swept profile.
+func (s *SweepSDF3) Evaluate(p v3.Vec) float64 {
+	// work out the distance to every segment
+	// (a stack buffer avoids an allocation for most paths)
+	var buf [sweepBuffer]float64
+	ds := buf[:0]
+	minIndex := 0
+	for i := range s.segment {
+		ds = append(ds, math.Sqrt(s.segment[i].distance2(p)))
+		if ds[i] < ds[minIndex] {
+			minIndex = i
+		}
+	}
+	d := s.segment[minIndex].evaluate(s.profile, p)
+	for i := range s.segment {
+		// only segments that could be closer than the current
+		// minimum are worthy of consideration
+		if i != minIndex && ds[i]-s.segment[i].reach < d {
+			d = math.Min(d, s.segment[i].evaluate(s.profile, p))
+		}
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box for a swept profile.
+func (s *SweepSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// splinePath3 returns a polyline approximating a 3D cubic spline through a set of knots.
+// See CubicSpline2D for the details of the spline construction.
+func splinePath3(knot v3.VecSet, n int) (v3.VecSet, error) {
+	if len(knot) < 2 {
+		return nil, errors.New("cubic splines need at least 2 knots")
+	}
+	if n < 1 {
+		return nil, errors.New("n < 1")
+	}
+	// Build and solve the tridiagonal matrices
+	k := len(knot)
+	m := make([]v3.Vec, k)
+	d := [3][]float64{}
+	for j := range d {
+		d[j] = make([]float64, k)
+	}
+	for i := 1; i < k-1; i++ {
+		m[i] = v3.Vec{1, 4, 1}
+		for j := range d {
+			d[j][i] = 3 * (knot[i+1].Get(j) - knot[i-1].Get(j))
+		}
+	}
+	// Special case the end splines.
+	// Assume the 2nd derivative at the end points is 0.
+	m[0] = v3.Vec{0, 2, 1}
+	m[k-1] = v3.Vec{1, 2, 0}
+	for j := range d {
+		d[j][0] = 3 * (knot[1].Get(j) - knot[0].Get(j))
+		d[j][k-1] = 3 * (knot[k-1].Get(j) - knot[k-2].Get(j))
+	}
+	// solve to give the first derivatives at the knot points
+	var x [3][]float64
+	for j := range d {
+		var err error
+		x[j], err = triDiagonal(m, d[j])
+		if err != nil {
+			return nil, err
+		}
+	}
+	// sample the splines
+	path := make(v3.VecSet, 0, (k-1)*n+1)
+	for i := 0; i < k-1; i++ {
+		var p [3]CubicPolynomial
+		for j := range p {
+			p[j].Set(knot[i].Get(j), knot[i+1].Get(j), x[j][i], x[j][i+1])
+		}
+		for l := 0; l < n; l++ {
+			t := float64(l) / float64(n)
+			path = append(path, v3.Vec{p[0].f0(t), p[1].f0(t), p[2].f0(t)})
+		}
+	}
+	return append(path, knot[k-1]), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,125 @@
+//-----------------------------------------------------------------------------
+
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Sweep3D_Straight(t *testing.T) {
+	circle, err := Circle2D(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a straight sweep along z is a cylinder
+	s0, err := Sweep3D(circle, v3.VecSet{{0, 0, -2}, {0, 0, 0}, {0, 0, 2}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1, _ := Cylinder3D(4, 1, 0)
+	if !s0.BoundingBox().Equals(s1.BoundingBox(), tolerance) {
+		t.Errorf("bounding box %v expected %v", s0.BoundingBox(), s1.BoundingBox())
+	}
+	b := NewBox3(v3.Vec{}, v3.Vec{6, 6, 6})
+	for _, p := range b.RandomSet(1000) {
+		d0 := s0.Evaluate(p)
+		d1 := s1.Evaluate(p)
+		if math.Abs(d0-d1) > tolerance {
+			t.Errorf("%v %f (expected) %f (actual)", p, d1, d0)
+		}
+	}
+}
+
+func Test_Sweep3D_Bend(t *testing.T) {
+	circle, _ := Circle2D(1)
+	// a right angle bend in the XZ plane
+	s, err := Sweep3D(circle, v3.VecSet{{0, 0, 0}, {0, 0, 10}, {10, 0, 10}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		p v3.Vec
+		d float64
+	}{
+		{v3.Vec{0, 0, 5}, -1},
+		{v3.Vec{3, 0, 5}, 2},
+		{v3.Vec{0, 4, 5}, 3},
+		{v3.Vec{5, 0, 10}, -1},
+		{v3.Vec{5, 0, 13}, 2},
+		{v3.Vec{5, 2, 10}, 1},
+		{v3.Vec{0, 0, -1}, 1},
+		{v3.Vec{11, 0, 10}, 1},
+	}
+	for _, x := range tests {
+		d := s.Evaluate(x.p)
+		if math.Abs(d-x.d) > tolerance {
+			t.Errorf("%v %f (expected) %f (actual)", x.p, x.d, d)
+		}
+	}
+	bb := Box3{v3.Vec{-1, -1, 0}, v3.Vec{10, 1, 11}}
+	if !s.BoundingBox().Equals(bb, tolerance) {
+		t.Errorf("bounding box %v expected %v", s.BoundingBox(), bb)
+	}
+}
+
+func Test_Sweep3D_Allocs(t *testing.T) {
+	circle, _ := Circle2D(1)
+	var path v3.VecSet
+	for i := 0; i < 20; i++ {
+		path = append(path, v3.Vec{float64(i & 1), 0, float64(i)})
+	}
+	s, err := Sweep3D(circle, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// evaluation doesn't allocate
+	if n := testing.AllocsPerRun(100, func() { s.Evaluate(v3.Vec{1, 2, 3}) }); n != 0 {
+		t.Errorf("%f allocations per evaluation", n)
+	}
+}
+
+func Test_Sweep3D_Twist(t *testing.T) {
+	// an offset profile so the twist is visible
+	box := Box2D(v2.Vec{1, 1}, 0)
+	profile := Transform2D(box, Translate2d(v2.Vec{2, 0}))
+	// sweep along knots on a straight line, with twist
+	s0, err := SweepSpline3D(profile, v3.VecSet{{0, 0, -2}, {0, 0, -1}, {0, 0, 2}}, 1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// twisted extrusions have the twist centered on z = 0
+	s1 := Transform3D(TwistExtrude3D(profile, 4, 1), RotateZ(-0.5))
+	// The twisted extrusion distance is not exact away from the surface,
+	// so just check that both agree on inside/outside.
+	b := NewBox3(v3.Vec{}, v3.Vec{8, 8, 6})
+	for _, p := range b.RandomSet(1000) {
+		d0 := s0.Evaluate(p)
+		d1 := s1.Evaluate(p)
+		if (d0 < 0) != (d1 < 0) && math.Abs(d1) > tolerance {
+			t.Errorf("%v %f (expected) %f (actual)", p, d1, d0)
+		}
+	}
+}
+
+func Test_Sweep3D_Errors(t *testing.T) {
+	circle, _ := Circle2D(1)
+	if _, err := Sweep3D(circle, v3.VecSet{{0, 0, 0}, {0, 0, 0}}, 0); err == nil {
+		t.Error("expected error for a degenerate path")
+	}
+	if _, err := Sweep3D(circle, v3.VecSet{{0, 0, 0}, {0, 0, 1}, {0, 0, 0}}, 0); err == nil {
+		t.Error("expected error for a path that reverses")
+	}
+	if _, err := SweepSpline3D(circle, v3.VecSet{{0, 0, 0}, {0, 0, 1}}, 0, 0); err == nil {
+		t.Error("expected error for n == 0")
+	}
+}
+
+//-----------------------------------------------------------------------------
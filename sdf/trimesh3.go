@@ -0,0 +1,332 @@
+//-----------------------------------------------------------------------------
+/*
+
+Indexed Triangle Meshes
+
+A mesh with a shared vertex buffer and an index buffer of triangles.
+
+Vertices are welded when the mesh is built. Vertices within a tolerance of
+each other become a single vertex. Welding uses a spatial hash with a cell
+size equal to the tolerance, so only nearby vertices are compared.
+
+Topology:
+
+Each edge of a closed 2-manifold mesh is shared by exactly two triangles.
+Boundary edges are used by one triangle, non-manifold edges by more than two.
+
+For a closed orientable mesh with C connected components the Euler
+characteristic is V - E + F = 2C - 2g, where g is the total genus.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"errors"
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Mesh3 is a triangle mesh with shared vertices.
+type Mesh3 struct {
+	Vertex []v3.Vec // vertex positions
+	Index  [][3]int // triangle vertex indices, anti-clockwise when viewed from outside
+}
+
+// Edge3 is a mesh edge given as a pair of vertex indices (lowest index first).
+type Edge3 [2]int
+
+// newEdge3 returns the edge between two vertices.
+func newEdge3(a, b int) Edge3 {
+	if a > b {
+		a, b = b, a
+	}
+	return Edge3{a, b}
+}
+
+//-----------------------------------------------------------------------------
+// Vertex Welding
+
+// vertexWelder maps vertex positions to indices, merging nearby vertices.
+type vertexWelder struct {
+	tolerance float64
+	vertex    []v3.Vec
+	exact     map[v3.Vec]int     // tolerance == 0
+	grid      map[[3]int64][]int // tolerance > 0, vertex indices by grid cell
+}
+
+func newVertexWelder(tolerance float64) *vertexWelder {
+	w := &vertexWelder{tolerance: tolerance}
+	if tolerance > 0 {
+		w.grid = make(map[[3]int64][]int)
+	} else {
+		w.exact = make(map[v3.Vec]int)
+	}
+	return w
+}
+
+// cell returns the grid cell for a position.
+func (w *vertexWelder) cell(v v3.Vec) [3]int64 {
+	return [3]int64{
+		int64(math.Floor(v.X / w.tolerance)),
+		int64(math.Floor(v.Y / w.tolerance)),
+		int64(math.Floor(v.Z / w.tolerance)),
+	}
+}
+
+// add returns the index of a vertex, adding it if there is no vertex nearby.
+func (w *vertexWelder) add(v v3.Vec) int {
+	if w.exact != nil {
+		if i, ok := w.exact[v]; ok {
+			return i
+		}
+		i := len(w.vertex)
+		w.vertex = append(w.vertex, v)
+		w.exact[v] = i
+		return i
+	}
+	// a nearby vertex is in this cell or an adjacent cell
+	c := w.cell(v)
+	t2 := w.tolerance * w.tolerance
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			for dz := int64(-1); dz <= 1; dz++ {
+				for _, i := range w.grid[[3]int64{c[0] + dx, c[1] + dy, c[2] + dz}] {
+					if w.vertex[i].Sub(v).Length2() <= t2 {
+						return i
+					}
+				}
+			}
+		}
+	}
+	i := len(w.vertex)
+	w.vertex = append(w.vertex, v)
+	w.grid[c] = append(w.grid[c], i)
+	return i
+}
+
+//-----------------------------------------------------------------------------
+
+// NewMesh3 returns an indexed mesh for a set of triangles.
+// Vertices within tolerance of each other are welded into a single vertex.
+// Triangles that become degenerate after welding are dropped.
+func NewMesh3(triangles []*Triangle3, tolerance float64) (*Mesh3, error) {
+	if tolerance < 0 {
+		return nil, errors.New("tolerance < 0")
+	}
+	w := newVertexWelder(tolerance)
+	index := make([][3]int, 0, len(triangles))
+	for _, t := range triangles {
+		i := [3]int{w.add(t[0]), w.add(t[1]), w.add(t[2])}
+		if i[0] == i[1] || i[1] == i[2] || i[2] == i[0] {
+			continue
+		}
+		index = append(index, i)
+	}
+	m := &Mesh3{
+		Vertex: w.vertex,
+		Index:  index,
+	}
+	m.compact()
+	return m, nil
+}
+
+// compact removes vertices that are not used by any triangle.
+func (m *Mesh3) compact() {
+	remap := make([]int, len(m.Vertex))
+	for i := range remap {
+		remap[i] = -1
+	}
+	vertex := make([]v3.Vec, 0, len(m.Vertex))
+	for i := range m.Index {
+		for j, k := range m.Index[i] {
+			if remap[k] < 0 {
+				remap[k] = len(vertex)
+				vertex = append(vertex, m.Vertex[k])
+			}
+			m.Index[i][j] = remap[k]
+		}
+	}
+	m.Vertex = vertex
+}
+
+// Triangles returns the triangles of the mesh.
+func (m *Mesh3) Triangles() []*Triangle3 {
+	triangles := make([]*Triangle3, len(m.Index))
+	for i, x := range m.Index {
+		triangles[i] = &Triangle3{m.Vertex[x[0]], m.Vertex[x[1]], m.Vertex[x[2]]}
+	}
+	return triangles
+}
+
+// BoundingBox returns the bounding box of the mesh (an empty box for no vertices).
+func (m *Mesh3) BoundingBox() Box3 {
+	if len(m.Vertex) == 0 {
+		return Box3{}
+	}
+	vs := v3.VecSet(m.Vertex)
+	return Box3{vs.Min(), vs.Max()}
+}
+
+//-----------------------------------------------------------------------------
+// Normals
+
+// normalize returns a unit vector, or a zero vector if the length is zero.
+func normalize(v v3.Vec) v3.Vec {
+	l := v.Length()
+	if l == 0 {
+		return v3.Vec{}
+	}
+	return v.DivScalar(l)
+}
+
+// TriangleNormals returns the unit normal of each triangle.
+// Zero area triangles have a zero normal.
+func (m *Mesh3) TriangleNormals() []v3.Vec {
+	n := make([]v3.Vec, len(m.Index))
+	for i, x := range m.Index {
+		e1 := m.Vertex[x[1]].Sub(m.Vertex[x[0]])
+		e2 := m.Vertex[x[2]].Sub(m.Vertex[x[0]])
+		n[i] = normalize(e1.Cross(e2))
+	}
+	return n
+}
+
+// VertexNormals returns the unit normal at each vertex.
+// The normal is the area weighted average of the normals of the adjacent triangles.
+func (m *Mesh3) VertexNormals() []v3.Vec {
+	n := make([]v3.Vec, len(m.Vertex))
+	for _, x := range m.Index {
+		e1 := m.Vertex[x[1]].Sub(m.Vertex[x[0]])
+		e2 := m.Vertex[x[2]].Sub(m.Vertex[x[0]])
+		// the cross product length is twice the triangle area
+		c := e1.Cross(e2)
+		for _, k := range x {
+			n[k] = n[k].Add(c)
+		}
+	}
+	for i := range n {
+		n[i] = normalize(n[i])
+	}
+	return n
+}
+
+//-----------------------------------------------------------------------------
+// Topology
+
+// edgeCount returns the number of triangles using each edge.
+func (m *Mesh3) edgeCount() map[Edge3]int {
+	count := make(map[Edge3]int, len(m.Index)*3/2)
+	for _, x := range m.Index {
+		count[newEdge3(x[0], x[1])]++
+		count[newEdge3(x[1], x[2])]++
+		count[newEdge3(x[2], x[0])]++
+	}
+	return count
+}
+
+// Edges returns the number of distinct edges in the mesh.
+func (m *Mesh3) Edges() int {
+	return len(m.edgeCount())
+}
+
+// BoundaryEdges returns the edges used by a single triangle.
+func (m *Mesh3) BoundaryEdges() []Edge3 {
+	var edges []Edge3
+	for e, n := range m.edgeCount() {
+		if n == 1 {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// NonManifoldEdges returns the edges used by more than two triangles.
+func (m *Mesh3) NonManifoldEdges() []Edge3 {
+	var edges []Edge3
+	for e, n := range m.edgeCount() {
+		if n > 2 {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// IsClosed returns true if every edge of the mesh is used by exactly two triangles.
+func (m *Mesh3) IsClosed() bool {
+	for _, n := range m.edgeCount() {
+		if n != 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// EulerCharacteristic returns V - E + F for the mesh.
+func (m *Mesh3) EulerCharacteristic() int {
+	return len(m.Vertex) - m.Edges() + len(m.Index)
+}
+
+// Components returns the number of connected components in the mesh.
+func (m *Mesh3) Components() int {
+	// union-find over the vertices
+	parent := make([]int, len(m.Vertex))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	n := len(m.Vertex)
+	for _, x := range m.Index {
+		for j := 1; j < 3; j++ {
+			a, b := find(x[0]), find(x[j])
+			if a != b {
+				parent[a] = b
+				n--
+			}
+		}
+	}
+	return n
+}
+
+// IsOriented returns true if adjacent triangles have consistent winding.
+// That is, no directed edge is used by more than one triangle.
+func (m *Mesh3) IsOriented() bool {
+	edges := make(map[[2]int]bool, len(m.Index)*3)
+	for _, x := range m.Index {
+		for i := 0; i < 3; i++ {
+			e := [2]int{x[i], x[(i+1)%3]}
+			if edges[e] {
+				return false
+			}
+			edges[e] = true
+		}
+	}
+	return true
+}
+
+// Genus returns the total genus of a closed, consistently oriented mesh.
+func (m *Mesh3) Genus() (int, error) {
+	if !m.IsClosed() {
+		return 0, errors.New("mesh is not closed")
+	}
+	if !m.IsOriented() {
+		return 0, errors.New("mesh is not consistently oriented")
+	}
+	x := 2*m.Components() - m.EulerCharacteristic()
+	if x < 0 || x&1 != 0 {
+		return 0, errors.New("mesh has an invalid euler characteristic")
+	}
+	return x / 2, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,190 @@
+//-----------------------------------------------------------------------------
+/*
+
+Indexed Mesh Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// gridTriangles returns the triangles for an n x m grid of quads on a parametric surface.
+// Vertices are computed separately for each triangle so the mesh needs welding.
+func gridTriangles(n, m int, f func(u, v float64) v3.Vec) []*Triangle3 {
+	var triangles []*Triangle3
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			u0 := float64(i) / float64(n)
+			u1 := float64(i+1) / float64(n)
+			v0 := float64(j) / float64(m)
+			v1 := float64(j+1) / float64(m)
+			triangles = append(triangles, &Triangle3{f(u0, v0), f(u1, v0), f(u1, v1)})
+			triangles = append(triangles, &Triangle3{f(u0, v0), f(u1, v1), f(u0, v1)})
+		}
+	}
+	return triangles
+}
+
+// torusTriangles returns the triangles for a torus about the z-axis.
+func torusTriangles(r0, r1 float64, n, m int) []*Triangle3 {
+	return gridTriangles(n, m, func(u, v float64) v3.Vec {
+		a := u * Tau
+		b := v * Tau
+		r := r0 + r1*math.Cos(b)
+		return v3.Vec{r * math.Cos(a), r * math.Sin(a), r1 * math.Sin(b)}
+	})
+}
+
+// cubeTriangles returns the triangles for a unit cube.
+func cubeTriangles() []*Triangle3 {
+	v := Box3{v3.Vec{0, 0, 0}, v3.Vec{1, 1, 1}}.Vertices()
+	// anti-clockwise faces viewed from outside
+	faces := [][4]int{
+		{0, 1, 3, 2}, {4, 6, 7, 5}, {0, 4, 5, 1},
+		{2, 3, 7, 6}, {0, 2, 6, 4}, {1, 5, 7, 3},
+	}
+	var triangles []*Triangle3
+	for _, f := range faces {
+		triangles = append(triangles, &Triangle3{v[f[0]], v[f[1]], v[f[2]]})
+		triangles = append(triangles, &Triangle3{v[f[0]], v[f[2]], v[f[3]]})
+	}
+	return triangles
+}
+
+func Test_Mesh3_Topology(t *testing.T) {
+	torus := torusTriangles(5, 1, 32, 16)
+	two := append(cubeTriangles(), torus...)
+	tests := []struct {
+		name       string
+		triangles  []*Triangle3
+		vertices   int
+		components int
+		genus      int
+	}{
+		{"cube", cubeTriangles(), 8, 1, 0},
+		{"torus", torus, 32 * 16, 1, 1},
+		{"cube+torus", two, 8 + 32*16, 2, 1},
+	}
+	for _, x := range tests {
+		m, err := NewMesh3(x.triangles, 1e-9)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Vertex) != x.vertices {
+			t.Errorf("%s: %d vertices, expected %d", x.name, len(m.Vertex), x.vertices)
+		}
+		if len(m.Index) != len(x.triangles) {
+			t.Errorf("%s: %d triangles, expected %d", x.name, len(m.Index), len(x.triangles))
+		}
+		if !m.IsClosed() || !m.IsOriented() {
+			t.Errorf("%s: expected a closed, oriented mesh", x.name)
+		}
+		if n := m.Components(); n != x.components {
+			t.Errorf("%s: %d components, expected %d", x.name, n, x.components)
+		}
+		g, err := m.Genus()
+		if err != nil {
+			t.Errorf("%s: %s", x.name, err)
+		} else if g != x.genus {
+			t.Errorf("%s: genus %d, expected %d", x.name, g, x.genus)
+		}
+	}
+}
+
+func Test_Mesh3_Welding(t *testing.T) {
+	// without welding the torus seams are open
+	triangles := torusTriangles(5, 1, 16, 8)
+	m, _ := NewMesh3(triangles, 0)
+	if len(m.BoundaryEdges()) == 0 {
+		t.Errorf("expected boundary edges with exact welding")
+	}
+	m, _ = NewMesh3(triangles, 1e-6)
+	if len(m.BoundaryEdges()) != 0 {
+		t.Errorf("unexpected boundary edges with tolerance welding")
+	}
+	// triangles that collapse are dropped
+	tiny := &Triangle3{{0, 0, 0}, {1e-3, 0, 0}, {0, 1e-3, 0}}
+	m, _ = NewMesh3(append(cubeTriangles(), tiny), 1e-2)
+	if len(m.Index) != 12 || len(m.Vertex) != 8 {
+		t.Errorf("%d triangles, %d vertices", len(m.Index), len(m.Vertex))
+	}
+	// round trip to triangles
+	for i, tri := range m.Triangles() {
+		if !tri.Equals(cubeTriangles()[i], 0) {
+			t.Errorf("triangle %d: %v", i, tri)
+		}
+	}
+	if _, err := NewMesh3(triangles, -1); err == nil {
+		t.Errorf("expected an error for a negative tolerance")
+	}
+	// only collapsed triangles is an empty mesh
+	m, _ = NewMesh3([]*Triangle3{tiny}, 1e-2)
+	if len(m.Index) != 0 || m.BoundingBox() != (Box3{}) {
+		t.Errorf("expected an empty mesh")
+	}
+}
+
+func Test_Mesh3_Edges(t *testing.T) {
+	// remove a face from the cube
+	m, _ := NewMesh3(cubeTriangles()[2:], 0)
+	if n := len(m.BoundaryEdges()); n != 4 {
+		t.Errorf("%d boundary edges, expected 4", n)
+	}
+	if _, err := m.Genus(); err == nil {
+		t.Errorf("expected an error for an open mesh")
+	}
+	// add a fin on a cube edge
+	fin := &Triangle3{{0, 0, 0}, {1, 0, 0}, {0.5, -1, -1}}
+	m, _ = NewMesh3(append(cubeTriangles(), fin), 0)
+	if n := len(m.NonManifoldEdges()); n != 1 {
+		t.Errorf("%d non-manifold edges, expected 1", n)
+	}
+	// flip a triangle
+	triangles := cubeTriangles()
+	triangles[0] = &Triangle3{triangles[0][0], triangles[0][2], triangles[0][1]}
+	m, _ = NewMesh3(triangles, 0)
+	if !m.IsClosed() || m.IsOriented() {
+		t.Errorf("expected a closed mesh with inconsistent orientation")
+	}
+}
+
+func Test_Mesh3_Normals(t *testing.T) {
+	m, _ := NewMesh3(torusTriangles(5, 1, 64, 32), 1e-9)
+	normals := m.VertexNormals()
+	for i, v := range m.Vertex {
+		// the exact normal points away from the core circle of the torus
+		c := v3.Vec{v.X, v.Y, 0}.Normalize().MulScalar(5)
+		n := v.Sub(c).Normalize()
+		if normals[i].Dot(n) < 0.99 {
+			t.Errorf("%v: normal %v, expected %v", v, normals[i], n)
+		}
+	}
+	bb := m.BoundingBox()
+	if !bb.Equals(Box3{v3.Vec{-6, -6, -1}, v3.Vec{6, 6, 1}}, 1e-2) {
+		t.Errorf("bounding box %v", bb)
+	}
+	// cube faces point outwards
+	m, _ = NewMesh3(cubeTriangles(), 0)
+	for i, v := range m.VertexNormals() {
+		n := m.Vertex[i].SubScalar(0.5).Normalize()
+		if v.Dot(n) < 0.9 {
+			t.Errorf("%v: normal %v, expected %v", m.Vertex[i], v, n)
+		}
+	}
+	for i, n := range m.TriangleNormals() {
+		if n.Dot(m.Triangles()[i].Normal()) < 1-tolerance {
+			t.Errorf("triangle %d: normal %v", i, n)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -227,6 +227,58 @@ func ScaleTwistExtrude(height, twist float64, scale v2.Vec) ExtrudeFunc {
 	}
 }
 
+//-----------------------------------------------------------------------------
+// Distance bounds for non-linear space warps
+//
+// Twisted extrusions and screws map 3d space onto the 2d space of an SDF2.
+// These mappings stretch space, so the SDF2 distance overestimates the 3d distance.
+// If the gradient (Jacobian norm) of the mapping is bounded, then we can scale
+// the distance so it is a lower bound on the 3d distance. That's all we need for
+// offsetting, shelling, raycasting and octree culling.
+
+// GradientFunc returns an upper bound on the gradient of a space warp.
+// The bound applies to points at distance r from the z-axis, and on the way
+// from height z to the surface of the object. It should increase or decrease
+// monotonically with r.
+type GradientFunc func(r, z float64) float64
+
+// lipschitzBound returns a distance that is a lower bound on the distance to the surface.
+// d is the distance from the unwarped SDF, p is at radius r and height z.
+// Moving from p to the surface changes the SDF value by |d|, and the gradient
+// is at most the maximum of g over the radii we can reach. So the true distance
+// D satisfies D * max(g) >= |d|. We iterate once from an overestimate of D
+// to get a guaranteed underestimate.
+func lipschitzBound(d, r, z float64, g GradientFunc) float64 {
+	d0 := math.Abs(d) / g(r, z)
+	k := math.Max(g(math.Max(r-d0, 0), z), g(r+d0, z))
+	return d / k
+}
+
+// twistGradient returns the gradient bound for TwistExtrude.
+func twistGradient(height, twist float64) GradientFunc {
+	k := twist / height
+	return func(r, z float64) float64 {
+		return math.Sqrt(1 + k*k*r*r)
+	}
+}
+
+// scaleTwistGradient returns the gradient bound for ScaleTwistExtrude (and ScaleExtrude with twist = 0).
+func scaleTwistGradient(height, twist float64, scale v2.Vec) GradientFunc {
+	k := math.Abs(twist / height)
+	inv := v2.Vec{1 / scale.X, 1 / scale.Y}
+	m := inv.Sub(v2.Vec{1, 1}).DivScalar(height) // slope
+	b := inv.MulScalar(0.5).AddScalar(0.5)       // intercept
+	// The xy scaling is linear with z, so the maximum is at the ends
+	// of the extrusion, or at z if that is beyond the ends.
+	sEnd := math.Max(1, inv.Abs().MaxComponent())
+	mMax := m.Abs().MaxComponent()
+	return func(r, z float64) float64 {
+		sMax := math.Max(sEnd, m.MulScalar(z).Add(b).Abs().MaxComponent())
+		dz := r * (k*sMax + mMax)
+		return math.Sqrt(sMax*sMax + dz*dz)
+	}
+}
+
 //-----------------------------------------------------------------------------
 // Raycasting
 
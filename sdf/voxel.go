@@ -10,12 +10,75 @@ package sdf
 
 import (
 	"github.com/deadsy/sdfx/vec/conv"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
 	v3 "github.com/deadsy/sdfx/vec/v3"
 	"github.com/deadsy/sdfx/vec/v3i"
 )
 
 //-----------------------------------------------------------------------------
 
+// VoxelInterp selects the interpolation between the voxel corner values.
+type VoxelInterp int
+
+const (
+	// VoxelLinear is (bi/tri)linear interpolation. It is fast, but rounds off sharp features.
+	VoxelLinear VoxelInterp = iota
+	// VoxelCatmullRom is cubic interpolation (Catmull-Rom spline) over the neighbouring corner values.
+	VoxelCatmullRom
+	// VoxelHermite is cubic interpolation of the corner values and gradients.
+	// The gradients are stored with the values, so it uses more memory and setup time.
+	VoxelHermite
+)
+
+// voxelGradientStep is the step (relative to the voxel size) for working out gradients.
+const voxelGradientStep = 1e-3
+
+// linearWeights returns the linear interpolation weights for the corners at 0 and 1.
+func linearWeights(t float64) [2]float64 {
+	return [2]float64{1 - t, t}
+}
+
+// catmullRomWeights returns the Catmull-Rom weights for the corners at -1, 0, 1 and 2.
+func catmullRomWeights(t float64) [4]float64 {
+	t2 := t * t
+	t3 := t2 * t
+	return [4]float64{
+		0.5 * (-t3 + 2*t2 - t),
+		0.5 * (3*t3 - 5*t2 + 2),
+		0.5 * (-3*t3 + 4*t2 + t),
+		0.5 * (t3 - t2),
+	}
+}
+
+// hermiteWeights returns the cubic Hermite weights for the values (h) and derivatives (d) at 0 and 1.
+func hermiteWeights(t float64) (h, d [2]float64) {
+	t2 := t * t
+	t3 := t2 * t
+	h = [2]float64{2*t3 - 3*t2 + 1, -2*t3 + 3*t2}
+	d = [2]float64{t3 - 2*t2 + t, t3 - t2}
+	return
+}
+
+// voxelIndex returns the index of the voxel containing x (0..n-1) and the position within it (0..1).
+func voxelIndex(x float64, n int) (int, float64) {
+	i := int(x)
+	if i < 0 {
+		i = 0
+	}
+	if i > n-1 {
+		i = n - 1
+	}
+	return i, x - float64(i)
+}
+
+// voxelCells returns the number of voxels along an axis.
+func voxelCells(size, resolution float64) int {
+	return maxInt(int(size/resolution), 1)
+}
+
+//-----------------------------------------------------------------------------
+
 // VoxelSDF3 is the SDF that represents a pre-computed voxel-based SDF3.
 // It can be used as a cache, or for smoothing.
 //
@@ -26,31 +89,83 @@ import (
 // It performs trilinear mapping for inner values and may be used as a cache for any other SDF, losing some accuracy.
 //
 // WARNING: It may lose sharp features, even if meshCells is high.
+// Cubic interpolation (see NewVoxelSDF3Interp) keeps more of them.
+//
+// See SparseVoxelSDF3 for a version that uses less memory and can be saved to a file.
 type VoxelSDF3 struct {
-	// voxelCorners are the values of this SDF in each voxel corner
-	voxelCorners map[v3i.Vec]float64 // TODO: Octree + k-d tree to simplify/reduce memory consumption + speed-up access?
+	// values are the values of this SDF in each voxel corner
+	values []float64
+	// gradients are the gradients of this SDF in each voxel corner (hermite interpolation only)
+	gradients []v3.Vec
+	// interp is the interpolation between the voxel corners.
+	interp VoxelInterp
 	// bb is the bounding box.
 	bb Box3
-	// Number of voxelCorners to consider
+	// Number of voxels to consider
 	numVoxels v3i.Vec
 }
 
-// NewVoxelSDF3 returns a VoxelSDF3.
+// NewVoxelSDF3 returns a VoxelSDF3 with trilinear interpolation.
 // This populates the whole cache from the given SDF.
 // The progress listener may be nil.
+// It returns nil for invalid arguments (meshCells <= 0).
+//
+// Deprecated: Use NewVoxelSDF3Interp with VoxelLinear, which returns the error.
 func NewVoxelSDF3(s SDF3, meshCells int, progress chan float64) SDF3 {
+	m, _ := NewVoxelSDF3Interp(s, meshCells, VoxelLinear, progress)
+	return m
+}
+
+// NewVoxelSDF3Interp returns a VoxelSDF3 with the selected interpolation.
+// This populates the whole cache from the given SDF.
+// The progress listener may be nil.
+func NewVoxelSDF3Interp(s SDF3, meshCells int, interp VoxelInterp, progress chan float64) (SDF3, error) {
+	if meshCells <= 0 {
+		return nil, ErrMsg("meshCells <= 0")
+	}
+	if interp < VoxelLinear || interp > VoxelHermite {
+		return nil, ErrMsg("unknown voxel interpolation")
+	}
+
 	bb := s.BoundingBox() // TODO: Use default code to avoid duplication
 	bbSize := bb.Size()
 	resolution := bbSize.MaxComponent() / float64(meshCells)
-	cells := conv.V3ToV3i(bbSize.DivScalar(resolution))
+	cells := v3i.Vec{
+		voxelCells(bbSize.X, resolution),
+		voxelCells(bbSize.Y, resolution),
+		voxelCells(bbSize.Z, resolution),
+	}
+
+	m := &VoxelSDF3{
+		values:    make([]float64, (cells.X+1)*(cells.Y+1)*(cells.Z+1)),
+		interp:    interp,
+		bb:        bb,
+		numVoxels: cells,
+	}
+	voxelSize := m.voxelSize()
+	// steps for the gradient central differences
+	h := voxelSize.MulScalar(voxelGradientStep)
+	dx := v3.Vec{h.X, 0, 0}
+	dy := v3.Vec{0, h.Y, 0}
+	dz := v3.Vec{0, 0, h.Z}
+	if interp == VoxelHermite {
+		m.gradients = make([]v3.Vec, len(m.values))
+	}
 
-	voxelCorners := map[v3i.Vec]float64{}
 	voxelCornerIndex := v3i.Vec{}
 	for voxelCornerIndex.X = 0; voxelCornerIndex.X <= cells.X; voxelCornerIndex.X++ {
 		for voxelCornerIndex.Y = 0; voxelCornerIndex.Y <= cells.Y; voxelCornerIndex.Y++ {
 			for voxelCornerIndex.Z = 0; voxelCornerIndex.Z <= cells.Z; voxelCornerIndex.Z++ {
-				voxelCorner := bb.Min.Add(bbSize.Mul(conv.V3iToV3(voxelCornerIndex)).Div(conv.V3iToV3(cells)))
-				voxelCorners[voxelCornerIndex] = s.Evaluate(voxelCorner)
+				voxelCorner := bb.Min.Add(voxelSize.Mul(conv.V3iToV3(voxelCornerIndex)))
+				k := m.index(voxelCornerIndex.X, voxelCornerIndex.Y, voxelCornerIndex.Z)
+				m.values[k] = s.Evaluate(voxelCorner)
+				if m.gradients != nil {
+					m.gradients[k] = v3.Vec{
+						s.Evaluate(voxelCorner.Add(dx)) - s.Evaluate(voxelCorner.Sub(dx)),
+						s.Evaluate(voxelCorner.Add(dy)) - s.Evaluate(voxelCorner.Sub(dy)),
+						s.Evaluate(voxelCorner.Add(dz)) - s.Evaluate(voxelCorner.Sub(dz)),
+					}.Div(h.MulScalar(2))
+				}
 			}
 		}
 		if progress != nil {
@@ -58,41 +173,95 @@ func NewVoxelSDF3(s SDF3, meshCells int, progress chan float64) SDF3 {
 		}
 	}
 
-	return &VoxelSDF3{
-		voxelCorners: voxelCorners,
-		bb:           bb,
-		numVoxels:    cells,
+	return m, nil
+}
+
+// voxelSize returns the size of a voxel.
+func (m *VoxelSDF3) voxelSize() v3.Vec {
+	return m.bb.Size().Div(conv.V3iToV3(m.numVoxels))
+}
+
+// index returns the index of a voxel corner.
+func (m *VoxelSDF3) index(x, y, z int) int {
+	return (x*(m.numVoxels.Y+1)+y)*(m.numVoxels.Z+1) + z
+}
+
+// sample returns the value at a voxel corner.
+// Corners beyond the grid (needed for cubic interpolation) are linearly extrapolated.
+func (m *VoxelSDF3) sample(x, y, z int) float64 {
+	n := m.numVoxels
+	switch {
+	case x < 0:
+		return 2*m.sample(0, y, z) - m.sample(1, y, z)
+	case x > n.X:
+		return 2*m.sample(n.X, y, z) - m.sample(n.X-1, y, z)
+	case y < 0:
+		return 2*m.sample(x, 0, z) - m.sample(x, 1, z)
+	case y > n.Y:
+		return 2*m.sample(x, n.Y, z) - m.sample(x, n.Y-1, z)
+	case z < 0:
+		return 2*m.sample(x, y, 0) - m.sample(x, y, 1)
+	case z > n.Z:
+		return 2*m.sample(x, y, n.Z) - m.sample(x, y, n.Z-1)
 	}
+	return m.values[m.index(x, y, z)]
 }
 
 // Evaluate returns the minimum distance to a VoxelSDF3.
+// Outside of the bounding box the distance to the box is added to the boundary value.
 func (m *VoxelSDF3) Evaluate(p v3.Vec) float64 {
-	// Find the voxel's {0,0,0} corner quickly and compute p's displacement
-	voxelSize := m.bb.Size().Div(conv.V3iToV3(m.numVoxels))
-	voxelStartIndex := conv.V3ToV3i(p.Sub(m.bb.Min).Div(voxelSize))
-	voxelStart := m.bb.Min.Add(voxelSize.Mul(conv.V3iToV3(voxelStartIndex)))
-	d := p.Sub(voxelStart).Div(voxelSize) // [0, 1) for each dimension
-	// Get the values at the voxel's corners
-	c000 := m.voxelCorners[voxelStartIndex]
-	c001 := m.voxelCorners[voxelStartIndex.Add(v3i.Vec{0, 0, 1})]
-	c010 := m.voxelCorners[voxelStartIndex.Add(v3i.Vec{0, 1, 0})]
-	c011 := m.voxelCorners[voxelStartIndex.Add(v3i.Vec{0, 1, 1})]
-	c100 := m.voxelCorners[voxelStartIndex.Add(v3i.Vec{1, 0, 0})]
-	c101 := m.voxelCorners[voxelStartIndex.Add(v3i.Vec{1, 0, 1})]
-	c110 := m.voxelCorners[voxelStartIndex.Add(v3i.Vec{1, 1, 0})]
-	c111 := m.voxelCorners[voxelStartIndex.Add(v3i.Vec{1, 1, 1})]
-	// Perform trilinear interpolation over the voxel's corners
-	// - 4 linear interpolations
-	c00 := c000*(1-d.X) + c100*d.X
-	c01 := c001*(1-d.X) + c101*d.X
-	c10 := c010*(1-d.X) + c110*d.X
-	c11 := c011*(1-d.X) + c111*d.X
-	// - 2 bilinear interpolations
-	c0 := c00*(1-d.Y) + c10*d.Y
-	c1 := c01*(1-d.Y) + c11*d.Y
-	// - 1 trilinear interpolation
-	c := c0*(1-d.Z) + c1*d.Z
-	return c
+	q := p.Clamp(m.bb.Min, m.bb.Max)
+	d := p.Sub(q).Length()
+	// Find the voxel's {0,0,0} corner and p's displacement within it
+	voxelSize := m.voxelSize()
+	q = q.Sub(m.bb.Min).Div(voxelSize)
+	x, tx := voxelIndex(q.X, m.numVoxels.X)
+	y, ty := voxelIndex(q.Y, m.numVoxels.Y)
+	z, tz := voxelIndex(q.Z, m.numVoxels.Z)
+
+	c := 0.0
+	switch m.interp {
+	case VoxelCatmullRom:
+		wx := catmullRomWeights(tx)
+		wy := catmullRomWeights(ty)
+		wz := catmullRomWeights(tz)
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				for k := 0; k < 4; k++ {
+					c += wx[i] * wy[j] * wz[k] * m.sample(x+i-1, y+j-1, z+k-1)
+				}
+			}
+		}
+	case VoxelHermite:
+		hx, dx := hermiteWeights(tx)
+		hy, dy := hermiteWeights(ty)
+		hz, dz := hermiteWeights(tz)
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 2; j++ {
+				for k := 0; k < 2; k++ {
+					n := m.index(x+i, y+j, z+k)
+					g := m.gradients[n].Mul(voxelSize)
+					c += hx[i]*hy[j]*hz[k]*m.values[n] +
+						dx[i]*hy[j]*hz[k]*g.X +
+						hx[i]*dy[j]*hz[k]*g.Y +
+						hx[i]*hy[j]*dz[k]*g.Z
+				}
+			}
+		}
+	default:
+		// Perform trilinear interpolation over the voxel's corners
+		wx := linearWeights(tx)
+		wy := linearWeights(ty)
+		wz := linearWeights(tz)
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 2; j++ {
+				for k := 0; k < 2; k++ {
+					c += wx[i] * wy[j] * wz[k] * m.values[m.index(x+i, y+j, z+k)]
+				}
+			}
+		}
+	}
+	return c + d
 }
 
 // BoundingBox returns the bounding box for a VoxelSDF3.
@@ -101,3 +270,159 @@ func (m *VoxelSDF3) BoundingBox() Box3 {
 }
 
 //-----------------------------------------------------------------------------
+
+// VoxelSDF2 is the SDF that represents a pre-computed voxel-based SDF2.
+// It can be used as a cache, or for smoothing. See VoxelSDF3.
+type VoxelSDF2 struct {
+	// values are the values of this SDF in each voxel corner
+	values []float64
+	// gradients are the gradients of this SDF in each voxel corner (hermite interpolation only)
+	gradients []v2.Vec
+	// interp is the interpolation between the voxel corners.
+	interp VoxelInterp
+	// bb is the bounding box.
+	bb Box2
+	// Number of voxels to consider
+	numVoxels v2i.Vec
+}
+
+// NewVoxelSDF2 returns a VoxelSDF2 with bilinear interpolation.
+// This populates the whole cache from the given SDF.
+// The progress listener may be nil.
+func NewVoxelSDF2(s SDF2, meshCells int, progress chan float64) SDF2 {
+	m, _ := NewVoxelSDF2Interp(s, meshCells, VoxelLinear, progress)
+	return m
+}
+
+// NewVoxelSDF2Interp returns a VoxelSDF2 with the selected interpolation.
+// This populates the whole cache from the given SDF.
+// The progress listener may be nil.
+func NewVoxelSDF2Interp(s SDF2, meshCells int, interp VoxelInterp, progress chan float64) (SDF2, error) {
+	if meshCells <= 0 {
+		return nil, ErrMsg("meshCells <= 0")
+	}
+	if interp < VoxelLinear || interp > VoxelHermite {
+		return nil, ErrMsg("unknown voxel interpolation")
+	}
+
+	bb := s.BoundingBox()
+	bbSize := bb.Size()
+	resolution := bbSize.MaxComponent() / float64(meshCells)
+	cells := v2i.Vec{
+		voxelCells(bbSize.X, resolution),
+		voxelCells(bbSize.Y, resolution),
+	}
+
+	m := &VoxelSDF2{
+		values:    make([]float64, (cells.X+1)*(cells.Y+1)),
+		interp:    interp,
+		bb:        bb,
+		numVoxels: cells,
+	}
+	voxelSize := m.voxelSize()
+	// steps for the gradient central differences
+	h := voxelSize.MulScalar(voxelGradientStep)
+	dx := v2.Vec{h.X, 0}
+	dy := v2.Vec{0, h.Y}
+	if interp == VoxelHermite {
+		m.gradients = make([]v2.Vec, len(m.values))
+	}
+
+	voxelCornerIndex := v2i.Vec{}
+	for voxelCornerIndex.X = 0; voxelCornerIndex.X <= cells.X; voxelCornerIndex.X++ {
+		for voxelCornerIndex.Y = 0; voxelCornerIndex.Y <= cells.Y; voxelCornerIndex.Y++ {
+			voxelCorner := bb.Min.Add(voxelSize.Mul(conv.V2iToV2(voxelCornerIndex)))
+			k := m.index(voxelCornerIndex.X, voxelCornerIndex.Y)
+			m.values[k] = s.Evaluate(voxelCorner)
+			if m.gradients != nil {
+				m.gradients[k] = v2.Vec{
+					s.Evaluate(voxelCorner.Add(dx)) - s.Evaluate(voxelCorner.Sub(dx)),
+					s.Evaluate(voxelCorner.Add(dy)) - s.Evaluate(voxelCorner.Sub(dy)),
+				}.Div(h.MulScalar(2))
+			}
+		}
+		if progress != nil {
+			progress <- float64(voxelCornerIndex.X) / float64(cells.X)
+		}
+	}
+
+	return m, nil
+}
+
+// voxelSize returns the size of a voxel.
+func (m *VoxelSDF2) voxelSize() v2.Vec {
+	return m.bb.Size().Div(conv.V2iToV2(m.numVoxels))
+}
+
+// index returns the index of a voxel corner.
+func (m *VoxelSDF2) index(x, y int) int {
+	return x*(m.numVoxels.Y+1) + y
+}
+
+// sample returns the value at a voxel corner.
+// Corners beyond the grid (needed for cubic interpolation) are linearly extrapolated.
+func (m *VoxelSDF2) sample(x, y int) float64 {
+	n := m.numVoxels
+	switch {
+	case x < 0:
+		return 2*m.sample(0, y) - m.sample(1, y)
+	case x > n.X:
+		return 2*m.sample(n.X, y) - m.sample(n.X-1, y)
+	case y < 0:
+		return 2*m.sample(x, 0) - m.sample(x, 1)
+	case y > n.Y:
+		return 2*m.sample(x, n.Y) - m.sample(x, n.Y-1)
+	}
+	return m.values[m.index(x, y)]
+}
+
+// Evaluate returns the minimum distance to a VoxelSDF2.
+// Outside of the bounding box the distance to the box is added to the boundary value.
+func (m *VoxelSDF2) Evaluate(p v2.Vec) float64 {
+	q := p.Clamp(m.bb.Min, m.bb.Max)
+	d := p.Sub(q).Length()
+	// Find the voxel's {0,0} corner and p's displacement within it
+	voxelSize := m.voxelSize()
+	q = q.Sub(m.bb.Min).Div(voxelSize)
+	x, tx := voxelIndex(q.X, m.numVoxels.X)
+	y, ty := voxelIndex(q.Y, m.numVoxels.Y)
+
+	c := 0.0
+	switch m.interp {
+	case VoxelCatmullRom:
+		wx := catmullRomWeights(tx)
+		wy := catmullRomWeights(ty)
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				c += wx[i] * wy[j] * m.sample(x+i-1, y+j-1)
+			}
+		}
+	case VoxelHermite:
+		hx, dx := hermiteWeights(tx)
+		hy, dy := hermiteWeights(ty)
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 2; j++ {
+				n := m.index(x+i, y+j)
+				g := m.gradients[n].Mul(voxelSize)
+				c += hx[i]*hy[j]*m.values[n] + dx[i]*hy[j]*g.X + hx[i]*dy[j]*g.Y
+			}
+		}
+	default:
+		// Perform bilinear interpolation over the voxel's corners
+		wx := linearWeights(tx)
+		wy := linearWeights(ty)
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 2; j++ {
+				c += wx[i] * wy[j] * m.values[m.index(x+i, y+j)]
+			}
+		}
+	}
+	return c + d
+}
+
+// BoundingBox returns the bounding box for a VoxelSDF2.
+func (m *VoxelSDF2) BoundingBox() Box2 {
+	return m.bb
+}
+
+//-----------------------------------------------------------------------------
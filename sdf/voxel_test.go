@@ -0,0 +1,109 @@
+//-----------------------------------------------------------------------------
+/*
+
+Voxel SDF Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// voxelError3 returns the maximum error of a voxel SDF3 near the surface.
+func voxelError3(t *testing.T, s SDF3, interp VoxelInterp) float64 {
+	v, err := NewVoxelSDF3Interp(s, 16, interp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := s.BoundingBox()
+	e := 0.0
+	for i := 0; i < 5000; i++ {
+		p := b.Random()
+		d := s.Evaluate(p)
+		if math.Abs(d) < 2 {
+			e = math.Max(e, math.Abs(v.Evaluate(p)-d))
+		}
+	}
+	// the corner values are exact
+	p := b.Min.Add(b.Size().MulScalar(0.25))
+	if !EqualFloat64(v.Evaluate(p), s.Evaluate(p), tolerance) {
+		t.Errorf("interp %d: expected %f at a voxel corner, got %f", interp, s.Evaluate(p), v.Evaluate(p))
+	}
+	return e
+}
+
+// voxelError2 returns the maximum error of a voxel SDF2 near the surface.
+func voxelError2(t *testing.T, s SDF2, interp VoxelInterp) float64 {
+	v, err := NewVoxelSDF2Interp(s, 16, interp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := s.BoundingBox()
+	e := 0.0
+	for i := 0; i < 5000; i++ {
+		p := b.Random()
+		d := s.Evaluate(p)
+		if math.Abs(d) < 2 {
+			e = math.Max(e, math.Abs(v.Evaluate(p)-d))
+		}
+	}
+	p := b.Min.Add(b.Size().MulScalar(0.25))
+	if !EqualFloat64(v.Evaluate(p), s.Evaluate(p), tolerance) {
+		t.Errorf("interp %d: expected %f at a voxel corner, got %f", interp, s.Evaluate(p), v.Evaluate(p))
+	}
+	return e
+}
+
+func Test_VoxelSDF3(t *testing.T) {
+	s, _ := Sphere3D(10)
+	linear := voxelError3(t, s, VoxelLinear)
+	catmullRom := voxelError3(t, s, VoxelCatmullRom)
+	hermite := voxelError3(t, s, VoxelHermite)
+	if catmullRom >= linear || hermite >= linear {
+		t.Errorf("expected cubic errors (%f, %f) < linear error (%f)", catmullRom, hermite, linear)
+	}
+	// outside the bounding box
+	v, err := NewVoxelSDF3Interp(s, 16, VoxelLinear, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := v3.Vec{20, 0, 0}
+	if d := v.Evaluate(p); !EqualFloat64(d, 10, 1e-2) {
+		t.Errorf("%v: expected 10, got %f", p, d)
+	}
+	if _, err := NewVoxelSDF3Interp(s, 16, VoxelInterp(-1), nil); err == nil {
+		t.Error("expected error for unknown interpolation")
+	}
+	if NewVoxelSDF3(s, 0, nil) != nil {
+		t.Error("expected nil for meshCells == 0")
+	}
+}
+
+func Test_VoxelSDF2(t *testing.T) {
+	s, _ := Circle2D(10)
+	linear := voxelError2(t, s, VoxelLinear)
+	catmullRom := voxelError2(t, s, VoxelCatmullRom)
+	hermite := voxelError2(t, s, VoxelHermite)
+	if catmullRom >= linear || hermite >= linear {
+		t.Errorf("expected cubic errors (%f, %f) < linear error (%f)", catmullRom, hermite, linear)
+	}
+	v := NewVoxelSDF2(s, 16, nil)
+	p := v2.Vec{0, -15}
+	if d := v.Evaluate(p); !EqualFloat64(d, 5, 1e-2) {
+		t.Errorf("%v: expected 5, got %f", p, d)
+	}
+	if _, err := NewVoxelSDF2Interp(s, 0, VoxelLinear, nil); err == nil {
+		t.Error("expected error for meshCells == 0")
+	}
+}
+
+//-----------------------------------------------------------------------------
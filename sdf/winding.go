@@ -0,0 +1,170 @@
+//-----------------------------------------------------------------------------
+/*
+
+Generalized Winding Numbers
+
+The winding number of a closed, consistently oriented mesh is 1 inside and 0
+outside. The generalized winding number (the sum of the signed solid angles
+of the triangles divided by 4 pi) degrades gracefully for meshes with holes,
+self-intersections and a few flipped triangles. Thresholding it at 0.5 gives
+a robust inside/outside test.
+
+The solid angles of distant groups of triangles are approximated with a
+dipole (the area weighted normal of the group), so evaluation visits a small
+fraction of the triangles.
+
+See: "Fast Winding Numbers for Soups and Clouds", Barill et al, 2018.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// windingBeta is the accuracy parameter for the dipole approximation.
+// A node is approximated if the point is further than beta * node radius
+// from its center. Larger values are more accurate and slower.
+const windingBeta = 2.0
+
+// windingNode holds the dipole for the triangles of a bvh node.
+type windingNode struct {
+	center v3.Vec  // area weighted center of the triangles
+	normal v3.Vec  // sum of the area weighted triangle normals
+	radius float64 // radius of a sphere about the center containing the triangles
+	area   float64 // total area of the triangles
+}
+
+// Winding3 evaluates the generalized winding number of a triangle mesh.
+type Winding3 struct {
+	mesh []*Triangle3
+	t    *bvh3
+	node []windingNode
+}
+
+// NewWinding3 returns the winding number evaluator for a triangle mesh.
+func NewWinding3(mesh []*Triangle3) (*Winding3, error) {
+	if len(mesh) == 0 {
+		return nil, ErrMsg("no triangles")
+	}
+	bb := make([]Box3, len(mesh))
+	for i, t := range mesh {
+		bb[i] = t.BoundingBox()
+	}
+	w := &Winding3{
+		mesh: mesh,
+		t:    newBVH3(bb),
+	}
+	w.node = make([]windingNode, len(w.t.node))
+	// child nodes follow their parents, so build the dipoles bottom up
+	for k := len(w.t.node) - 1; k >= 0; k-- {
+		w.node[k] = w.dipole(k)
+	}
+	return w, nil
+}
+
+// dipole returns the dipole for a bvh node (the child nodes have been done).
+func (w *Winding3) dipole(k int) windingNode {
+	node := &w.t.node[k]
+	var n windingNode
+	if node.left < 0 {
+		for _, i := range w.t.item[node.start:node.end] {
+			t := w.mesh[i]
+			an := t[1].Sub(t[0]).Cross(t[2].Sub(t[0])).MulScalar(0.5)
+			a := an.Length()
+			n.normal = n.normal.Add(an)
+			n.center = n.center.Add(t[0].Add(t[1]).Add(t[2]).MulScalar(a / 3))
+			n.area += a
+		}
+	} else {
+		for _, child := range []int{node.left, node.right} {
+			c := &w.node[child]
+			n.normal = n.normal.Add(c.normal)
+			n.center = n.center.Add(c.center.MulScalar(c.area))
+			n.area += c.area
+		}
+	}
+	if n.area > 0 {
+		n.center = n.center.DivScalar(n.area)
+	} else {
+		n.center = node.bb.Center()
+	}
+	// the node box contains the triangles
+	for _, v := range node.bb.Vertices() {
+		n.radius = math.Max(n.radius, v.Sub(n.center).Length())
+	}
+	// a tighter bound from the triangle vertices or child spheres
+	r := 0.0
+	if node.left < 0 {
+		for _, i := range w.t.item[node.start:node.end] {
+			for _, v := range w.mesh[i] {
+				r = math.Max(r, v.Sub(n.center).Length())
+			}
+		}
+	} else {
+		for _, child := range []int{node.left, node.right} {
+			c := &w.node[child]
+			r = math.Max(r, c.center.Sub(n.center).Length()+c.radius)
+		}
+	}
+	n.radius = math.Min(n.radius, r)
+	return n
+}
+
+// solidAngle returns the signed solid angle of a triangle viewed from p.
+// See: "The Solid Angle of a Plane Triangle", Van Oosterom and Strackee, 1983.
+func solidAngle(t *Triangle3, p v3.Vec) float64 {
+	a := t[0].Sub(p)
+	b := t[1].Sub(p)
+	c := t[2].Sub(p)
+	la := a.Length()
+	lb := b.Length()
+	lc := c.Length()
+	num := a.Dot(b.Cross(c))
+	den := la*lb*lc + a.Dot(b)*lc + b.Dot(c)*la + c.Dot(a)*lb
+	return 2 * math.Atan2(num, den)
+}
+
+// Evaluate returns the generalized winding number at p.
+// It is close to 1 inside the mesh and close to 0 outside.
+func (w *Winding3) Evaluate(p v3.Vec) float64 {
+	omega := 0.0
+	var stack [bvhStackSize]int
+	n := 1
+	for n > 0 {
+		n--
+		k := stack[n]
+		node := &w.t.node[k]
+		dn := &w.node[k]
+		if node.left < 0 {
+			for _, i := range w.t.item[node.start:node.end] {
+				omega += solidAngle(w.mesh[i], p)
+			}
+			continue
+		}
+		r := dn.center.Sub(p)
+		d := r.Length()
+		if d > windingBeta*dn.radius {
+			// far away: approximate the node with its dipole
+			omega += r.Dot(dn.normal) / (d * d * d)
+			continue
+		}
+		stack[n] = node.left
+		stack[n+1] = node.right
+		n += 2
+	}
+	return omega / (2 * Tau)
+}
+
+// Inside returns true if p is inside the mesh.
+func (w *Winding3) Inside(p v3.Vec) bool {
+	return w.Evaluate(p) >= 0.5
+}
+
+//-----------------------------------------------------------------------------
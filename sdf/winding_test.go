@@ -0,0 +1,139 @@
+//-----------------------------------------------------------------------------
+/*
+
+Generalized Winding Number Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// exactWinding returns the winding number summed over all the triangles.
+func exactWinding(mesh []*Triangle3, p v3.Vec) float64 {
+	omega := 0.0
+	for _, t := range mesh {
+		omega += solidAngle(t, p)
+	}
+	return omega / (2 * Tau)
+}
+
+func Test_Winding3(t *testing.T) {
+	mesh := torusTriangles(3, 1, 64, 32)
+	w, err := NewWinding3(mesh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// distance to the torus
+	torus := func(p v3.Vec) float64 {
+		return math.Hypot(math.Hypot(p.X, p.Y)-3, p.Z) - 1
+	}
+	b := w.t.node[0].bb.ScaleAboutCenter(1.5)
+	for i := 0; i < 1000; i++ {
+		p := b.Random()
+		got := w.Evaluate(p)
+		want := exactWinding(mesh, p)
+		// the dipole approximation is good to a few percent
+		if math.Abs(got-want) > 0.1 {
+			t.Errorf("%v: expected %f, got %f", p, want, got)
+		}
+		// skip points close to the surface (the mesh is an approximation)
+		d := torus(p)
+		if math.Abs(d) < 0.05 {
+			continue
+		}
+		if w.Inside(p) != (d < 0) {
+			t.Errorf("%v: distance %f, winding %f", p, d, got)
+		}
+	}
+}
+
+func Test_Winding3_Broken(t *testing.T) {
+	mesh := torusTriangles(3, 1, 64, 32)
+	// punch some holes
+	var holes []*Triangle3
+	for i, x := range mesh {
+		if i%37 != 0 {
+			holes = append(holes, x)
+		}
+	}
+	// flip some triangles
+	var flipped []*Triangle3
+	for i, x := range mesh {
+		if i%41 == 0 {
+			x = &Triangle3{x[0], x[2], x[1]}
+		}
+		flipped = append(flipped, x)
+	}
+	// duplicate (overlapping) surfaces
+	overlap := append(append([]*Triangle3{}, mesh...), torusTriangles(3, 1.0001, 48, 24)...)
+
+	inside := []v3.Vec{{3, 0, 0}, {0, -3, 0}, {-2.5, 0, 0.5}, {2.2, 2.2, -0.3}}
+	outside := []v3.Vec{{0, 0, 0}, {0, 5, 0}, {1.5, 0, 0}, {3, 0, 1.5}, {0, 0, 3}}
+	for i, m := range [][]*Triangle3{holes, flipped} {
+		w, err := NewWinding3(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range inside {
+			if !w.Inside(p) {
+				t.Errorf("test %d: %v expected inside, winding %f", i, p, w.Evaluate(p))
+			}
+		}
+		for _, p := range outside {
+			if w.Inside(p) {
+				t.Errorf("test %d: %v expected outside, winding %f", i, p, w.Evaluate(p))
+			}
+		}
+	}
+	// overlapping surfaces have a winding number of 2 inside
+	w, _ := NewWinding3(overlap)
+	for _, p := range inside {
+		if !EqualFloat64(w.Evaluate(p), 2, 0.1) {
+			t.Errorf("%v: expected 2, got %f", p, w.Evaluate(p))
+		}
+	}
+}
+
+func Test_Mesh3DSign(t *testing.T) {
+	mesh := cubeTriangles()
+	// the cube without the x = 0 face
+	open := mesh[2:]
+	tests := []struct {
+		p v3.Vec
+		d float64
+	}{
+		{v3.Vec{0.5, 0.5, 0.5}, -0.5},
+		{v3.Vec{0.5, 0.25, 0.6}, -0.25},
+		{v3.Vec{0.5, 0.5, 1.5}, 0.5},
+		{v3.Vec{2, 2, 0.5}, math.Sqrt2},
+		{v3.Vec{-1, -1, -1}, math.Sqrt(3)},
+	}
+	for _, m := range [][]*Triangle3{mesh, open} {
+		for _, sign := range []MeshSign{MeshSignNormal, MeshSignWinding} {
+			s, err := Mesh3DSign(m, sign)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, test := range tests {
+				d := s.Evaluate(test.p)
+				if !EqualFloat64(d, test.d, tolerance) {
+					t.Errorf("%d triangles, sign %d, test %d: expected %f, got %f", len(m), sign, i, test.d, d)
+				}
+			}
+		}
+	}
+	if _, err := Mesh3DSign(mesh, MeshSign(99)); err == nil {
+		t.Error("expected error for unknown sign")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -178,6 +178,22 @@ func (a VecSet) Max() Vec {
 	return vmax
 }
 
+// IsClosed returns true if the first and last vectors of the set are equal.
+func (a VecSet) IsClosed(tolerance float64) bool {
+	if len(a) < 2 {
+		return true
+	}
+	return a[0].Equals(a[len(a)-1], tolerance)
+}
+
+// Close returns the set of vectors with the first vector appended (if it isn't closed).
+func (a VecSet) Close(tolerance float64) VecSet {
+	if a.IsClosed(tolerance) {
+		return a
+	}
+	return append(a, a[0])
+}
+
 //-----------------------------------------------------------------------------
 
 // VecSetByX sorts the vector set by X value